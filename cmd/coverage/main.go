@@ -0,0 +1,108 @@
+// Command coverage reports which controls of the control catalog are implemented by the features under test/features,
+// on which providers and with which kinds of control, and which controls have no features.
+//
+//	go run ./cmd/coverage -catalog catalog/controls.yaml -format json test/features
+//
+// With -strict, it exits with status 1 when a control has no features or a feature is tagged with a control that is not in the catalog.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"citihub.com/compliance-as-code/internal/controls"
+	"citihub.com/compliance-as-code/internal/csp"
+)
+
+func main() {
+	catalog := flag.String("catalog", "catalog/controls.yaml", "control catalog")
+	format := flag.String("format", "text", "output format, text or json")
+	strict := flag.Bool("strict", false, "exit with status 1 when a control is not covered or a feature is tagged with an unknown control")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [-catalog file] [-format text|json] [-strict] [directory ...]\n\nThe default directory is test/features.\n\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	c, err := controls.Load(*catalog)
+	if err != nil {
+		log.Fatalf("[ERROR] Cannot load control catalog: %v", err)
+	}
+
+	roots := flag.Args()
+	if len(roots) == 0 {
+		roots = []string{"test/features"}
+	}
+	var features []controls.Feature
+	for _, root := range roots {
+		f, err := controls.Files(root)
+		if err != nil {
+			log.Fatalf("[ERROR] Cannot read features in %v: %v", root, err)
+		}
+		features = append(features, f...)
+	}
+
+	r := controls.Cover(c, features)
+	switch *format {
+	case "json":
+		e := json.NewEncoder(os.Stdout)
+		e.SetIndent("", "  ")
+		if err := e.Encode(r); err != nil {
+			log.Fatalf("[ERROR] Cannot write report: %v", err)
+		}
+	case "text":
+		printText(r)
+	default:
+		log.Fatalf("[ERROR] Unknown format %v, use text or json", *format)
+	}
+
+	if *strict && (len(r.Uncovered) > 0 || len(r.Unknown) > 0) {
+		os.Exit(1)
+	}
+}
+
+func printText(r controls.Report) {
+	fmt.Printf("%d control(s) covered:\n", len(r.Covered))
+	for _, cov := range r.Covered {
+		fmt.Printf("\n%s %s\n", cov.ID, cov.Title)
+		for _, p := range cov.Providers() {
+			fmt.Printf("  %-6s %s\n", csp.DisplayName(p)+":", capabilities(cov.Capabilities[p]))
+		}
+		for _, impl := range cov.Implementations {
+			fmt.Printf("  %s (%s)\n", impl.Feature, impl.Path)
+		}
+	}
+
+	fmt.Printf("\n%d control(s) not covered:\n", len(r.Uncovered))
+	for _, ctl := range r.Uncovered {
+		fmt.Printf("  %s %s\n", ctl.ID, ctl.Title)
+	}
+
+	if len(r.Unknown) > 0 {
+		var ids []string
+		for id := range r.Unknown {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		fmt.Printf("\n%d control(s) tagged but not in the catalog:\n", len(ids))
+		for _, id := range ids {
+			fmt.Printf("  %s: %s\n", id, strings.Join(r.Unknown[id], ", "))
+		}
+	}
+}
+
+func capabilities(cs []csp.Capability) string {
+	if len(cs) == 0 {
+		return "none"
+	}
+	s := make([]string, len(cs))
+	for i, c := range cs {
+		s[i] = string(c)
+	}
+	return strings.Join(s, ", ")
+}
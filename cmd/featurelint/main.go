@@ -0,0 +1,59 @@
+// Command featurelint checks the conventions of the Gherkin features under test/features, e.g. that every control feature
+// has a @CCO: tag and that every step has a step definition for each @csp. provider, and reports the findings as text or JSON.
+//
+//	go run ./cmd/featurelint -format json test/features
+//
+// It exits with status 1 when there are findings.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"citihub.com/compliance-as-code/internal/featurelint"
+)
+
+func main() {
+	format := flag.String("format", "text", "output format, text or json")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [-format text|json] [directory ...]\n\nThe default directory is test/features.\n\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	roots := flag.Args()
+	if len(roots) == 0 {
+		roots = []string{"test/features"}
+	}
+
+	findings := []featurelint.Finding{}
+	for _, root := range roots {
+		f, err := featurelint.Files(root)
+		if err != nil {
+			log.Fatalf("[ERROR] Cannot lint features in %v: %v", root, err)
+		}
+		findings = append(findings, f...)
+	}
+
+	switch *format {
+	case "json":
+		e := json.NewEncoder(os.Stdout)
+		e.SetIndent("", "  ")
+		if err := e.Encode(findings); err != nil {
+			log.Fatalf("[ERROR] Cannot write findings: %v", err)
+		}
+	case "text":
+		for _, f := range findings {
+			fmt.Println(f)
+		}
+	default:
+		log.Fatalf("[ERROR] Unknown format %v, use text or json", *format)
+	}
+
+	if len(findings) > 0 {
+		os.Exit(1)
+	}
+}
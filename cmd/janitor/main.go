@@ -0,0 +1,108 @@
+// Command janitor deletes the resources that the suites left behind in the shared accounts and subscriptions, the buckets
+// and resource groups tagged with a run ID or named as the suites name them and, with -tag, tagged as given, once their
+// expires-at tag is past or, without one, once older than -older-than:
+//
+//	go run ./cmd/janitor -csp aws,azure -dry-run
+//	go run ./cmd/janitor -tag compliance-as-code:run-id=20201018t060652-1a2b3c4d -dry-run
+//
+// prints the plan, and without -dry-run the resources are deleted, buckets emptied first, with -workers deletions at a
+// time. Resources are not deleted in an environment that guardrail.yaml protects, unless given in GUARDRAIL_OVERRIDE.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"citihub.com/compliance-as-code/internal/csp"
+	"citihub.com/compliance-as-code/internal/guardrail"
+	"citihub.com/compliance-as-code/internal/janitor"
+)
+
+// tagFlag collects the -tag flags.
+type tagFlag map[string]string
+
+func (t tagFlag) String() string {
+	var tags []string
+	for k, v := range t {
+		tags = append(tags, k+"="+v)
+	}
+	return strings.Join(tags, ",")
+}
+
+func (t tagFlag) Set(s string) error {
+	kv := strings.SplitN(s, "=", 2)
+	if len(kv) != 2 || kv[0] == "" {
+		return fmt.Errorf("expected key=value, got '%s'", s)
+	}
+	t[kv[0]] = kv[1]
+	return nil
+}
+
+func main() {
+	providers := flag.String("csp", "aws,azure", "comma-separated providers to clean up")
+	olderThan := flag.Duration("older-than", 24*time.Hour, "minimum age of the resources without an expires-at tag to delete, younger ones may belong to a run in progress")
+	tags := tagFlag{}
+	flag.Var(tags, "tag", "key=value tag selecting the resources of the suites that have it rather than all of them; may be repeated, resources must have all")
+	unknownAge := flag.Bool("unknown-age", false, "also delete the resources whose age cannot be told, e.g. resource groups whose createdTime Azure does not return")
+	workers := flag.Int("workers", 4, "maximum number of deletions at a time")
+	dryRun := flag.Bool("dry-run", false, "print the resources to delete without deleting them")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [-csp aws,azure] [-older-than 24h] [-tag key=value]... [-workers n] [-dry-run]\n\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() > 0 || *workers < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ctx := context.Background()
+	opts := janitor.Options{OlderThan: *olderThan, Tags: tags, UnknownAge: *unknownAge, Now: time.Now()}
+	var plan []janitor.Candidate
+	failed := 0
+	for _, p := range strings.Split(*providers, ",") {
+		p = strings.ToLower(strings.TrimSpace(p))
+		if p == "" {
+			continue
+		}
+		found, err := janitor.Find(ctx, p, opts)
+		if err != nil {
+			log.Printf("[ERROR] Cannot find the resources of %s: %v", csp.DisplayName(p), err)
+			failed++
+			continue
+		}
+		fmt.Printf("%s: %d resource(s) to delete\n", csp.DisplayName(p), len(found))
+		for _, c := range found {
+			fmt.Printf("  %v\n", c)
+		}
+		if len(found) == 0 || *dryRun {
+			continue
+		}
+		if d := guardrail.Check(p); !d.Allowed {
+			log.Printf("[ERROR] Not deleting the resources of %s: %s", csp.DisplayName(p), d.Reason)
+			failed += len(found)
+			continue
+		}
+		plan = append(plan, found...)
+	}
+
+	if len(plan) > 0 {
+		fmt.Printf("\nDeleting %d resource(s)\n", len(plan))
+		for _, r := range janitor.Delete(ctx, plan, *workers) {
+			if r.Err != nil {
+				fmt.Printf("  failed  %s %s %s: %v\n", r.Provider, r.Kind, r.Name, r.Err)
+				failed++
+			} else {
+				fmt.Printf("  deleted %s %s %s\n", r.Provider, r.Kind, r.Name)
+			}
+		}
+	}
+	if failed > 0 {
+		log.Fatalf("[ERROR] %d resource(s) or provider(s) could not be cleaned up", failed)
+	}
+}
@@ -0,0 +1,88 @@
+// Command oscal exports the results of the suites as an OSCAL Assessment Results document and validates it against the
+// NIST schema of the OSCAL version it claims, vendored with cmd/schemas, and the stricter schema of the export kept in the
+// repository. Each argument is the results of the suites run against a provider, written with
+// -godog.format=cucumber, -evidence names the file that the runs recorded their evidence in (see internal/evidence), and
+// -assessment-plan the OSCAL Assessment Plan document that the suites were run for, which the results import:
+//
+//	go run ./cmd/oscal -assessment-plan https://example.com/assessment-plan.json -evidence results/evidence.jsonl -o results/assessment-results.json aws=results/aws.json azure=results/azure.json
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+
+	"citihub.com/compliance-as-code/internal/controls"
+	"citihub.com/compliance-as-code/internal/evidence"
+	"citihub.com/compliance-as-code/internal/oscal"
+	"citihub.com/compliance-as-code/internal/results"
+)
+
+func main() {
+	catalog := flag.String("catalog", "catalog/controls.yaml", "control catalog")
+	schema := flag.String("schema", oscal.SchemaFile, "NIST OSCAL Assessment Results schema, vendored from "+oscal.SchemaURL)
+	exportSchema := flag.String("export-schema", "catalog/oscal_export_schema.json", "schema of the export, which rejects the fields it does not write")
+	plan := flag.String("assessment-plan", "", "location of the OSCAL Assessment Plan document that the suites were run for, required")
+	evidenceFile := flag.String("evidence", "", "evidence recorded by the runs, with the "+evidence.EnvVar+" environment variable, required")
+	title := flag.String("title", "Compliance as Code Assessment Results", "title of the document")
+	version := flag.String("version", "1.0", "version of the document, e.g. the build that ran the suites")
+	out := flag.String("o", "", "output file, standard output if empty")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s -assessment-plan href -evidence file [-o file] provider=results.json ...\n\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 || *plan == "" || *evidenceFile == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	c, err := controls.Load(*catalog)
+	if err != nil {
+		log.Fatalf("[ERROR] Cannot load control catalog: %v", err)
+	}
+
+	var runs []results.Run
+	for _, arg := range flag.Args() {
+		i := strings.Index(arg, "=")
+		if i <= 0 {
+			log.Fatalf("[ERROR] Argument %v is not provider=results.json", arg)
+		}
+		r, err := results.Load(arg[:i], arg[i+1:])
+		if err != nil {
+			log.Fatalf("[ERROR] Cannot load results: %v", err)
+		}
+		runs = append(runs, r)
+	}
+
+	recorded, err := evidence.Load(*evidenceFile)
+	if err != nil {
+		log.Fatalf("[ERROR] Cannot load evidence: %v", err)
+	}
+
+	doc, err := oscal.Export(oscal.Options{Title: *title, Version: *version, Catalog: c, CatalogHref: *catalog, AssessmentPlanHref: *plan}, runs, recorded)
+	if err != nil {
+		log.Fatalf("[ERROR] Cannot export results: %v", err)
+	}
+	b, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		log.Fatalf("[ERROR] Cannot write document: %v", err)
+	}
+	for _, sf := range []string{*schema, *exportSchema} {
+		if err := oscal.Validate(sf, b); err != nil {
+			log.Fatalf("[ERROR] %v", err)
+		}
+	}
+
+	if *out == "" {
+		fmt.Println(string(b))
+		return
+	}
+	if err := ioutil.WriteFile(*out, append(b, '\n'), 0644); err != nil {
+		log.Fatalf("[ERROR] Cannot write document: %v", err)
+	}
+}
@@ -0,0 +1,136 @@
+// Command recover replays the ledger of the resources created by the suites (see internal/ledger) and deletes those that
+// were not deleted, e.g. because a run panicked or was killed before its teardown:
+//
+//	go run ./cmd/recover -dry-run
+//
+// lists them, and without -dry-run deletes them, most recently created first, buckets emptied first, and journals their
+// deletion. The ledger is the one of the suites, i.e. named by the LEDGER_FILE environment variable or in the temporary
+// directory. The resources of the runs still in progress are left alone, and -run selects the resources of one run:
+//
+//	go run ./cmd/recover -run 20201018t060652-1a2b3c4d
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"citihub.com/compliance-as-code/internal/awsutil"
+	"citihub.com/compliance-as-code/internal/azureutil/resource"
+	"citihub.com/compliance-as-code/internal/clouderr"
+	"citihub.com/compliance-as-code/internal/ledger"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// azureAPIVersions are the API versions that Azure resources are deleted with, by kind, those of the SDK packages that
+// create them in internal/azureutil.
+var azureAPIVersions = map[string]string{
+	ledger.KindResourceGroup:        "2018-02-01",
+	ledger.KindStorageAccount:       "2019-04-01",
+	ledger.KindSQLServer:            "2015-05-01-preview",
+	ledger.KindVirtualNetwork:       "2019-08-01",
+	ledger.KindNetworkSecurityGroup: "2019-08-01",
+	ledger.KindNetworkInterface:     "2019-08-01",
+	ledger.KindPublicIPAddress:      "2019-08-01",
+}
+
+func main() {
+	run := flag.String("run", "", "ID of the run whose resources to delete, all the runs that ended if empty")
+	dryRun := flag.Bool("dry-run", false, "list the resources to delete without deleting them")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [-run id] [-dry-run]\n\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	path := ledger.Path()
+	entries, err := ledger.Pending(path)
+	if err != nil {
+		log.Fatalf("[ERROR] Cannot replay ledger: %v", err)
+	}
+	pending := selectRuns(entries, *run)
+	if len(pending) == 0 {
+		fmt.Printf("No resources left behind in %v\n", path)
+		return
+	}
+
+	if *dryRun {
+		fmt.Printf("%d resource(s) left behind in %v:\n", len(pending), path)
+		for _, r := range pending {
+			fmt.Printf("  %v\n", r)
+		}
+		return
+	}
+
+	ctx := context.Background()
+	failed := 0
+	for _, r := range pending {
+		fmt.Printf("Deleting %v\n", r)
+		err := remove(ctx, r)
+		if clouderr.Classify(err) == clouderr.KindNotFound {
+			log.Printf("[DEBUG] %v no longer exists", r)
+			err = nil
+		}
+		if err != nil {
+			log.Printf("[ERROR] Cannot delete %v: %v", r, err)
+			failed++
+			continue
+		}
+		ledger.Release(r)
+	}
+	if failed > 0 {
+		log.Fatalf("[ERROR] %d of %d resource(s) could not be deleted, run recover again or delete them manually", failed, len(pending))
+	}
+}
+
+// selectRuns returns the resources of the run, or of all the runs when run is empty, except the runs still in progress,
+// any of whose processes is alive. The runs of other hosts may be in progress, so they are only selected by their ID.
+func selectRuns(entries []ledger.Entry, run string) []ledger.Resource {
+	alive := map[string]bool{}
+	for _, e := range entries {
+		if e.Alive() && (run == "" || e.Local()) {
+			alive[e.RunID] = true
+		}
+	}
+	var selected []ledger.Resource
+	skipped := map[string]int{}
+	for _, e := range entries {
+		switch {
+		case run != "" && e.RunID != run:
+		case alive[e.RunID]:
+			skipped[e.RunID]++
+		default:
+			selected = append(selected, e.Resource)
+		}
+	}
+	for id, n := range skipped {
+		log.Printf("[WARN] Leaving %d resource(s) of run %s, which is still in progress or was run on another host", n, id)
+	}
+	return selected
+}
+
+// remove deletes a resource and waits for the deletion to complete.
+func remove(ctx context.Context, r ledger.Resource) error {
+	switch r.Provider {
+	case ledger.AWS:
+		if r.Kind != ledger.KindBucket {
+			break
+		}
+		sess, err := session.NewSession(&aws.Config{Region: aws.String(r.Region)})
+		if err != nil {
+			return err
+		}
+		return awsutil.DeleteBucket(ctx, s3.New(sess), r.ID)
+	case ledger.Azure:
+		v, ok := azureAPIVersions[r.Kind]
+		if !ok {
+			break
+		}
+		return resource.DeleteByID(ctx, r.ID, v)
+	}
+	return fmt.Errorf("cannot delete resources of kind %s of %s", r.Kind, r.Provider)
+}
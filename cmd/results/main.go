@@ -0,0 +1,105 @@
+// Command results aggregates the results of the suites by the controls of external frameworks, e.g. NIST SP 800-53, that the
+// @CCO: tags of the features are mapped to in the crosswalk. Each argument is the results of the suites run against a
+// provider, written with -godog.format=cucumber:
+//
+//	go run ./cmd/results -framework nist-800-53 aws=results/aws.json azure=results/azure.json
+//
+// prints e.g. "SC-8: 2/3 scenarios passing on AWS". The scenarios skipped by the guardrail are told from those that are not
+// applicable by its decision, which the results written by cmd/run hold; for results written by go test, -evidence names
+// the file that the runs recorded their evidence in (see internal/evidence).
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"citihub.com/compliance-as-code/internal/controls"
+	"citihub.com/compliance-as-code/internal/csp"
+	"citihub.com/compliance-as-code/internal/evidence"
+	"citihub.com/compliance-as-code/internal/results"
+)
+
+func main() {
+	catalog := flag.String("catalog", "catalog/controls.yaml", "control catalog")
+	crosswalk := flag.String("crosswalk", "catalog/crosswalk.yaml", "crosswalk from the controls of the catalog to external frameworks")
+	framework := flag.String("framework", "", "ID of the framework to report on, e.g. nist-800-53, all frameworks if empty")
+	format := flag.String("format", "text", "output format, text or json")
+	evidenceFile := flag.String("evidence", "", "evidence recorded by the runs, with the "+evidence.EnvVar+" environment variable, for the decisions of the guardrail")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [-framework id] [-format text|json] [-evidence file] provider=results.json ...\n\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	c, err := controls.Load(*catalog)
+	if err != nil {
+		log.Fatalf("[ERROR] Cannot load control catalog: %v", err)
+	}
+	cw, err := controls.LoadCrosswalk(*crosswalk, c)
+	if err != nil {
+		log.Fatalf("[ERROR] Cannot load crosswalk: %v", err)
+	}
+	if _, ok := cw.Framework(*framework); *framework != "" && !ok {
+		log.Fatalf("[ERROR] Framework %v is not in crosswalk %v", *framework, *crosswalk)
+	}
+
+	var recorded []evidence.Scenario
+	if *evidenceFile != "" {
+		if recorded, err = evidence.Load(*evidenceFile); err != nil {
+			log.Fatalf("[ERROR] Cannot load evidence: %v", err)
+		}
+	}
+
+	var runs []results.Run
+	for _, arg := range flag.Args() {
+		i := strings.Index(arg, "=")
+		if i <= 0 {
+			log.Fatalf("[ERROR] Argument %v is not provider=results.json", arg)
+		}
+		r, err := results.Load(arg[:i], arg[i+1:])
+		if err != nil {
+			log.Fatalf("[ERROR] Cannot load results: %v", err)
+		}
+		r.Annotate(recorded)
+		runs = append(runs, r)
+	}
+
+	fcs := results.ByFramework(cw, *framework, runs)
+	switch *format {
+	case "json":
+		e := json.NewEncoder(os.Stdout)
+		e.SetIndent("", "  ")
+		if err := e.Encode(fcs); err != nil {
+			log.Fatalf("[ERROR] Cannot write results: %v", err)
+		}
+	case "text":
+		printText(cw, fcs)
+	default:
+		log.Fatalf("[ERROR] Unknown format %v, use text or json", *format)
+	}
+}
+
+func printText(cw *controls.Crosswalk, fcs []results.FrameworkControl) {
+	framework := ""
+	for _, fc := range fcs {
+		if fc.Framework != framework {
+			framework = fc.Framework
+			f, _ := cw.Framework(framework)
+			fmt.Printf("\n%s\n", f.Name)
+		}
+
+		var tallies []string
+		for _, p := range fc.ProviderNames() {
+			tallies = append(tallies, fmt.Sprintf("%s on %s", fc.Providers[p], csp.DisplayName(p)))
+		}
+		fmt.Printf("  %s: %s (%s)\n", fc.Control, strings.Join(tallies, "; "), strings.Join(fc.Controls, ", "))
+	}
+}
@@ -0,0 +1,178 @@
+// Command run runs the suites of every feature in one process, against each of the selected providers, and prints a
+// combined report. Scenarios are selected with a godog tag expression:
+//
+//	go run ./cmd/run -csp aws,azure -tags "@preventative && ~@intrusive_test" -o results
+//
+// writes the combined report to results/report.json and the results of each provider, in the cucumber format, to e.g.
+// results/aws.json for cmd/results and cmd/oscal, with the decision of the guardrail for the scenarios that it stopped. The
+// exit status is 1 when the results fail the -fail-on policy.
+// The report includes the decisions of the guardrail on whether @intrusive_test scenarios may run on each provider, and
+// the run ID that the resources created by the suites are tagged with, e.g. to delete them with cmd/janitor.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"citihub.com/compliance-as-code/internal/csp"
+	"citihub.com/compliance-as-code/internal/guardrail"
+	"citihub.com/compliance-as-code/internal/results"
+	"citihub.com/compliance-as-code/internal/suite"
+	"citihub.com/compliance-as-code/internal/tagging"
+)
+
+// report is the combined report of the runs.
+type report struct {
+	RunID     string               `json:"run_id"`
+	Runs      []results.Run        `json:"runs"`
+	Guardrail []guardrail.Decision `json:"guardrail,omitempty"`
+}
+
+func main() {
+	root := flag.String("root", "test/features", "directory of the features, including its sub-directories")
+	providers := flag.String("csp", os.Getenv(csp.EnvVar), "comma-separated providers to run the features against, e.g. aws,azure")
+	tags := flag.String("tags", "", "godog tag expression selecting the scenarios, e.g. \"@preventative && ~@intrusive_test\"")
+	out := flag.String("o", "", "directory to write the combined report and the results of each provider to")
+	failOn := flag.String("fail-on", suite.FailOnFailed, "exit policy, one of "+strings.Join(suite.Policies, ", "))
+	keepFailed := flag.Bool("keep-failed", csp.KeepFailed(), "keep the resources of the scenarios that fail for debugging, see "+csp.KeepFailedEnvVar)
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [-csp aws,azure] [-tags expression] [-o dir] [-fail-on policy] [-keep-failed]\n\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() > 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if !contains(suite.Policies, *failOn) {
+		log.Fatalf("[ERROR] Unknown exit policy %v, use one of %v", *failOn, suite.Policies)
+	}
+
+	var p []string
+	for _, name := range strings.Split(*providers, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			p = append(p, name)
+		}
+	}
+	if len(p) == 0 {
+		log.Printf("[WARN] No provider given with -csp or the %s environment variable, only the internal suites are run", csp.EnvVar)
+	}
+
+	// The suites run in this process
+	os.Setenv(csp.KeepFailedEnvVar, strconv.FormatBool(*keepFailed))
+	runID := tagging.RunID()
+	runs, err := suite.Run(suite.Options{Root: *root, Providers: p, Tags: *tags})
+	if err != nil {
+		log.Fatalf("[ERROR] %v", err)
+	}
+
+	r := report{RunID: runID, Runs: runs, Guardrail: guardrail.Decisions()}
+	printText(r)
+	if *out != "" {
+		if err := write(*out, r); err != nil {
+			log.Fatalf("[ERROR] Cannot write results: %v", err)
+		}
+	}
+
+	fail, err := suite.Fails(*failOn, runs)
+	if err != nil {
+		log.Fatalf("[ERROR] %v", err)
+	}
+	if fail {
+		os.Exit(1)
+	}
+}
+
+func contains(values []string, v string) bool {
+	for _, e := range values {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
+
+func printText(rep report) {
+	fmt.Printf("Run %s\n", rep.RunID)
+	for _, r := range rep.Runs {
+		var total results.Tally
+		var lines []string
+		for _, f := range r.Features {
+			var t results.Tally
+			var listed []string
+			for _, s := range f.Elements {
+				outcome := s.Outcome()
+				t.Add(outcome)
+				total.Add(outcome)
+				switch outcome {
+				case results.Failed:
+					listed = append(listed, fmt.Sprintf("    failed: %s%s", s.Name, reason(s)))
+				case results.SkippedGuardrail:
+					listed = append(listed, fmt.Sprintf("    skipped by the guardrail: %s - %s", s.Name, s.Guardrail.Reason))
+				}
+			}
+			lines = append(lines, fmt.Sprintf("  %s (%s): %s", f.Name, f.URI, t))
+			lines = append(lines, listed...)
+		}
+
+		fmt.Printf("\n%s: %s\n", csp.DisplayName(r.Provider), total)
+		for _, l := range lines {
+			fmt.Println(l)
+		}
+	}
+
+	if len(rep.Guardrail) > 0 {
+		fmt.Println("\nGuardrail:")
+		for _, d := range rep.Guardrail {
+			fmt.Printf("  %v\n", d)
+		}
+	}
+}
+
+// reason returns the error of the step that failed the scenario, or why it failed.
+func reason(s results.Scenario) string {
+	for _, st := range s.Steps {
+		switch st.Result.Status {
+		case results.StatusFailed:
+			return " - " + st.Result.ErrorMessage
+		case results.StatusUndefined:
+			return fmt.Sprintf(" - step '%s%s' has no step definition", st.Keyword, st.Name)
+		}
+	}
+	return ""
+}
+
+// write writes the combined report to report.json and the results of each provider to <provider>.json in dir.
+func write(dir string, rep report) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if err := writeJSON(filepath.Join(dir, "report.json"), rep); err != nil {
+		return err
+	}
+	for _, r := range rep.Runs {
+		features := r.Features
+		if features == nil {
+			features = []results.Feature{}
+		}
+		if err := writeJSON(filepath.Join(dir, r.Provider+".json"), features); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeJSON(path string, v interface{}) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(b, '\n'), 0644)
+}
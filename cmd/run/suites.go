@@ -0,0 +1,13 @@
+package main
+
+// The suites run by the runner, which register themselves with internal/suite. A suite whose package is not imported here
+// is reported by the runner as soon as one of its features is selected.
+import (
+	_ "citihub.com/compliance-as-code/test/features/general/feature_validation"
+	_ "citihub.com/compliance-as-code/test/features/general/object_storage/general/access_whitelisting"
+	_ "citihub.com/compliance-as-code/test/features/general/object_storage/general/encryption_at_rest"
+	_ "citihub.com/compliance-as-code/test/features/general/object_storage/general/encryption_in_flight"
+	_ "citihub.com/compliance-as-code/test/features/general/policy_drift"
+	_ "citihub.com/compliance-as-code/test/features/general/policy_evaluation"
+	_ "citihub.com/compliance-as-code/test/features/general/policy_validation"
+)
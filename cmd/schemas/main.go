@@ -0,0 +1,84 @@
+// Command schemas vendors the published JSON schemas that the repository validates against, so that validation runs
+// offline and against the schema of the version that is claimed rather than the latest one. Each schema is fetched from
+// where it is published, at a pinned version, and written to its path in the repository:
+//
+//	go run ./cmd/schemas
+//
+// With -check, it writes nothing and exits with status 1 when a vendored schema differs from the published one.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"citihub.com/compliance-as-code/internal/azurepolicy"
+	"citihub.com/compliance-as-code/internal/oscal"
+)
+
+// schema is a published schema and the path it is vendored in, relative to the root of the repository.
+type schema struct {
+	URL  string
+	Path string
+}
+
+var schemas = []schema{
+	{URL: oscal.SchemaURL, Path: oscal.SchemaFile},
+	{URL: azurepolicy.SchemaURL, Path: azurepolicy.SchemaFile},
+}
+
+func main() {
+	check := flag.Bool("check", false, "check that the vendored schemas are those published rather than vendor them")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [-check]\n\nRun from the root of the repository.\n\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	client := &http.Client{Timeout: time.Minute}
+	stale := false
+	for _, s := range schemas {
+		b, err := fetch(client, s.URL)
+		if err != nil {
+			log.Fatalf("[ERROR] Cannot fetch schema %v: %v", s.URL, err)
+		}
+
+		if *check {
+			vendored, err := ioutil.ReadFile(s.Path)
+			if err != nil || !bytes.Equal(vendored, b) {
+				fmt.Printf("%s is not the schema published at %s\n", s.Path, s.URL)
+				stale = true
+			}
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(s.Path), 0755); err != nil {
+			log.Fatalf("[ERROR] Cannot vendor schema %v: %v", s.URL, err)
+		}
+		if err := ioutil.WriteFile(s.Path, b, 0644); err != nil {
+			log.Fatalf("[ERROR] Cannot vendor schema %v: %v", s.URL, err)
+		}
+		fmt.Printf("Vendored %s in %s\n", s.URL, s.Path)
+	}
+	if stale {
+		os.Exit(1)
+	}
+}
+
+func fetch(client *http.Client, url string) ([]byte, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %s", resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
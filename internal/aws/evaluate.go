@@ -0,0 +1,440 @@
+package aws
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Condition keys populated from the fields of a Request.
+const (
+	KeySourceIP        = "aws:SourceIp"
+	KeySecureTransport = "aws:SecureTransport"
+	KeySourceVpce      = "aws:sourceVpce"
+	KeySourceVpc       = "aws:SourceVpc"
+	KeyPrincipalArn    = "aws:PrincipalArn"
+)
+
+// Decision is the outcome of evaluating a PolicyDocument against a Request.
+type Decision string
+
+// Policy evaluation decisions.
+const (
+	// Allowed means that an Allow statement matched and no Deny statement did.
+	Allowed Decision = "Allow"
+	// ExplicitDeny means that at least one Deny statement matched.
+	ExplicitDeny Decision = "Deny"
+	// ImplicitDeny means that no statement matched. For a resource policy the request may still be allowed by the caller's identity policy.
+	ImplicitDeny Decision = "ImplicitDeny"
+)
+
+// Request is a simulated request context used to evaluate a PolicyDocument offline.
+type Request struct {
+	// Principal is the ARN of the caller, e.g. "arn:aws:iam::123456789012:user/alice". Empty for an anonymous caller.
+	Principal string
+	// Action is the API action, e.g. "s3:GetObject".
+	Action string
+	// Resource is the ARN of the resource, e.g. "arn:aws:s3:::bucket/key". Empty matches any Resource element.
+	Resource string
+	// SourceIP populates the aws:SourceIp condition key when set.
+	SourceIP net.IP
+	// SecureTransport populates the aws:SecureTransport condition key, i.e. whether the request was sent over TLS.
+	SecureTransport bool
+	// SourceVpce populates the aws:sourceVpce condition key when set.
+	SourceVpce string
+	// SourceVpc populates the aws:SourceVpc condition key when set.
+	SourceVpc string
+	// Context holds any further condition keys, e.g. "s3:x-amz-server-side-encryption".
+	Context map[string][]string
+}
+
+// Evaluation is the result of evaluating a PolicyDocument against a Request.
+type Evaluation struct {
+	Decision Decision
+	// Matched holds the statements that decided the outcome: every matching Deny statement for ExplicitDeny, every matching Allow statement for Allowed.
+	Matched []Statement
+	// positions are the positions of the Matched statements in the policy, from 1, to name the statements without a Sid.
+	positions []int
+}
+
+// String describes the evaluation, e.g. `Deny (matched: "DenyInsecureTransport")`.
+func (e Evaluation) String() string {
+	if len(e.Matched) == 0 {
+		return string(e.Decision)
+	}
+	var ids []string
+	for i, s := range e.Matched {
+		switch {
+		case s.Sid != "":
+			ids = append(ids, fmt.Sprintf("%q", s.Sid))
+		case i < len(e.positions):
+			ids = append(ids, fmt.Sprintf("statement #%d", e.positions[i]))
+		default:
+			ids = append(ids, "statement without Sid")
+		}
+	}
+	return fmt.Sprintf("%s (matched: %s)", e.Decision, strings.Join(ids, ", "))
+}
+
+// Evaluate evaluates the policy against the request following the AWS policy evaluation logic for a single policy:
+// an explicit Deny overrides any Allow, and a request that matches no statement is implicitly denied.
+func (d PolicyDocument) Evaluate(r Request) (Evaluation, error) {
+	var allows, denies Evaluation
+	for i, s := range d.Statement {
+		ok, err := s.Matches(r)
+		if err != nil {
+			return Evaluation{}, fmt.Errorf("statement #%d (%s): %v", i+1, s.Sid, err)
+		}
+		if !ok {
+			continue
+		}
+		switch s.Effect {
+		case Deny:
+			denies.Matched, denies.positions = append(denies.Matched, s), append(denies.positions, i+1)
+		case Allow:
+			allows.Matched, allows.positions = append(allows.Matched, s), append(allows.positions, i+1)
+		default:
+			return Evaluation{}, fmt.Errorf("statement #%d (%s): unsupported effect '%s'", i+1, s.Sid, s.Effect)
+		}
+	}
+
+	switch {
+	case len(denies.Matched) > 0:
+		denies.Decision = ExplicitDeny
+		return denies, nil
+	case len(allows.Matched) > 0:
+		allows.Decision = Allowed
+		return allows, nil
+	default:
+		return Evaluation{Decision: ImplicitDeny}, nil
+	}
+}
+
+// Matches reports whether the statement applies to the request: its principal, action, resource and every condition must match.
+func (s Statement) Matches(r Request) (bool, error) {
+	if !s.matchesPrincipal(r.Principal) || !s.matchesAction(r.Action) || !s.matchesResource(r.Resource) {
+		return false, nil
+	}
+	return s.Condition.Matches(r)
+}
+
+func (s Statement) matchesPrincipal(principal string) bool {
+	switch {
+	case s.Principal != nil:
+		return s.Principal.Matches(principal)
+	case s.NotPrincipal != nil:
+		return !s.NotPrincipal.Matches(principal)
+	}
+	// Identity policies have no Principal element
+	return true
+}
+
+func (s Statement) matchesAction(action string) bool {
+	switch {
+	case len(s.Action) > 0:
+		return matchAny(s.Action, action, true)
+	case len(s.NotAction) > 0:
+		return !matchAny(s.NotAction, action, true)
+	}
+	return false
+}
+
+func (s Statement) matchesResource(resource string) bool {
+	if resource == "" {
+		return true
+	}
+	switch {
+	case len(s.Resource) > 0:
+		return matchAny(s.Resource, resource, false)
+	case len(s.NotResource) > 0:
+		return !matchAny(s.NotResource, resource, false)
+	}
+	return true
+}
+
+// Matches reports whether the principal element applies to the caller ARN. An AWS account ID or account root ARN applies to every principal in that account.
+func (p *Principal) Matches(principal string) bool {
+	if p == nil {
+		return false
+	}
+	if p.IsEveryone() {
+		return true
+	}
+	if principal == "" {
+		return false
+	}
+	for _, values := range p.Values {
+		for _, v := range values {
+			if matchWildcard(v, principal, false) {
+				return true
+			}
+			if account := principalAccount(principal); account != "" &&
+				(v == account || v == "arn:aws:iam::"+account+":root") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Matches reports whether every condition in the block is satisfied by the request. An empty block always matches.
+func (c Condition) Matches(r Request) (bool, error) {
+	for op, values := range c {
+		for key, expected := range values {
+			actual, present := r.value(key)
+			ok, err := evaluateCondition(op, expected, actual, present)
+			if err != nil {
+				return false, fmt.Errorf("condition %s on '%s': %v", op, key, err)
+			}
+			if !ok {
+				return false, nil
+			}
+		}
+	}
+	return true, nil
+}
+
+// value returns the request values for a condition key. Condition keys are matched case-insensitively.
+func (r Request) value(key string) ([]string, bool) {
+	switch strings.ToLower(key) {
+	case strings.ToLower(KeySourceIP):
+		if r.SourceIP == nil {
+			return nil, false
+		}
+		return []string{r.SourceIP.String()}, true
+	case strings.ToLower(KeySecureTransport):
+		return []string{strconv.FormatBool(r.SecureTransport)}, true
+	case strings.ToLower(KeySourceVpce):
+		if r.SourceVpce == "" {
+			return nil, false
+		}
+		return []string{r.SourceVpce}, true
+	case strings.ToLower(KeySourceVpc):
+		if r.SourceVpc == "" {
+			return nil, false
+		}
+		return []string{r.SourceVpc}, true
+	case strings.ToLower(KeyPrincipalArn):
+		if r.Principal == "" {
+			return nil, false
+		}
+		return []string{r.Principal}, true
+	}
+
+	for k, v := range r.Context {
+		if strings.EqualFold(k, key) {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+func evaluateCondition(op ConditionOperator, expected StringList, actual []string, present bool) (bool, error) {
+	base := op.Base()
+
+	if base == Null {
+		if len(expected) != 1 {
+			return false, fmt.Errorf("the Null operator expects a single \"true\" or \"false\" value")
+		}
+		want, err := strconv.ParseBool(expected[0])
+		if err != nil {
+			return false, err
+		}
+		return want == !present, nil
+	}
+
+	negated := isNegated(base)
+	if !present {
+		// A missing key matches an "IfExists" operator and "ForAllValues:", as every one of no value matches, but not
+		// "ForAnyValue:", as none does, even negated. Otherwise it matches a negated operator such as StringNotEquals.
+		switch {
+		case op.IfExists() || op.ForAllValues():
+			return true, nil
+		case op.ForAnyValue():
+			return false, nil
+		}
+		return negated, nil
+	}
+
+	match := func(v string) (bool, error) {
+		for _, e := range expected {
+			ok, err := compare(base, e, v)
+			if err != nil {
+				return false, err
+			}
+			if ok {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	if op.ForAllValues() {
+		for _, v := range actual {
+			ok, err := match(v)
+			if err != nil {
+				return false, err
+			}
+			if ok == negated {
+				return false, nil
+			}
+		}
+		return true, nil
+	}
+
+	for _, v := range actual {
+		ok, err := match(v)
+		if err != nil {
+			return false, err
+		}
+		if ok != negated {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func isNegated(op ConditionOperator) bool {
+	switch op {
+	case StringNotEquals, StringNotEqualsIgnoreCase, StringNotLike, NumericNotEquals, DateNotEquals, NotIPAddress, ArnNotEquals, ArnNotLike:
+		return true
+	}
+	return false
+}
+
+// compare compares a single expected value with a single request value using the positive form of the operator.
+func compare(op ConditionOperator, expected, actual string) (bool, error) {
+	switch op {
+	case StringEquals, StringNotEquals:
+		return expected == actual, nil
+	case StringEqualsIgnoreCase, StringNotEqualsIgnoreCase:
+		return strings.EqualFold(expected, actual), nil
+	case StringLike, StringNotLike, ArnEquals, ArnNotEquals, ArnLike, ArnNotLike:
+		return matchWildcard(expected, actual, false), nil
+	case Bool:
+		return strings.EqualFold(expected, actual), nil
+	case BinaryEquals:
+		// Both values are base64 encoded
+		return expected == actual, nil
+	case IPAddress, NotIPAddress:
+		return matchIP(expected, actual)
+	case NumericEquals, NumericNotEquals, NumericLessThan, NumericLessThanEquals, NumericGreaterThan, NumericGreaterThanEquals:
+		e, err := strconv.ParseFloat(expected, 64)
+		if err != nil {
+			return false, err
+		}
+		a, err := strconv.ParseFloat(actual, 64)
+		if err != nil {
+			return false, err
+		}
+		return compareOrdered(op, a-e), nil
+	case DateEquals, DateNotEquals, DateLessThan, DateLessThanEquals, DateGreaterThan, DateGreaterThanEquals:
+		e, err := parseDate(expected)
+		if err != nil {
+			return false, err
+		}
+		a, err := parseDate(actual)
+		if err != nil {
+			return false, err
+		}
+		return compareOrdered(op, float64(a.Sub(e))), nil
+	}
+	return false, fmt.Errorf("unsupported condition operator")
+}
+
+func compareOrdered(op ConditionOperator, diff float64) bool {
+	switch op {
+	case NumericLessThan, DateLessThan:
+		return diff < 0
+	case NumericLessThanEquals, DateLessThanEquals:
+		return diff <= 0
+	case NumericGreaterThan, DateGreaterThan:
+		return diff > 0
+	case NumericGreaterThanEquals, DateGreaterThanEquals:
+		return diff >= 0
+	}
+	return diff == 0
+}
+
+func parseDate(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	epoch, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("'%s' is neither an ISO 8601 date nor an epoch time", s)
+	}
+	return time.Unix(epoch, 0).UTC(), nil
+}
+
+func matchIP(expected, actual string) (bool, error) {
+	ip := net.ParseIP(actual)
+	if ip == nil {
+		return false, fmt.Errorf("'%s' is not an IP address", actual)
+	}
+	if !strings.Contains(expected, "/") {
+		e := net.ParseIP(expected)
+		if e == nil {
+			return false, fmt.Errorf("'%s' is not an IP address or CIDR block", expected)
+		}
+		return e.Equal(ip), nil
+	}
+	_, cidr, err := net.ParseCIDR(expected)
+	if err != nil {
+		return false, err
+	}
+	return cidr.Contains(ip), nil
+}
+
+func principalAccount(arn string) string {
+	// arn:aws:iam::123456789012:user/name
+	parts := strings.SplitN(arn, ":", 6)
+	if len(parts) < 6 {
+		return ""
+	}
+	return parts[4]
+}
+
+func matchAny(patterns StringList, value string, fold bool) bool {
+	for _, p := range patterns {
+		if matchWildcard(p, value, fold) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchWildcard matches the value against a pattern where '*' matches any sequence of characters and '?' matches any single character.
+func matchWildcard(pattern, value string, fold bool) bool {
+	if fold {
+		pattern, value = strings.ToLower(pattern), strings.ToLower(value)
+	}
+
+	p, v := 0, 0
+	star, mark := -1, 0
+	for v < len(value) {
+		switch {
+		case p < len(pattern) && (pattern[p] == '?' || pattern[p] == value[v]):
+			p++
+			v++
+		case p < len(pattern) && pattern[p] == '*':
+			star, mark = p, v
+			p++
+		case star >= 0:
+			p = star + 1
+			mark++
+			v = mark
+		default:
+			return false
+		}
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
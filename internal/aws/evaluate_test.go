@@ -0,0 +1,65 @@
+package aws
+
+import (
+	"testing"
+)
+
+func TestEvaluationNumbersStatementsByTheirPositionInThePolicy(t *testing.T) {
+	d, err := ParsePolicyDocument(`{
+		"Version": "2012-10-17",
+		"Statement": [
+			{"Sid": "AllowRead", "Effect": "Allow", "Principal": "*", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::bucket/*"},
+			{"Effect": "Allow", "Principal": "*", "Action": "s3:PutObject", "Resource": "arn:aws:s3:::bucket/*"},
+			{"Effect": "Deny", "Principal": "*", "Action": "s3:*", "Resource": "arn:aws:s3:::bucket/*",
+				"Condition": {"Bool": {"aws:SecureTransport": "false"}}}
+		]
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	eval, err := d.Evaluate(Request{Action: "s3:GetObject", Resource: "arn:aws:s3:::bucket/key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Deny (matched: statement #3)"; eval.String() != want {
+		t.Errorf("String() = %q, want %q", eval.String(), want)
+	}
+
+	eval, err = d.Evaluate(Request{Action: "s3:PutObject", Resource: "arn:aws:s3:::bucket/key", SecureTransport: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Allow (matched: statement #2)"; eval.String() != want {
+		t.Errorf("String() = %q, want %q", eval.String(), want)
+	}
+}
+
+func TestConditionOnMissingKey(t *testing.T) {
+	tests := []struct {
+		op   ConditionOperator
+		want bool
+	}{
+		{StringEquals, false},
+		{StringNotEquals, true},
+		{"StringEqualsIfExists", true},
+		{"ForAllValues:StringEquals", true},
+		{"ForAllValues:StringNotEquals", true},
+		{"ForAnyValue:StringEquals", false},
+		{"ForAnyValue:StringNotEquals", false},
+		{"ForAnyValue:StringNotLike", false},
+		{"ForAnyValue:NotIpAddress", false},
+		{"ForAnyValue:StringNotEqualsIfExists", true},
+	}
+	for _, tt := range tests {
+		c := Condition{tt.op: ConditionValues{"aws:PrincipalTag/team": StringList{"security"}}}
+		got, err := c.Matches(Request{Action: "s3:GetObject"})
+		if err != nil {
+			t.Errorf("%s: %v", tt.op, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s on a missing key = %v, want %v", tt.op, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,285 @@
+package aws
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Effect is the effect of a policy Statement, either Allow or Deny.
+type Effect string
+
+// Statement effects.
+const (
+	Allow Effect = "Allow"
+	Deny  Effect = "Deny"
+)
+
+// ConditionOperator is a condition operator used in the Condition block of a policy Statement, e.g. "IpAddress" or "StringNotEqualsIfExists".
+type ConditionOperator string
+
+// Condition operators, see https://docs.aws.amazon.com/IAM/latest/UserGuide/reference_policies_elements_condition_operators.html
+const (
+	StringEquals              ConditionOperator = "StringEquals"
+	StringNotEquals           ConditionOperator = "StringNotEquals"
+	StringEqualsIgnoreCase    ConditionOperator = "StringEqualsIgnoreCase"
+	StringNotEqualsIgnoreCase ConditionOperator = "StringNotEqualsIgnoreCase"
+	StringLike                ConditionOperator = "StringLike"
+	StringNotLike             ConditionOperator = "StringNotLike"
+	NumericEquals             ConditionOperator = "NumericEquals"
+	NumericNotEquals          ConditionOperator = "NumericNotEquals"
+	NumericLessThan           ConditionOperator = "NumericLessThan"
+	NumericLessThanEquals     ConditionOperator = "NumericLessThanEquals"
+	NumericGreaterThan        ConditionOperator = "NumericGreaterThan"
+	NumericGreaterThanEquals  ConditionOperator = "NumericGreaterThanEquals"
+	DateEquals                ConditionOperator = "DateEquals"
+	DateNotEquals             ConditionOperator = "DateNotEquals"
+	DateLessThan              ConditionOperator = "DateLessThan"
+	DateLessThanEquals        ConditionOperator = "DateLessThanEquals"
+	DateGreaterThan           ConditionOperator = "DateGreaterThan"
+	DateGreaterThanEquals     ConditionOperator = "DateGreaterThanEquals"
+	Bool                      ConditionOperator = "Bool"
+	BinaryEquals              ConditionOperator = "BinaryEquals"
+	IPAddress                 ConditionOperator = "IpAddress"
+	NotIPAddress              ConditionOperator = "NotIpAddress"
+	ArnEquals                 ConditionOperator = "ArnEquals"
+	ArnNotEquals              ConditionOperator = "ArnNotEquals"
+	ArnLike                   ConditionOperator = "ArnLike"
+	ArnNotLike                ConditionOperator = "ArnNotLike"
+	Null                      ConditionOperator = "Null"
+)
+
+const (
+	ifExistsSuffix     = "IfExists"
+	forAnyValuePrefix  = "ForAnyValue:"
+	forAllValuesPrefix = "ForAllValues:"
+)
+
+// Base returns the operator without any "ForAnyValue:"/"ForAllValues:" set qualifier and "IfExists" suffix, e.g. "StringLike" for "ForAnyValue:StringLikeIfExists".
+func (o ConditionOperator) Base() ConditionOperator {
+	s := string(o)
+	s = strings.TrimPrefix(s, forAnyValuePrefix)
+	s = strings.TrimPrefix(s, forAllValuesPrefix)
+	if o != Null {
+		s = strings.TrimSuffix(s, ifExistsSuffix)
+	}
+	return ConditionOperator(s)
+}
+
+// IfExists reports whether the operator carries the "IfExists" suffix, i.e. evaluates to true when the condition key is absent.
+func (o ConditionOperator) IfExists() bool {
+	return strings.HasSuffix(string(o), ifExistsSuffix)
+}
+
+// ForAllValues reports whether the operator carries the "ForAllValues:" set qualifier.
+func (o ConditionOperator) ForAllValues() bool {
+	return strings.HasPrefix(string(o), forAllValuesPrefix)
+}
+
+// ForAnyValue reports whether the operator carries the "ForAnyValue:" set qualifier.
+func (o ConditionOperator) ForAnyValue() bool {
+	return strings.HasPrefix(string(o), forAnyValuePrefix)
+}
+
+// PolicyDocument is an IAM identity or resource (e.g. S3 bucket) policy.
+type PolicyDocument struct {
+	Version   string     `json:"Version,omitempty"`
+	ID        string     `json:"Id,omitempty"`
+	Statement Statements `json:"Statement"`
+}
+
+// Statements is the Statement element of a PolicyDocument. It is unmarshalled from either a single Statement or a list of Statements, and always marshalled as a list.
+type Statements []Statement
+
+// Statement is a single statement of a PolicyDocument.
+type Statement struct {
+	Sid          string     `json:"Sid,omitempty"`
+	Effect       Effect     `json:"Effect"`
+	Principal    *Principal `json:"Principal,omitempty"`
+	NotPrincipal *Principal `json:"NotPrincipal,omitempty"`
+	Action       StringList `json:"Action,omitempty"`
+	NotAction    StringList `json:"NotAction,omitempty"`
+	Resource     StringList `json:"Resource,omitempty"`
+	NotResource  StringList `json:"NotResource,omitempty"`
+	Condition    Condition  `json:"Condition,omitempty"`
+}
+
+// StringList is a policy element that is either a single value or a list of values, e.g. Action or Resource.
+// A list with a single value is marshalled as a plain string. Boolean and numeric condition values are converted to strings when unmarshalled.
+type StringList []string
+
+// Principal is the Principal or NotPrincipal element of a Statement: either the wildcard "*" or a set of principals keyed by type ("AWS", "Service", "Federated" or "CanonicalUser").
+type Principal struct {
+	Wildcard bool
+	Values   map[string]StringList
+}
+
+// Condition is the Condition element of a Statement, keyed by ConditionOperator.
+type Condition map[ConditionOperator]ConditionValues
+
+// ConditionValues maps condition keys (e.g. "aws:SourceIp") to the values that they are compared with.
+type ConditionValues map[string]StringList
+
+// ParsePolicyDocument parses a JSON policy document, as returned by e.g. S3 GetBucketPolicy.
+func ParsePolicyDocument(policy string) (PolicyDocument, error) {
+	var d PolicyDocument
+	if err := json.Unmarshal([]byte(policy), &d); err != nil {
+		return d, fmt.Errorf("unable to parse policy document: %v", err)
+	}
+	return d, nil
+}
+
+// String returns the JSON representation of the policy document.
+func (d PolicyDocument) String() string {
+	b, err := json.Marshal(d)
+	if err != nil {
+		return fmt.Sprintf("invalid policy document: %v", err)
+	}
+	return string(b)
+}
+
+// UnmarshalJSON accepts either a single Statement object or a list of Statements.
+func (s *Statements) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	if len(b) > 0 && b[0] == '{' {
+		var stmt Statement
+		if err := json.Unmarshal(b, &stmt); err != nil {
+			return err
+		}
+		*s = Statements{stmt}
+		return nil
+	}
+
+	var stmts []Statement
+	if err := json.Unmarshal(b, &stmts); err != nil {
+		return err
+	}
+	*s = stmts
+	return nil
+}
+
+// UnmarshalJSON accepts a single value or a list of values. Boolean and numeric values are converted to strings.
+func (l *StringList) UnmarshalJSON(b []byte) error {
+	var v interface{}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	if err := d.Decode(&v); err != nil {
+		return err
+	}
+
+	switch t := v.(type) {
+	case nil:
+		*l = nil
+	case []interface{}:
+		list := make(StringList, 0, len(t))
+		for _, e := range t {
+			s, err := scalarString(e)
+			if err != nil {
+				return err
+			}
+			list = append(list, s)
+		}
+		*l = list
+	default:
+		s, err := scalarString(t)
+		if err != nil {
+			return err
+		}
+		*l = StringList{s}
+	}
+	return nil
+}
+
+// MarshalJSON returns a single value as a plain string and multiple values as a list.
+func (l StringList) MarshalJSON() ([]byte, error) {
+	if len(l) == 1 {
+		return json.Marshal(l[0])
+	}
+	return json.Marshal([]string(l))
+}
+
+// Contains reports whether the list holds the value, compared case-sensitively.
+func (l StringList) Contains(value string) bool {
+	for _, v := range l {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// UnmarshalJSON accepts either the wildcard "*" or an object keyed by principal type.
+func (p *Principal) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		if s != "*" {
+			return fmt.Errorf("unsupported principal '%s': must be \"*\" or an object", s)
+		}
+		*p = Principal{Wildcard: true}
+		return nil
+	}
+
+	var values map[string]StringList
+	if err := json.Unmarshal(b, &values); err != nil {
+		return fmt.Errorf("unable to parse principal: %v", err)
+	}
+	*p = Principal{Values: values}
+	return nil
+}
+
+// MarshalJSON returns "*" for the wildcard principal, otherwise an object keyed by principal type.
+func (p Principal) MarshalJSON() ([]byte, error) {
+	if p.Wildcard {
+		return json.Marshal("*")
+	}
+	return json.Marshal(p.Values)
+}
+
+// IsEveryone reports whether the principal matches any caller, either as "*" or as {"AWS": "*"}.
+func (p *Principal) IsEveryone() bool {
+	if p == nil {
+		return false
+	}
+	return p.Wildcard || p.Values["AWS"].Contains("*")
+}
+
+// Values returns the values for the condition key under the operator. Condition keys are matched case-insensitively.
+func (c Condition) Values(op ConditionOperator, key string) (StringList, bool) {
+	for k, v := range c[op] {
+		if strings.EqualFold(k, key) {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// Keys returns the condition keys used in the Condition block, sorted alphabetically and without duplicates.
+func (c Condition) Keys() []string {
+	seen := map[string]bool{}
+	var keys []string
+	for _, values := range c {
+		for k := range values {
+			if !seen[strings.ToLower(k)] {
+				seen[strings.ToLower(k)] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func scalarString(v interface{}) (string, error) {
+	switch t := v.(type) {
+	case string:
+		return t, nil
+	case bool:
+		return fmt.Sprintf("%t", t), nil
+	case json.Number:
+		return t.String(), nil
+	default:
+		return "", fmt.Errorf("unsupported policy value %v: must be a string, boolean or number", v)
+	}
+}
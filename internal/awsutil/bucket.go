@@ -0,0 +1,67 @@
+// Package awsutil holds the helpers that the AWS implementations of the suites create resources with.
+package awsutil
+
+import (
+	"context"
+	"fmt"
+
+	"citihub.com/compliance-as-code/internal/tagging"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// TagBucket tags a bucket with the tagging policy carried by the context (see internal/tagging). Buckets cannot be tagged
+// when they are created, so it is to be called right after.
+func TagBucket(ctx context.Context, svc *s3.S3, bucket string) error {
+	tags := tagging.Tags(ctx, nil)
+	set := make([]*s3.Tag, 0, len(tags))
+	for _, k := range tagging.Keys(tags) {
+		set = append(set, &s3.Tag{Key: aws.String(k), Value: aws.String(tags[k])})
+	}
+	_, err := svc.PutBucketTaggingWithContext(ctx, &s3.PutBucketTaggingInput{
+		Bucket:  aws.String(bucket),
+		Tagging: &s3.Tagging{TagSet: set},
+	})
+	if err != nil {
+		return fmt.Errorf("cannot tag bucket %s: %v", bucket, err)
+	}
+	return nil
+}
+
+// DeleteBucket empties a bucket, including the versions of its objects and their delete markers, as only empty buckets
+// can be deleted, and deletes it. svc must be a client of the region of the bucket.
+func DeleteBucket(ctx context.Context, svc *s3.S3, bucket string) error {
+	var deleteErr error
+	err := svc.ListObjectVersionsPagesWithContext(ctx, &s3.ListObjectVersionsInput{Bucket: aws.String(bucket)},
+		func(page *s3.ListObjectVersionsOutput, last bool) bool {
+			var objects []*s3.ObjectIdentifier
+			for _, v := range page.Versions {
+				objects = append(objects, &s3.ObjectIdentifier{Key: v.Key, VersionId: v.VersionId})
+			}
+			for _, m := range page.DeleteMarkers {
+				objects = append(objects, &s3.ObjectIdentifier{Key: m.Key, VersionId: m.VersionId})
+			}
+			if len(objects) == 0 {
+				return true
+			}
+			// A page has at most 1000 versions, as many as can be deleted at once
+			out, err := svc.DeleteObjectsWithContext(ctx, &s3.DeleteObjectsInput{
+				Bucket: aws.String(bucket),
+				Delete: &s3.Delete{Objects: objects, Quiet: aws.Bool(true)},
+			})
+			if err == nil && len(out.Errors) > 0 {
+				e := out.Errors[0]
+				err = fmt.Errorf("cannot delete %d object(s), e.g. %s: %s", len(out.Errors), aws.StringValue(e.Key), aws.StringValue(e.Message))
+			}
+			deleteErr = err
+			return err == nil
+		})
+	if err == nil {
+		err = deleteErr
+	}
+	if err != nil {
+		return err
+	}
+	_, err = svc.DeleteBucketWithContext(ctx, &s3.DeleteBucketInput{Bucket: aws.String(bucket)})
+	return err
+}
@@ -0,0 +1,167 @@
+// Package alias loads the catalog of Azure Policy aliases checked in at catalog/azure_policy_aliases.json, so that
+// policy rules can be checked for unknown aliases and aliases resolved to ARM property paths without calling Azure.
+package alias
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+// Alias is a policy alias of a resource type, e.g. "Microsoft.Storage/storageAccounts/supportsHttpsTrafficOnly".
+type Alias struct {
+	Name        string `json:"name"`
+	Paths       []Path `json:"paths"`
+	Type        string `json:"type"`
+	DefaultPath string `json:"defaultPath"`
+}
+
+// Path is the property path of an alias for a set of API versions.
+type Path struct {
+	Path        string   `json:"path"`
+	APIVersions []string `json:"apiVersions"`
+}
+
+// ResourceType is a resource type of a provider with its aliases.
+type ResourceType struct {
+	ResourceType string  `json:"resourceType"`
+	Aliases      []Alias `json:"aliases"`
+}
+
+// Provider is a resource provider, as listed by `az provider list --expand "resourceTypes/aliases"`.
+type Provider struct {
+	Namespace     string         `json:"namespace"`
+	ResourceTypes []ResourceType `json:"resourceTypes"`
+}
+
+// Catalog is a set of aliases, looked up case-insensitively by name.
+type Catalog struct {
+	aliases map[string]Alias
+}
+
+// Parse parses a catalog in the output format of `az provider list --expand "resourceTypes/aliases"`.
+func Parse(b []byte) (*Catalog, error) {
+	var providers []Provider
+	if err := json.Unmarshal(b, &providers); err != nil {
+		return nil, fmt.Errorf("invalid alias catalog: %v", err)
+	}
+
+	c := &Catalog{aliases: make(map[string]Alias)}
+	for _, p := range providers {
+		for _, rt := range p.ResourceTypes {
+			for _, a := range rt.Aliases {
+				c.aliases[strings.ToLower(a.Name)] = a
+			}
+		}
+	}
+	return c, nil
+}
+
+// Load reads and parses the alias catalog in the named file.
+func Load(path string) (*Catalog, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c, err := Parse(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return c, nil
+}
+
+// Lookup returns the named alias.
+func (c *Catalog) Lookup(name string) (Alias, bool) {
+	a, ok := c.aliases[strings.ToLower(name)]
+	return a, ok
+}
+
+// PropertyPath returns the ARM property path of the named alias, e.g. "properties.encryption.services.blob.enabled" for
+// "Microsoft.Storage/storageAccounts/enableBlobEncryption". It implements azurepolicy.AliasResolver.
+func (c *Catalog) PropertyPath(name string) (string, bool) {
+	a, ok := c.Lookup(name)
+	if !ok {
+		return "", false
+	}
+	if a.DefaultPath != "" {
+		return a.DefaultPath, true
+	}
+	if len(a.Paths) > 0 {
+		return a.Paths[0].Path, true
+	}
+	return "", false
+}
+
+// Validate returns an error if the named alias is not in the catalog, suggesting aliases of the same resource type whose name is close to it.
+func (c *Catalog) Validate(name string) error {
+	if _, ok := c.Lookup(name); ok {
+		return nil
+	}
+
+	if s := c.suggest(name); len(s) > 0 {
+		return fmt.Errorf("unknown alias '%s', did you mean %s?", name, strings.Join(s, " or "))
+	}
+	return fmt.Errorf("unknown alias '%s'", name)
+}
+
+// Names returns the names of every alias in the catalog, sorted alphabetically.
+func (c *Catalog) Names() []string {
+	names := make([]string, 0, len(c.aliases))
+	for _, a := range c.aliases {
+		names = append(names, a.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// suggest returns the aliases of the same resource type within an edit distance of 3 of name.
+func (c *Catalog) suggest(name string) []string {
+	lower := strings.ToLower(name)
+	i := strings.LastIndex(lower, "/")
+	if i < 0 {
+		return nil
+	}
+	prefix := lower[:i+1]
+
+	var s []string
+	for k, a := range c.aliases {
+		if strings.HasPrefix(k, prefix) && distance(k[i+1:], lower[i+1:]) <= 3 {
+			s = append(s, "'"+a.Name+"'")
+		}
+	}
+	sort.Strings(s)
+	return s
+}
+
+// distance returns the Levenshtein distance between a and b.
+func distance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = minOf(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func minOf(v ...int) int {
+	m := v[0]
+	for _, x := range v[1:] {
+		if x < m {
+			m = x
+		}
+	}
+	return m
+}
@@ -0,0 +1,236 @@
+package azurepolicy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ManifestFile is the name of the manifest describing the policy definition of a policy module, e.g.
+// terraform/modules/policies/deny_non_cmk_storage_account/policy_definition.json. Terraform reads the definition from it.
+const ManifestFile = "policy_definition.json"
+
+// SchemaURL is where Microsoft publishes the JSON schema of the policy rules of API version 2019-06-01. The schema is
+// vendored in SchemaFile with cmd/schemas so that policy rules are validated against it offline.
+const SchemaURL = "https://schema.management.azure.com/schemas/2019-06-01/policyDefinition.json"
+
+// SchemaFile is the path of the vendored policy rule schema, relative to the root of the repository.
+var SchemaFile = filepath.Join("catalog", "azure", "2019-06-01", "policyDefinition.json")
+
+// Definition is a custom policy definition, either as deployed or as held in source control.
+type Definition struct {
+	Name        string                 `json:"name"`
+	DisplayName string                 `json:"displayName,omitempty"`
+	Description string                 `json:"description,omitempty"`
+	Mode        string                 `json:"mode,omitempty"`
+	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+	PolicyRule  interface{}            `json:"policyRule"`
+	Parameters  interface{}            `json:"parameters,omitempty"`
+}
+
+// LoadDefinition reads a policy definition manifest. In the manifest, policyRule and parameters may name a JSON file
+// relative to the manifest instead of holding the rule or parameters, in which case the file is read in their place.
+func LoadDefinition(path string) (Definition, error) {
+	var d Definition
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return d, err
+	}
+	if err := decode(b, &d); err != nil {
+		return d, fmt.Errorf("%s: invalid policy definition: %v", path, err)
+	}
+	if d.Name == "" {
+		return d, fmt.Errorf("%s: invalid policy definition: missing \"name\"", path)
+	}
+
+	dir := filepath.Dir(path)
+	if d.PolicyRule, err = include(dir, d.PolicyRule); err != nil {
+		return d, fmt.Errorf("%s: policyRule: %v", path, err)
+	}
+	if d.Parameters, err = include(dir, d.Parameters); err != nil {
+		return d, fmt.Errorf("%s: parameters: %v", path, err)
+	}
+	return d, nil
+}
+
+// ParseDefinition parses a deployed policy definition, either its properties or the full resource holding them under "properties".
+func ParseDefinition(b []byte) (Definition, error) {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return Definition{}, fmt.Errorf("invalid policy definition: %v", err)
+	}
+
+	var d Definition
+	if props, ok := lookupRaw(doc, "properties"); ok {
+		if err := decode(props, &d); err != nil {
+			return d, fmt.Errorf("invalid policy definition properties: %v", err)
+		}
+		if name, ok := lookupRaw(doc, "name"); ok {
+			_ = json.Unmarshal(name, &d.Name)
+		}
+		return d, nil
+	}
+	if err := decode(b, &d); err != nil {
+		return d, fmt.Errorf("invalid policy definition: %v", err)
+	}
+	return d, nil
+}
+
+func include(dir string, v interface{}) (interface{}, error) {
+	file, ok := v.(string)
+	if !ok {
+		return v, nil
+	}
+	b, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(file)))
+	if err != nil {
+		return nil, err
+	}
+	var included interface{}
+	if err := decode(b, &included); err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	return included, nil
+}
+
+func decode(b []byte, v interface{}) error {
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	return d.Decode(v)
+}
+
+// Difference is a semantic difference between a policy definition in source control and the deployed definition.
+type Difference struct {
+	// Path locates the difference, e.g. "mode" or "policyRule.if.allOf[1].equals".
+	Path string
+	// Expected is the value in source control, nil if it is missing there.
+	Expected interface{}
+	// Actual is the deployed value, nil if it is missing there.
+	Actual interface{}
+}
+
+func (d Difference) String() string {
+	switch {
+	case d.Expected == nil:
+		return fmt.Sprintf("%s: deployed with %s, which is not in source control", d.Path, toString(d.Actual))
+	case d.Actual == nil:
+		return fmt.Sprintf("%s: %s in source control is not deployed", d.Path, toString(d.Expected))
+	}
+	return fmt.Sprintf("%s: expected %s, deployed %s", d.Path, toString(d.Expected), toString(d.Actual))
+}
+
+// Diff returns the semantic differences between a policy definition in source control and the deployed one.
+// Property names and the mode are compared case-insensitively as ARM does, numbers by value, and only the metadata
+// held in source control is compared, as Azure adds its own (e.g. "createdBy") to deployed definitions.
+func Diff(source, deployed Definition) []Difference {
+	var diffs []Difference
+
+	if source.DisplayName != deployed.DisplayName {
+		diffs = append(diffs, Difference{"displayName", source.DisplayName, deployed.DisplayName})
+	}
+	if source.Description != deployed.Description {
+		diffs = append(diffs, Difference{"description", source.Description, deployed.Description})
+	}
+	if !strings.EqualFold(source.Mode, deployed.Mode) {
+		diffs = append(diffs, Difference{"mode", source.Mode, deployed.Mode})
+	}
+
+	for _, k := range sortedKeys(source.Metadata) {
+		v, ok := lookup(deployed.Metadata, k)
+		if !ok {
+			diffs = append(diffs, Difference{"metadata." + k, source.Metadata[k], nil})
+			continue
+		}
+		diffValue("metadata."+k, source.Metadata[k], v, &diffs)
+	}
+
+	diffValue("policyRule", source.PolicyRule, deployed.PolicyRule, &diffs)
+	diffValue("parameters", emptyAsNil(source.Parameters), emptyAsNil(deployed.Parameters), &diffs)
+	return diffs
+}
+
+func diffValue(path string, expected, actual interface{}, diffs *[]Difference) {
+	switch e := expected.(type) {
+	case map[string]interface{}:
+		a, ok := actual.(map[string]interface{})
+		if !ok {
+			break
+		}
+		for _, k := range sortedKeys(e) {
+			v, ok := lookup(a, k)
+			if !ok {
+				*diffs = append(*diffs, Difference{path + "." + k, e[k], nil})
+				continue
+			}
+			diffValue(path+"."+k, e[k], v, diffs)
+		}
+		for _, k := range sortedKeys(a) {
+			if _, ok := lookup(e, k); !ok {
+				*diffs = append(*diffs, Difference{path + "." + k, nil, a[k]})
+			}
+		}
+		return
+	case []interface{}:
+		a, ok := actual.([]interface{})
+		if !ok || len(a) != len(e) {
+			break
+		}
+		for i := range e {
+			diffValue(fmt.Sprintf("%s[%d]", path, i), e[i], a[i], diffs)
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(normalise(expected), normalise(actual)) {
+		*diffs = append(*diffs, Difference{path, expected, actual})
+	}
+}
+
+func emptyAsNil(v interface{}) interface{} {
+	if isEmpty(v) {
+		return nil
+	}
+	return v
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// LoadDefinitions reads the policy definition manifests of every policy module directly under root, e.g. terraform/modules/policies.
+// Modules without a manifest, such as those assigning built-in definitions, are skipped.
+func LoadDefinitions(root string) ([]Definition, error) {
+	dirs, err := ioutil.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var defs []Definition
+	for _, dir := range dirs {
+		path := filepath.Join(root, dir.Name(), ManifestFile)
+		if !dir.IsDir() || !fileExists(path) {
+			continue
+		}
+		d, err := LoadDefinition(path)
+		if err != nil {
+			return nil, err
+		}
+		defs = append(defs, d)
+	}
+	return defs, nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
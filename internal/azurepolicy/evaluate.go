@@ -0,0 +1,688 @@
+package azurepolicy
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// AliasResolver maps a policy alias, e.g. "Microsoft.Storage/storageAccounts/supportsHttpsTrafficOnly", to the ARM property path that it refers to, e.g. "properties.supportsHttpsTrafficOnly".
+type AliasResolver interface {
+	PropertyPath(alias string) (string, bool)
+}
+
+// Evaluator evaluates policy rules against ARM resource documents offline.
+type Evaluator struct {
+	// Parameters holds the parameter definitions of the policy, whose default values are used when no value is assigned.
+	Parameters Parameters
+	// Values holds the parameter values of the policy assignment, keyed by parameter name.
+	Values map[string]interface{}
+	// Aliases resolves aliases to property paths. When nil, or when an alias is unknown, an alias of the form "<resource type>/<path>" is resolved to "properties.<path>".
+	Aliases AliasResolver
+}
+
+// Result is the outcome of evaluating a policy rule against a resource.
+type Result struct {
+	// Matched reports whether the "if" condition of the rule matched the resource.
+	Matched bool
+	// Effect is the resolved effect of the rule when it matched, EffectNone when it did not and EffectDisabled when the policy is disabled.
+	Effect string
+}
+
+// Evaluate evaluates the rule against the resource and returns the resulting effect.
+func (e Evaluator) Evaluate(rule Rule, resource Resource) (Result, error) {
+	ev := &evaluation{Evaluator: e, resource: resource}
+
+	effect, err := ev.effect(rule)
+	if err != nil {
+		return Result{}, err
+	}
+	if strings.EqualFold(effect, EffectDisabled) {
+		return Result{Effect: effect}, nil
+	}
+
+	matched, err := ev.condition(rule.If)
+	if err != nil {
+		return Result{}, err
+	}
+	if !matched {
+		return Result{Effect: EffectNone}, nil
+	}
+	return Result{Matched: true, Effect: effect}, nil
+}
+
+// Effect resolves the effect of the rule, e.g. "[parameters('effect')]", without evaluating its condition.
+func (e Evaluator) Effect(rule Rule) (string, error) {
+	return (&evaluation{Evaluator: e}).effect(rule)
+}
+
+// evaluation holds the state of a single evaluation of a rule against a resource.
+type evaluation struct {
+	Evaluator
+	resource Resource
+	// counts holds the array members being iterated over by enclosing "count" expressions, innermost last.
+	counts []countScope
+}
+
+type countScope struct {
+	// field is the [*] alias of a field count, e.g. "Microsoft.Storage/storageAccounts/networkAcls.ipRules[*]".
+	field string
+	// name is the name of a value count, used by current('name').
+	name  string
+	value interface{}
+}
+
+func (ev *evaluation) effect(rule Rule) (string, error) {
+	v, err := resolve(rule.Then.Effect, ev)
+	if err != nil {
+		return "", err
+	}
+	s, ok := v.(string)
+	if !ok || s == "" {
+		return "", fmt.Errorf("effect '%s' does not resolve to a string", rule.Then.Effect)
+	}
+	return s, nil
+}
+
+func (ev *evaluation) parameter(name string) (interface{}, error) {
+	def, declared := ev.Parameters.lookup(name)
+	if !declared {
+		return nil, fmt.Errorf("parameter '%s' is not defined", name)
+	}
+
+	for k, v := range ev.Values {
+		if strings.EqualFold(k, name) {
+			if len(def.AllowedValues) > 0 && !inList(v, def.AllowedValues) {
+				return nil, fmt.Errorf("value %v of parameter '%s' is not one of the allowed values %v", v, name, def.AllowedValues)
+			}
+			return v, nil
+		}
+	}
+	if def.DefaultValue == nil {
+		return nil, fmt.Errorf("parameter '%s' has no value and no default value", name)
+	}
+	return def.DefaultValue, nil
+}
+
+func (ev *evaluation) field(alias string) (interface{}, error) {
+	values, wildcard, exists := ev.fieldValues(alias)
+	if !exists {
+		return nil, nil
+	}
+	if wildcard {
+		return values, nil
+	}
+	return values[0], nil
+}
+
+func (ev *evaluation) current(name string) (interface{}, error) {
+	for i := len(ev.counts) - 1; i >= 0; i-- {
+		c := ev.counts[i]
+		if name == "" || strings.EqualFold(c.name, name) || strings.EqualFold(c.field, name) {
+			return c.value, nil
+		}
+	}
+	return nil, fmt.Errorf("current('%s') used outside of a matching count expression", name)
+}
+
+func (ev *evaluation) condition(c map[string]interface{}) (bool, error) {
+	if v, ok := lookup(c, "allOf"); ok {
+		conditions, err := conditionList("allOf", v)
+		if err != nil {
+			return false, err
+		}
+		for _, sub := range conditions {
+			ok, err := ev.condition(sub)
+			if err != nil || !ok {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+
+	if v, ok := lookup(c, "anyOf"); ok {
+		conditions, err := conditionList("anyOf", v)
+		if err != nil {
+			return false, err
+		}
+		for _, sub := range conditions {
+			ok, err := ev.condition(sub)
+			if err != nil || ok {
+				return ok, err
+			}
+		}
+		return false, nil
+	}
+
+	if v, ok := lookup(c, "not"); ok {
+		sub, ok := v.(map[string]interface{})
+		if !ok {
+			return false, fmt.Errorf("\"not\" must hold a single condition")
+		}
+		ok, err := ev.condition(sub)
+		return !ok, err
+	}
+
+	op, operand, err := operator(c)
+	if err != nil {
+		return false, err
+	}
+	operand, err = resolve(operand, ev)
+	if err != nil {
+		return false, err
+	}
+
+	if f, ok := lookup(c, "field"); ok {
+		alias, ok := f.(string)
+		if !ok {
+			return false, fmt.Errorf("\"field\" must be a string")
+		}
+		if isExpression(alias) {
+			v, err := resolve(alias, ev)
+			if err != nil {
+				return false, err
+			}
+			alias = toString(v)
+		}
+		values, wildcard, exists := ev.fieldValues(alias)
+		if !exists {
+			return compare(op, nil, false, operand)
+		}
+		if !wildcard {
+			return compare(op, values[0], true, operand)
+		}
+		// A condition on a [*] alias is only true when every member of the array satisfies it
+		for _, v := range values {
+			ok, err := compare(op, v, true, operand)
+			if err != nil || !ok {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+
+	if v, ok := lookup(c, "value"); ok {
+		v, err := resolve(v, ev)
+		if err != nil {
+			return false, err
+		}
+		return compare(op, v, v != nil, operand)
+	}
+
+	if v, ok := lookup(c, "count"); ok {
+		n, err := ev.count(v)
+		if err != nil {
+			return false, err
+		}
+		return compare(op, json.Number(strconv.Itoa(n)), true, operand)
+	}
+
+	return false, fmt.Errorf("condition %v has neither \"field\", \"value\" nor \"count\"", c)
+}
+
+func (ev *evaluation) count(v interface{}) (int, error) {
+	c, ok := v.(map[string]interface{})
+	if !ok {
+		return 0, fmt.Errorf("\"count\" must be an object")
+	}
+	where, hasWhere := lookup(c, "where")
+	var whereCondition map[string]interface{}
+	if hasWhere {
+		if whereCondition, ok = where.(map[string]interface{}); !ok {
+			return 0, fmt.Errorf("\"count.where\" must be a condition")
+		}
+	}
+
+	var scope countScope
+	var members []interface{}
+	if f, ok := lookup(c, "field"); ok {
+		alias, _ := f.(string)
+		if !strings.HasSuffix(alias, "[*]") {
+			return 0, fmt.Errorf("\"count.field\" '%s' must be an array alias ending in [*]", alias)
+		}
+		values, _, exists := ev.fieldValues(alias)
+		if exists {
+			members = values
+		}
+		scope.field = alias
+	} else if val, ok := lookup(c, "value"); ok {
+		resolved, err := resolve(val, ev)
+		if err != nil {
+			return 0, err
+		}
+		list, ok := resolved.([]interface{})
+		if !ok && resolved != nil {
+			return 0, fmt.Errorf("\"count.value\" must resolve to an array")
+		}
+		members = list
+		if n, ok := lookup(c, "name"); ok {
+			scope.name, _ = n.(string)
+		}
+	} else {
+		return 0, fmt.Errorf("\"count\" has neither \"field\" nor \"value\"")
+	}
+
+	if !hasWhere {
+		return len(members), nil
+	}
+
+	n := 0
+	for _, m := range members {
+		scope.value = m
+		ev.counts = append(ev.counts, scope)
+		ok, err := ev.condition(whereCondition)
+		ev.counts = ev.counts[:len(ev.counts)-1]
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// fieldValues returns the value of a field for a plain field, or the values of every array member for a [*] field.
+// exists is false if the field is not present on the resource.
+func (ev *evaluation) fieldValues(field string) (values []interface{}, wildcard bool, exists bool) {
+	// Inside a field count, the counted alias refers to the current array member
+	for i := len(ev.counts) - 1; i >= 0; i-- {
+		c := ev.counts[i]
+		if c.field == "" || len(field) < len(c.field) || !strings.EqualFold(field[:len(c.field)], c.field) {
+			continue
+		}
+		rest := strings.TrimPrefix(field[len(c.field):], ".")
+		return walk(c.value, parsePath(rest))
+	}
+
+	path, ok := ev.propertyPath(field)
+	if !ok {
+		return nil, false, false
+	}
+	return walk(map[string]interface{}(ev.resource), parsePath(path))
+}
+
+// propertyPath returns the path of a field within the ARM resource document.
+func (ev *evaluation) propertyPath(field string) (string, bool) {
+	lower := strings.ToLower(field)
+	switch lower {
+	case "name", "fullname", "kind", "type", "location", "id", "tags", "identity.type":
+		if lower == "fullname" {
+			return "name", true
+		}
+		return field, true
+	}
+
+	// tags['name'], tags[name] and tags.name
+	if strings.HasPrefix(lower, "tags[") && strings.HasSuffix(lower, "]") {
+		return "tags." + strings.Trim(field[len("tags["):len(field)-1], "'"), true
+	}
+	if strings.HasPrefix(lower, "tags.") {
+		return field, true
+	}
+
+	if ev.Aliases != nil {
+		if p, ok := ev.Aliases.PropertyPath(field); ok {
+			return p, true
+		}
+	}
+
+	resourceType := ev.resource.Type()
+	if resourceType != "" && strings.HasPrefix(lower, strings.ToLower(resourceType)+"/") {
+		return "properties." + field[len(resourceType)+1:], true
+	}
+	// Aliases of other resource types never match
+	return "", false
+}
+
+type segment struct {
+	name     string
+	wildcard bool
+}
+
+// parsePath splits a property path such as "properties.networkAcls.ipRules[*].value" into segments.
+func parsePath(path string) []segment {
+	var segs []segment
+	for _, p := range strings.Split(path, ".") {
+		if p == "" {
+			continue
+		}
+		if strings.HasSuffix(p, "[*]") {
+			segs = append(segs, segment{name: strings.TrimSuffix(p, "[*]"), wildcard: true})
+			continue
+		}
+		segs = append(segs, segment{name: p})
+	}
+	return segs
+}
+
+func walk(v interface{}, segs []segment) ([]interface{}, bool, bool) {
+	if len(segs) == 0 {
+		return []interface{}{v}, false, true
+	}
+
+	s := segs[0]
+	if s.name != "" {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, hasWildcard(segs), false
+		}
+		if v, ok = lookup(m, s.name); !ok || v == nil {
+			return nil, hasWildcard(segs), false
+		}
+	}
+
+	if !s.wildcard {
+		return walk(v, segs[1:])
+	}
+
+	list, ok := v.([]interface{})
+	if !ok {
+		return nil, true, false
+	}
+	values := []interface{}{}
+	for _, member := range list {
+		vals, _, exists := walk(member, segs[1:])
+		if exists {
+			values = append(values, vals...)
+		}
+	}
+	return values, true, true
+}
+
+func hasWildcard(segs []segment) bool {
+	for _, s := range segs {
+		if s.wildcard {
+			return true
+		}
+	}
+	return false
+}
+
+var operators = []string{
+	"equals", "notEquals", "like", "notLike", "match", "matchInsensitively", "notMatch", "notMatchInsensitively",
+	"contains", "notContains", "in", "notIn", "containsKey", "notContainsKey",
+	"less", "lessOrEquals", "greater", "greaterOrEquals", "exists",
+}
+
+// operator returns the single condition operator of a condition, e.g. "equals", and its operand.
+func operator(c map[string]interface{}) (string, interface{}, error) {
+	var found []string
+	var operand interface{}
+	for k, v := range c {
+		for _, op := range operators {
+			if strings.EqualFold(k, op) {
+				found = append(found, op)
+				operand = v
+			}
+		}
+	}
+	switch len(found) {
+	case 0:
+		return "", nil, fmt.Errorf("condition %v has no supported operator", c)
+	case 1:
+		return found[0], operand, nil
+	}
+	sort.Strings(found)
+	return "", nil, fmt.Errorf("condition %v has more than one operator: %v", c, found)
+}
+
+// compare applies a condition operator to a value. exists is false when a field is not present on the resource, in which case it compares as an empty string.
+func compare(op string, value interface{}, exists bool, operand interface{}) (bool, error) {
+	switch op {
+	case "exists":
+		want, err := toBool(operand)
+		if err != nil {
+			return false, fmt.Errorf("exists: %v", err)
+		}
+		return exists == want, nil
+	case "equals":
+		return equal(value, operand), nil
+	case "notEquals":
+		return !equal(value, operand), nil
+	case "like":
+		return likeMatch(toString(operand), toString(value)), nil
+	case "notLike":
+		return !likeMatch(toString(operand), toString(value)), nil
+	case "match":
+		return patternMatch(toString(operand), toString(value), false), nil
+	case "matchInsensitively":
+		return patternMatch(toString(operand), toString(value), true), nil
+	case "notMatch":
+		return !patternMatch(toString(operand), toString(value), false), nil
+	case "notMatchInsensitively":
+		return !patternMatch(toString(operand), toString(value), true), nil
+	case "contains":
+		return contains(value, operand), nil
+	case "notContains":
+		return !contains(value, operand), nil
+	case "in", "notIn":
+		list, ok := operand.([]interface{})
+		if !ok {
+			return false, fmt.Errorf("%s expects an array, got %v", op, operand)
+		}
+		return inList(value, list) == (op == "in"), nil
+	case "containsKey", "notContainsKey":
+		m, _ := value.(map[string]interface{})
+		_, found := lookup(m, toString(operand))
+		return found == (op == "containsKey"), nil
+	case "less", "lessOrEquals", "greater", "greaterOrEquals":
+		c, err := order(value, operand)
+		if err != nil {
+			return false, fmt.Errorf("%s: %v", op, err)
+		}
+		switch op {
+		case "less":
+			return c < 0, nil
+		case "lessOrEquals":
+			return c <= 0, nil
+		case "greater":
+			return c > 0, nil
+		}
+		return c >= 0, nil
+	}
+	return false, fmt.Errorf("unsupported operator '%s'", op)
+}
+
+// equal compares two values the way Azure Policy does: strings case-insensitively, numbers numerically and booleans by their string form.
+func equal(a, b interface{}) bool {
+	if x, ok := toNumber(a); ok {
+		if y, ok := toNumber(b); ok {
+			return x == y
+		}
+	}
+	switch a.(type) {
+	case []interface{}, map[string]interface{}:
+		return reflect.DeepEqual(normalise(a), normalise(b))
+	}
+	return strings.EqualFold(toString(a), toString(b))
+}
+
+func inList(v interface{}, list []interface{}) bool {
+	for _, e := range list {
+		if equal(v, e) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(container, item interface{}) bool {
+	switch c := container.(type) {
+	case []interface{}:
+		return inList(item, c)
+	case map[string]interface{}:
+		_, ok := lookup(c, toString(item))
+		return ok
+	}
+	return strings.Contains(strings.ToLower(toString(container)), strings.ToLower(toString(item)))
+}
+
+func order(a, b interface{}) (int, error) {
+	if x, ok := toNumber(a); ok {
+		if y, ok := toNumber(b); ok {
+			switch {
+			case x < y:
+				return -1, nil
+			case x > y:
+				return 1, nil
+			}
+			return 0, nil
+		}
+	}
+	sa, aok := a.(string)
+	sb, bok := b.(string)
+	if !aok || !bok {
+		return 0, fmt.Errorf("cannot order %v and %v", a, b)
+	}
+	return strings.Compare(strings.ToLower(sa), strings.ToLower(sb)), nil
+}
+
+// likeMatch matches a value against a "like" pattern, in which '*' matches any sequence of characters. Matching is case-insensitive.
+func likeMatch(pattern, value string) bool {
+	parts := strings.Split(strings.ToLower(pattern), "*")
+	value = strings.ToLower(value)
+	if len(parts) == 1 {
+		return pattern == value || strings.EqualFold(pattern, value)
+	}
+	if !strings.HasPrefix(value, parts[0]) {
+		return false
+	}
+	value = value[len(parts[0]):]
+	for _, p := range parts[1 : len(parts)-1] {
+		i := strings.Index(value, p)
+		if i < 0 {
+			return false
+		}
+		value = value[i+len(p):]
+	}
+	return strings.HasSuffix(value, parts[len(parts)-1])
+}
+
+// patternMatch matches a value against a "match" pattern, in which '#' matches a digit, '?' a letter and '.' any character.
+func patternMatch(pattern, value string, insensitive bool) bool {
+	p, v := []rune(pattern), []rune(value)
+	if len(p) != len(v) {
+		return false
+	}
+	for i := range p {
+		switch p[i] {
+		case '#':
+			if v[i] < '0' || v[i] > '9' {
+				return false
+			}
+		case '?':
+			if !(v[i] >= 'a' && v[i] <= 'z') && !(v[i] >= 'A' && v[i] <= 'Z') {
+				return false
+			}
+		case '.':
+		default:
+			if insensitive {
+				if !strings.EqualFold(string(p[i]), string(v[i])) {
+					return false
+				}
+			} else if p[i] != v[i] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func conditionList(name string, v interface{}) ([]map[string]interface{}, error) {
+	list, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("\"%s\" must hold a list of conditions", name)
+	}
+	out := make([]map[string]interface{}, 0, len(list))
+	for _, e := range list {
+		c, ok := e.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("\"%s\" must hold a list of conditions", name)
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+func toString(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case bool:
+		return strconv.FormatBool(t)
+	case json.Number:
+		return t.String()
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+func toNumber(v interface{}) (float64, bool) {
+	switch t := v.(type) {
+	case json.Number:
+		f, err := t.Float64()
+		return f, err == nil
+	case float64:
+		return t, true
+	case int:
+		return float64(t), true
+	}
+	return 0, false
+}
+
+func toBool(v interface{}) (bool, error) {
+	switch t := v.(type) {
+	case bool:
+		return t, nil
+	case string:
+		return strconv.ParseBool(t)
+	}
+	return false, fmt.Errorf("%v is not a boolean", v)
+}
+
+func isEmpty(v interface{}) bool {
+	switch t := v.(type) {
+	case nil:
+		return true
+	case string:
+		return t == ""
+	case []interface{}:
+		return len(t) == 0
+	case map[string]interface{}:
+		return len(t) == 0
+	}
+	return false
+}
+
+// normalise converts numbers to float64 so that documents decoded with and without json.Number compare equal.
+func normalise(v interface{}) interface{} {
+	switch t := v.(type) {
+	case json.Number:
+		f, _ := t.Float64()
+		return f
+	case []interface{}:
+		out := make([]interface{}, len(t))
+		for i, e := range t {
+			out[i] = normalise(e)
+		}
+		return out
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(t))
+		for k, e := range t {
+			out[strings.ToLower(k)] = normalise(e)
+		}
+		return out
+	}
+	return v
+}
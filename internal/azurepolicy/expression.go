@@ -0,0 +1,449 @@
+package azurepolicy
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// functions is the context in which template expressions such as "[parameters('effect')]" are evaluated.
+type functions interface {
+	parameter(name string) (interface{}, error)
+	field(alias string) (interface{}, error)
+	current(name string) (interface{}, error)
+}
+
+// isExpression reports whether a string is a template expression, i.e. enclosed in square brackets. A leading "[[" escapes a literal string.
+func isExpression(s string) bool {
+	return strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]") && !strings.HasPrefix(s, "[[")
+}
+
+// resolve returns the value of a string, evaluating it if it is a template expression. Strings and slices are resolved recursively.
+func resolve(v interface{}, fn functions) (interface{}, error) {
+	switch t := v.(type) {
+	case string:
+		if strings.HasPrefix(t, "[[") {
+			return t[1:], nil
+		}
+		if !isExpression(t) {
+			return t, nil
+		}
+		p := &parser{input: t[1 : len(t)-1]}
+		n, err := p.parse()
+		if err != nil {
+			return nil, fmt.Errorf("invalid expression '%s': %v", t, err)
+		}
+		r, err := n.eval(fn)
+		if err != nil {
+			return nil, fmt.Errorf("cannot evaluate expression '%s': %v", t, err)
+		}
+		return r, nil
+	case []interface{}:
+		out := make([]interface{}, 0, len(t))
+		for _, e := range t {
+			r, err := resolve(e, fn)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, r)
+		}
+		return out, nil
+	}
+	return v, nil
+}
+
+// parameterReferences returns the names of the parameters referenced in a template expression, e.g. "effect" for "[parameters('effect')]".
+// Only parameters referenced by a literal name are returned.
+func parameterReferences(s string) ([]string, error) {
+	if !isExpression(s) {
+		return nil, nil
+	}
+	p := &parser{input: s[1 : len(s)-1]}
+	n, err := p.parse()
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression '%s': %v", s, err)
+	}
+
+	var names []string
+	var walk func(n node)
+	walk = func(n node) {
+		switch t := n.(type) {
+		case call:
+			if strings.EqualFold(t.name, "parameters") && len(t.args) == 1 {
+				if l, ok := t.args[0].(literal); ok {
+					if name, ok := l.value.(string); ok {
+						names = append(names, name)
+					}
+				}
+			}
+			for _, a := range t.args {
+				walk(a)
+			}
+		case index:
+			walk(t.target)
+			walk(t.key)
+		case property:
+			walk(t.target)
+		}
+	}
+	walk(n)
+	return names, nil
+}
+
+type node interface {
+	eval(fn functions) (interface{}, error)
+}
+
+type literal struct {
+	value interface{}
+}
+
+func (l literal) eval(functions) (interface{}, error) {
+	return l.value, nil
+}
+
+type call struct {
+	name string
+	args []node
+}
+
+type index struct {
+	target node
+	key    node
+}
+
+type property struct {
+	target node
+	name   string
+}
+
+func (i index) eval(fn functions) (interface{}, error) {
+	t, err := i.target.eval(fn)
+	if err != nil {
+		return nil, err
+	}
+	k, err := i.key.eval(fn)
+	if err != nil {
+		return nil, err
+	}
+	switch c := t.(type) {
+	case []interface{}:
+		n, ok := toNumber(k)
+		if !ok || int(n) < 0 || int(n) >= len(c) {
+			return nil, fmt.Errorf("index %v out of range", k)
+		}
+		return c[int(n)], nil
+	case map[string]interface{}:
+		v, _ := lookup(c, fmt.Sprint(k))
+		return v, nil
+	}
+	return nil, fmt.Errorf("cannot index %T", t)
+}
+
+func (p property) eval(fn functions) (interface{}, error) {
+	t, err := p.target.eval(fn)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := t.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("cannot access property '%s' of %T", p.name, t)
+	}
+	v, _ := lookup(m, p.name)
+	return v, nil
+}
+
+func (c call) eval(fn functions) (interface{}, error) {
+	args := make([]interface{}, 0, len(c.args))
+	for _, a := range c.args {
+		v, err := a.eval(fn)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, v)
+	}
+
+	argString := func(i int) (string, error) {
+		if i >= len(args) {
+			return "", fmt.Errorf("%s() expects at least %d argument(s)", c.name, i+1)
+		}
+		s, ok := args[i].(string)
+		if !ok {
+			return "", fmt.Errorf("%s() expects a string as argument %d", c.name, i+1)
+		}
+		return s, nil
+	}
+
+	switch strings.ToLower(c.name) {
+	case "parameters":
+		name, err := argString(0)
+		if err != nil {
+			return nil, err
+		}
+		return fn.parameter(name)
+	case "field":
+		alias, err := argString(0)
+		if err != nil {
+			return nil, err
+		}
+		return fn.field(alias)
+	case "current":
+		name := ""
+		if len(args) > 0 {
+			s, err := argString(0)
+			if err != nil {
+				return nil, err
+			}
+			name = s
+		}
+		return fn.current(name)
+	case "concat":
+		if len(args) > 0 {
+			if _, ok := args[0].([]interface{}); ok {
+				var out []interface{}
+				for _, a := range args {
+					l, ok := a.([]interface{})
+					if !ok {
+						return nil, fmt.Errorf("concat() cannot mix arrays and strings")
+					}
+					out = append(out, l...)
+				}
+				return out, nil
+			}
+		}
+		var sb strings.Builder
+		for _, a := range args {
+			sb.WriteString(toString(a))
+		}
+		return sb.String(), nil
+	case "tolower":
+		s, err := argString(0)
+		return strings.ToLower(s), err
+	case "toupper":
+		s, err := argString(0)
+		return strings.ToUpper(s), err
+	case "string":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("string() expects 1 argument")
+		}
+		return toString(args[0]), nil
+	case "length":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("length() expects 1 argument")
+		}
+		switch t := args[0].(type) {
+		case string:
+			return json.Number(strconv.Itoa(len(t))), nil
+		case []interface{}:
+			return json.Number(strconv.Itoa(len(t))), nil
+		case map[string]interface{}:
+			return json.Number(strconv.Itoa(len(t))), nil
+		}
+		return nil, fmt.Errorf("length() expects a string, array or object")
+	case "empty":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("empty() expects 1 argument")
+		}
+		return isEmpty(args[0]), nil
+	case "equals":
+		if len(args) != 2 {
+			return nil, fmt.Errorf("equals() expects 2 arguments")
+		}
+		return toString(args[0]) == toString(args[1]), nil
+	case "not":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("not() expects 1 argument")
+		}
+		b, ok := args[0].(bool)
+		if !ok {
+			return nil, fmt.Errorf("not() expects a boolean")
+		}
+		return !b, nil
+	case "if":
+		if len(args) != 3 {
+			return nil, fmt.Errorf("if() expects 3 arguments")
+		}
+		b, ok := args[0].(bool)
+		if !ok {
+			return nil, fmt.Errorf("if() expects a boolean condition")
+		}
+		if b {
+			return args[1], nil
+		}
+		return args[2], nil
+	case "split":
+		s, err := argString(0)
+		if err != nil {
+			return nil, err
+		}
+		sep, err := argString(1)
+		if err != nil {
+			return nil, err
+		}
+		var out []interface{}
+		for _, p := range strings.Split(s, sep) {
+			out = append(out, p)
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("unsupported function '%s'", c.name)
+}
+
+// parser is a recursive descent parser for the subset of ARM template expressions used in policy rules.
+type parser struct {
+	input string
+	pos   int
+}
+
+func (p *parser) parse() (node, error) {
+	n, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos != len(p.input) {
+		return nil, fmt.Errorf("unexpected '%s' at position %d", p.input[p.pos:], p.pos)
+	}
+	return n, nil
+}
+
+func (p *parser) expression() (node, error) {
+	p.skipSpace()
+	if p.pos >= len(p.input) {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+
+	var n node
+	c := p.input[p.pos]
+	switch {
+	case c == '\'':
+		s, err := p.stringLiteral()
+		if err != nil {
+			return nil, err
+		}
+		n = literal{s}
+	case c == '-' || (c >= '0' && c <= '9'):
+		start := p.pos
+		p.pos++
+		for p.pos < len(p.input) && (p.input[p.pos] == '.' || (p.input[p.pos] >= '0' && p.input[p.pos] <= '9')) {
+			p.pos++
+		}
+		n = literal{json.Number(p.input[start:p.pos])}
+	case unicode.IsLetter(rune(c)):
+		name := p.identifier()
+		p.skipSpace()
+		switch {
+		case p.peek('('):
+			args, err := p.arguments()
+			if err != nil {
+				return nil, err
+			}
+			n = call{name: name, args: args}
+		case strings.EqualFold(name, "true"):
+			n = literal{true}
+		case strings.EqualFold(name, "false"):
+			n = literal{false}
+		case strings.EqualFold(name, "null"):
+			n = literal{nil}
+		default:
+			return nil, fmt.Errorf("unexpected identifier '%s'", name)
+		}
+	default:
+		return nil, fmt.Errorf("unexpected '%c' at position %d", c, p.pos)
+	}
+
+	for {
+		p.skipSpace()
+		switch {
+		case p.peek('['):
+			p.pos++
+			k, err := p.expression()
+			if err != nil {
+				return nil, err
+			}
+			p.skipSpace()
+			if !p.peek(']') {
+				return nil, fmt.Errorf("missing ']' at position %d", p.pos)
+			}
+			p.pos++
+			n = index{target: n, key: k}
+		case p.peek('.'):
+			p.pos++
+			name := p.identifier()
+			if name == "" {
+				return nil, fmt.Errorf("missing property name at position %d", p.pos)
+			}
+			n = property{target: n, name: name}
+		default:
+			return n, nil
+		}
+	}
+}
+
+func (p *parser) arguments() ([]node, error) {
+	p.pos++ // (
+	var args []node
+	for {
+		p.skipSpace()
+		if p.peek(')') {
+			p.pos++
+			return args, nil
+		}
+		if len(args) > 0 {
+			if !p.peek(',') {
+				return nil, fmt.Errorf("missing ',' at position %d", p.pos)
+			}
+			p.pos++
+		}
+		a, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, a)
+	}
+}
+
+func (p *parser) stringLiteral() (string, error) {
+	p.pos++ // opening quote
+	var sb strings.Builder
+	for p.pos < len(p.input) {
+		c := p.input[p.pos]
+		p.pos++
+		if c != '\'' {
+			sb.WriteByte(c)
+			continue
+		}
+		// '' escapes a single quote
+		if p.peek('\'') {
+			sb.WriteByte('\'')
+			p.pos++
+			continue
+		}
+		return sb.String(), nil
+	}
+	return "", fmt.Errorf("unterminated string literal")
+}
+
+func (p *parser) identifier() string {
+	start := p.pos
+	for p.pos < len(p.input) {
+		c := rune(p.input[p.pos])
+		if !unicode.IsLetter(c) && !unicode.IsDigit(c) && c != '_' {
+			break
+		}
+		p.pos++
+	}
+	return p.input[start:p.pos]
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.input) && p.input[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+func (p *parser) peek(c byte) bool {
+	return p.pos < len(p.input) && p.input[p.pos] == c
+}
@@ -0,0 +1,183 @@
+package azurepolicy
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Lint checks.
+const (
+	CheckParameterisedEffect = "parameterised-effect"
+	CheckUndeclaredParameter = "undeclared-parameter"
+	CheckUnusedParameter     = "unused-parameter"
+	CheckParameterDefault    = "parameter-default"
+	CheckUnknownAlias        = "unknown-alias"
+	CheckInvalidExpression   = "invalid-expression"
+)
+
+// AliasValidator is implemented by alias resolvers that can explain why an alias is unknown, e.g. by suggesting similar aliases.
+type AliasValidator interface {
+	Validate(alias string) error
+}
+
+// Finding is a problem reported by Lint.
+type Finding struct {
+	Check   string
+	Message string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: %s", f.Check, f.Message)
+}
+
+// Lint checks a policy rule and its parameter definitions for problems that the policy definition schema cannot express:
+// the effect must be a parameter, every referenced parameter must be declared and every declared parameter used,
+// default values must be allowed values, and every aliased field must be known to aliases.
+// The alias check is skipped when aliases is nil. Findings are sorted by check and message, and reported once each, e.g.
+// once for a parameter that is referenced several times but not declared.
+func Lint(rule Rule, params Parameters, aliases AliasResolver) []Finding {
+	var findings []Finding
+
+	refs, err := parameterReferences(rule.Then.Effect)
+	if err != nil {
+		findings = append(findings, Finding{CheckInvalidExpression, err.Error()})
+	}
+	if len(refs) == 0 {
+		findings = append(findings, Finding{CheckParameterisedEffect, fmt.Sprintf("effect '%s' is not parameterised, use \"[parameters('effect')]\"", rule.Then.Effect)})
+	}
+
+	var strs []string
+	collectStrings(rule.If, &strs)
+	strs = append(strs, rule.Then.Effect)
+	if len(rule.Then.Details) > 0 {
+		var details interface{}
+		if err := json.Unmarshal(rule.Then.Details, &details); err == nil {
+			collectStrings(details, &strs)
+		}
+	}
+
+	used := map[string]bool{}
+	for _, s := range strs {
+		names, err := parameterReferences(s)
+		if err != nil {
+			findings = append(findings, Finding{CheckInvalidExpression, err.Error()})
+			continue
+		}
+		for _, n := range names {
+			// Parameters are checked once, however often they are referenced
+			if used[strings.ToLower(n)] {
+				continue
+			}
+			used[strings.ToLower(n)] = true
+			if _, ok := params.lookup(n); !ok {
+				findings = append(findings, Finding{CheckUndeclaredParameter, fmt.Sprintf("parameter '%s' is referenced but not declared", n)})
+			}
+		}
+	}
+
+	for _, name := range params.names() {
+		def := params[name]
+		if !used[strings.ToLower(name)] {
+			findings = append(findings, Finding{CheckUnusedParameter, fmt.Sprintf("parameter '%s' is declared but not referenced by the rule", name)})
+		}
+		if def.DefaultValue != nil && len(def.AllowedValues) > 0 && !inList(def.DefaultValue, def.AllowedValues) {
+			findings = append(findings, Finding{CheckParameterDefault, fmt.Sprintf("default value %v of parameter '%s' is not one of the allowed values %v", def.DefaultValue, name, def.AllowedValues)})
+		}
+	}
+
+	if aliases != nil {
+		var fields []string
+		collectFields(rule.If, &fields)
+		for _, f := range fields {
+			// Aliases are "<namespace>/<resource type>/<path>", other fields such as "type" or "tags" are not aliases
+			if !strings.Contains(f, "/") {
+				continue
+			}
+			if _, ok := aliases.PropertyPath(f); ok {
+				continue
+			}
+			msg := fmt.Sprintf("field '%s' is not a known alias", f)
+			if v, ok := aliases.(AliasValidator); ok {
+				if err := v.Validate(f); err != nil {
+					msg = err.Error()
+				}
+			}
+			findings = append(findings, Finding{CheckUnknownAlias, msg})
+		}
+	}
+
+	return sortFindings(findings)
+}
+
+// sortFindings sorts the findings by check and message, and drops the repeated ones, as the rule is walked through maps
+// in no particular order and may use a field or parameter several times.
+func sortFindings(findings []Finding) []Finding {
+	sort.Slice(findings, func(i, j int) bool {
+		if findings[i].Check != findings[j].Check {
+			return findings[i].Check < findings[j].Check
+		}
+		return findings[i].Message < findings[j].Message
+	})
+	var sorted []Finding
+	for i, f := range findings {
+		if i == 0 || f != findings[i-1] {
+			sorted = append(sorted, f)
+		}
+	}
+	return sorted
+}
+
+func (p Parameters) lookup(name string) (ParameterDefinition, bool) {
+	for k, v := range p {
+		if strings.EqualFold(k, name) {
+			return v, true
+		}
+	}
+	return ParameterDefinition{}, false
+}
+
+// names returns the parameter names in alphabetical order, so that findings are reported in a stable order.
+func (p Parameters) names() []string {
+	names := make([]string, 0, len(p))
+	for k := range p {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// collectStrings appends every string value within v.
+func collectStrings(v interface{}, strs *[]string) {
+	switch t := v.(type) {
+	case string:
+		*strs = append(*strs, t)
+	case []interface{}:
+		for _, e := range t {
+			collectStrings(e, strs)
+		}
+	case map[string]interface{}:
+		for _, e := range t {
+			collectStrings(e, strs)
+		}
+	}
+}
+
+// collectFields appends the value of every "field" within a condition, including count fields.
+func collectFields(v interface{}, fields *[]string) {
+	switch t := v.(type) {
+	case []interface{}:
+		for _, e := range t {
+			collectFields(e, fields)
+		}
+	case map[string]interface{}:
+		for k, e := range t {
+			if s, ok := e.(string); ok && strings.EqualFold(k, "field") && !isExpression(s) {
+				*fields = append(*fields, s)
+				continue
+			}
+			collectFields(e, fields)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package azurepolicy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// Effects that a policy rule may resolve to. Effects are compared case-insensitively.
+const (
+	EffectDeny              = "deny"
+	EffectAudit             = "audit"
+	EffectAppend            = "append"
+	EffectModify            = "modify"
+	EffectAuditIfNotExists  = "auditIfNotExists"
+	EffectDeployIfNotExists = "deployIfNotExists"
+	EffectDisabled          = "disabled"
+
+	// EffectNone is returned by Evaluate when the "if" condition of the rule does not match the resource.
+	EffectNone = "none"
+)
+
+// Rule is the policyRule of an Azure Policy definition.
+type Rule struct {
+	If   map[string]interface{} `json:"if"`
+	Then Then                   `json:"then"`
+}
+
+// Then is the "then" block of a policy rule.
+type Then struct {
+	// Effect is either a literal effect such as "deny" or a template expression such as "[parameters('effect')]".
+	Effect  string          `json:"effect"`
+	Details json.RawMessage `json:"details,omitempty"`
+}
+
+// ParameterDefinition is the definition of a single policy parameter, as found in the "parameters" of a policy definition or in a policy_parameters.json file.
+type ParameterDefinition struct {
+	Type          string                 `json:"type"`
+	Metadata      map[string]interface{} `json:"metadata,omitempty"`
+	AllowedValues []interface{}          `json:"allowedValues,omitempty"`
+	DefaultValue  interface{}            `json:"defaultValue,omitempty"`
+}
+
+// Parameters are the parameter definitions of a policy, keyed by parameter name.
+type Parameters map[string]ParameterDefinition
+
+// ParseRule parses a policy rule. The document may be either the bare rule ({"if": ..., "then": ...}) or a policy definition holding it under "policyRule" or "properties.policyRule".
+func ParseRule(b []byte) (Rule, error) {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return Rule{}, fmt.Errorf("invalid policy rule: %v", err)
+	}
+
+	if props, ok := lookupRaw(doc, "properties"); ok {
+		if err := json.Unmarshal(props, &doc); err != nil {
+			return Rule{}, fmt.Errorf("invalid policy definition properties: %v", err)
+		}
+	}
+	if rule, ok := lookupRaw(doc, "policyRule"); ok {
+		b = rule
+	}
+
+	var r Rule
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	if err := d.Decode(&r); err != nil {
+		return r, fmt.Errorf("invalid policy rule: %v", err)
+	}
+	if r.If == nil {
+		return r, fmt.Errorf("invalid policy rule: missing \"if\" condition")
+	}
+	if r.Then.Effect == "" {
+		return r, fmt.Errorf("invalid policy rule: missing \"then.effect\"")
+	}
+	return r, nil
+}
+
+// LoadRule reads and parses the policy rule in the named file.
+func LoadRule(path string) (Rule, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Rule{}, err
+	}
+	r, err := ParseRule(b)
+	if err != nil {
+		return r, fmt.Errorf("%s: %v", path, err)
+	}
+	return r, nil
+}
+
+// ParseParameters parses policy parameter definitions. The document may be either the bare definitions, as in policy_parameters.json, or a policy definition holding them under "parameters" or "properties.parameters".
+func ParseParameters(b []byte) (Parameters, error) {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("invalid policy parameters: %v", err)
+	}
+
+	if props, ok := lookupRaw(doc, "properties"); ok {
+		if err := json.Unmarshal(props, &doc); err != nil {
+			return nil, fmt.Errorf("invalid policy definition properties: %v", err)
+		}
+	}
+	if params, ok := lookupRaw(doc, "parameters"); ok {
+		b = params
+	}
+
+	var p Parameters
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	if err := d.Decode(&p); err != nil {
+		return nil, fmt.Errorf("invalid policy parameters: %v", err)
+	}
+	return p, nil
+}
+
+// LoadParameters reads and parses the policy parameter definitions in the named file.
+func LoadParameters(path string) (Parameters, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p, err := ParseParameters(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return p, nil
+}
+
+// Resource is an ARM resource document, e.g. the output of `az resource show` or a resource from an ARM template.
+type Resource map[string]interface{}
+
+// ParseResource parses an ARM resource JSON document.
+func ParseResource(b []byte) (Resource, error) {
+	var r Resource
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	if err := d.Decode(&r); err != nil {
+		return nil, fmt.Errorf("invalid resource: %v", err)
+	}
+	return r, nil
+}
+
+// LoadResource reads and parses the ARM resource JSON document in the named file.
+func LoadResource(path string) (Resource, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	r, err := ParseResource(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return r, nil
+}
+
+// Type returns the resource type, e.g. "Microsoft.Storage/storageAccounts".
+func (r Resource) Type() string {
+	t, _ := lookup(map[string]interface{}(r), "type")
+	s, _ := t.(string)
+	return s
+}
+
+func lookupRaw(m map[string]json.RawMessage, key string) (json.RawMessage, bool) {
+	for k, v := range m {
+		if strings.EqualFold(k, key) {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// lookup returns the value of a key, matched case-insensitively as ARM does.
+func lookup(m map[string]interface{}, key string) (interface{}, bool) {
+	if v, ok := m[key]; ok {
+		return v, true
+	}
+	for k, v := range m {
+		if strings.EqualFold(k, key) {
+			return v, true
+		}
+	}
+	return nil, false
+}
@@ -74,6 +74,7 @@ func CreateCluster(ctx context.Context) (e error) {
 
 	f, e = c.CreateOrUpdate(ctx, *rg.Name, clusterName, containerservice.ManagedCluster{
 		Location: to.StringPtr(os.Getenv("AZURE_LOCATION")),
+		Tags:     azureutil.Tags(ctx, nil),
 		ManagedClusterProperties: &containerservice.ManagedClusterProperties{
 			KubernetesVersion: to.StringPtr("1.15.5"),
 			DNSPrefix:         &clusterName,
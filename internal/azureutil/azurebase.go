@@ -7,6 +7,8 @@ import (
 
 const (
 	PolicyAssignmentManagementGroup string = "AZURE_POLICY_ASSIGNMENT_MANAGEMENT_GROUP"
+	PolicyDefinitionManagementGroup string = "AZURE_POLICY_DEFINITION_MANAGEMENT_GROUP"
+	RootManagementGroup             string = "AZURE_ROOT_MANAGEMENT_GROUP"
 )
 
 var prefix string
@@ -2,6 +2,7 @@ package group
 
 import (
 	"citihub.com/compliance-as-code/internal/azureutil"
+	"citihub.com/compliance-as-code/internal/ledger"
 	"context"
 	"github.com/Azure/azure-sdk-for-go/services/resources/mgmt/2018-02-01/resources"
 	"github.com/Azure/go-autorest/autorest/azure/auth"
@@ -12,33 +13,51 @@ import (
 // Create creates a new Resource Group in the default location (configured using the AZURE_LOCATION environment variable).
 func Create(ctx context.Context, name string) (resources.Group, error) {
 	log.Printf("[DEBUG] creating Resource Group '%s' in location: %v", name, azureutil.Location())
+	if err := ledger.Record(ledgerResource(name)); err != nil {
+		return resources.Group{}, err
+	}
 	return client().CreateOrUpdate(
 		ctx,
 		name,
 		resources.Group{
 			Location: to.StringPtr(azureutil.Location()),
+			Tags:     azureutil.Tags(ctx, nil),
 		})
 }
 
-// CreateWithTags creates a new Resource Group in the default location (configured using the AZURE_LOCATION environment variable) and sets the supplied tags.
+// CreateWithTags creates a new Resource Group in the default location (configured using the AZURE_LOCATION environment variable) and sets the supplied tags in addition to those of the tagging policy carried by ctx.
 func CreateWithTags(ctx context.Context, name string, tags map[string]*string) (resources.Group, error) {
 	log.Printf("[DEBUG] creating Resource Group '%s' on location: '%v'", name, azureutil.Location())
+	if err := ledger.Record(ledgerResource(name)); err != nil {
+		return resources.Group{}, err
+	}
 	return client().CreateOrUpdate(
 		ctx,
 		name,
 		resources.Group{
 			Location: to.StringPtr(azureutil.Location()),
-			Tags:     tags,
+			Tags:     azureutil.Tags(ctx, tags),
 		})
 }
 
-// Cleanup deletes the Resource Group created during testing (a test Resource Group name in the form 'test[a-z]{6}resourceGP').
+// Cleanup deletes the Resource Group created during testing (a test Resource Group name in the form 'test[a-z]{6}resourceGP') and waits for its deletion to complete.
 func Cleanup(ctx context.Context) error {
 	log.Println("[DEBUG] Deleting resources")
-	_, err := client().Delete(ctx, azureutil.ResourceGroup())
+	c := client()
+	future, err := c.Delete(ctx, azureutil.ResourceGroup())
+	if err == nil {
+		err = future.WaitForCompletionRef(ctx, c.Client)
+	}
+	if err == nil {
+		ledger.Release(ledgerResource(azureutil.ResourceGroup()))
+	}
 	return err
 }
 
+func ledgerResource(name string) ledger.Resource {
+	return azureutil.LedgerResource(ledger.KindResourceGroup, name, "", "")
+}
+
 func client() resources.GroupsClient {
 	c := resources.NewGroupsClient(azureutil.SubscriptionID())
 	authorizer, err := auth.NewAuthorizerFromEnvironment()
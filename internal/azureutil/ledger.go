@@ -0,0 +1,9 @@
+package azureutil
+
+import "citihub.com/compliance-as-code/internal/ledger"
+
+// LedgerResource returns the ledger resource of a resource of the type, e.g. "Microsoft.Storage/storageAccounts", in a
+// Resource Group of the Subscription, or of the Resource Group itself if resourceType is empty.
+func LedgerResource(kind, group, resourceType, name string) ledger.Resource {
+	return ledger.Resource{Provider: ledger.Azure, Kind: kind, ID: ledger.AzureID(SubscriptionID(), group, resourceType, name)}
+}
@@ -0,0 +1,123 @@
+package managementgroup
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"citihub.com/compliance-as-code/internal/azureutil"
+	"github.com/Azure/azure-sdk-for-go/services/preview/resources/mgmt/2019-11-01/managementgroups"
+	"github.com/Azure/go-autorest/autorest/azure/auth"
+	"github.com/Azure/go-autorest/autorest/to"
+)
+
+const subscriptionsPrefix = "/subscriptions/"
+
+// Root returns the Management Group at the top of the hierarchy to search, driven by environment variable AZURE_ROOT_MANAGEMENT_GROUP
+// (e.g. "boxbank-root", see terraform/directory/mgmtgroup.tf), or else the Tenant Root Group, whose name is the tenant ID, when
+// AZURE_POLICY_ASSIGNMENT_MANAGEMENT_GROUP does not name a group to start from instead, see Ancestors.
+// Searching the hierarchy needs read access to the root and its descendants, e.g. the Reader role assigned at the root.
+func Root() string {
+	if root := os.Getenv(azureutil.RootManagementGroup); root != "" {
+		return root
+	}
+	if os.Getenv(azureutil.PolicyAssignmentManagementGroup) != "" {
+		return ""
+	}
+	return os.Getenv("AZURE_TENANT_ID")
+}
+
+// Ancestors returns the names of the Management Groups that contain a Subscription, nearest first and ending with the root of
+// the hierarchy. It searches down from Root() or, when AZURE_ROOT_MANAGEMENT_GROUP is not set, from the Management Group in
+// AZURE_POLICY_ASSIGNMENT_MANAGEMENT_GROUP, walking up through the parents of that group to the root, so that the assignments
+// made above it are found too.
+func Ancestors(ctx context.Context, subscriptionID string) ([]string, error) {
+	from := Root()
+	if from == "" {
+		from = os.Getenv(azureutil.PolicyAssignmentManagementGroup)
+	}
+	if from == "" {
+		return nil, fmt.Errorf("no root Management Group: set environment variable %v, %v or AZURE_TENANT_ID", azureutil.RootManagementGroup, azureutil.PolicyAssignmentManagementGroup)
+	}
+
+	log.Printf("[DEBUG] Getting Management Group hierarchy under: %v", from)
+	mg, err := client().Get(ctx, from, "children", to.BoolPtr(true), "", "no-cache")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Management Group %v: %v", from, err)
+	}
+
+	var children []managementgroups.ChildInfo
+	if mg.Properties != nil && mg.Children != nil {
+		children = *mg.Children
+	}
+	path, ok := find(children, subscriptionID)
+	if !ok {
+		return nil, fmt.Errorf("subscription %v is not under Management Group %v", subscriptionID, from)
+	}
+
+	// find returns the path from the top down
+	ancestors := append([]string{from}, path...)
+	if Root() == "" {
+		above, err := parents(ctx, mg)
+		if err != nil {
+			return nil, fmt.Errorf("cannot walk up from Management Group %v, set %v to the root of the hierarchy: %v", from, azureutil.RootManagementGroup, err)
+		}
+		ancestors = append(above, ancestors...)
+	}
+	for i, j := 0, len(ancestors)-1; i < j; i, j = i+1, j-1 {
+		ancestors[i], ancestors[j] = ancestors[j], ancestors[i]
+	}
+	log.Printf("[DEBUG] Subscription %v is in Management Groups: %v", subscriptionID, ancestors)
+	return ancestors, nil
+}
+
+// parents returns the names of the Management Groups above a group, top down, following the parent of each group up to the
+// Tenant Root Group, which has none.
+func parents(ctx context.Context, mg managementgroups.ManagementGroup) ([]string, error) {
+	var above []string
+	seen := map[string]bool{strings.ToLower(to.String(mg.Name)): true}
+	for mg.Properties != nil && mg.Details != nil && mg.Details.Parent != nil {
+		name := to.String(mg.Details.Parent.Name)
+		if name == "" || seen[strings.ToLower(name)] {
+			break
+		}
+		seen[strings.ToLower(name)] = true
+		above = append([]string{name}, above...)
+
+		var err error
+		if mg, err = client().Get(ctx, name, "", nil, "", "no-cache"); err != nil {
+			return nil, fmt.Errorf("failed to get Management Group %v: %v", name, err)
+		}
+	}
+	return above, nil
+}
+
+// find returns the names of the Management Groups between the children and the Subscription, top down.
+func find(children []managementgroups.ChildInfo, subscriptionID string) ([]string, bool) {
+	for _, c := range children {
+		id := to.String(c.ID)
+		if strings.EqualFold(id, subscriptionsPrefix+subscriptionID) {
+			return nil, true
+		}
+		if c.Children == nil || strings.HasPrefix(strings.ToLower(id), subscriptionsPrefix) {
+			continue
+		}
+		if path, ok := find(*c.Children, subscriptionID); ok {
+			return append([]string{to.String(c.Name)}, path...), true
+		}
+	}
+	return nil, false
+}
+
+func client() managementgroups.Client {
+	c := managementgroups.NewClient()
+	a, err := auth.NewAuthorizerFromEnvironment()
+	if err == nil {
+		c.Authorizer = a
+	} else {
+		log.Fatalf("Unable to authorise Management Group client: %v", err)
+	}
+	return c
+}
@@ -0,0 +1,24 @@
+package network
+
+import (
+	"citihub.com/compliance-as-code/internal/azureutil"
+	"citihub.com/compliance-as-code/internal/ledger"
+)
+
+// Types of the network resources, as in their ARM IDs.
+const (
+	virtualNetworks   = "Microsoft.Network/virtualNetworks"
+	securityGroups    = "Microsoft.Network/networkSecurityGroups"
+	networkInterfaces = "Microsoft.Network/networkInterfaces"
+	publicIPAddresses = "Microsoft.Network/publicIPAddresses"
+)
+
+// record journals a resource of the test Resource Group in the ledger before it is created.
+func record(kind, resourceType, name string) error {
+	return ledger.Record(azureutil.LedgerResource(kind, azureutil.ResourceGroup(), resourceType, name))
+}
+
+// release journals that a resource of the test Resource Group was deleted.
+func release(kind, resourceType, name string) {
+	ledger.Release(azureutil.LedgerResource(kind, azureutil.ResourceGroup(), resourceType, name))
+}
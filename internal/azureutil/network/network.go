@@ -6,6 +6,7 @@ import (
 	"log"
 
 	"citihub.com/compliance-as-code/internal/azureutil"
+	"citihub.com/compliance-as-code/internal/ledger"
 
 	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2019-08-01/network"
 	"github.com/Azure/go-autorest/autorest/azure/auth"
@@ -14,6 +15,9 @@ import (
 
 // CreateVirtualNetwork creates a Virtual Network with CIDR 10.0.0.0/8  in the Subscription configured by environment variable AZURE_SUBSCRIPTION_ID.
 func CreateVirtualNetwork(ctx context.Context, name string) (vnet network.VirtualNetwork, err error) {
+	if err := record(ledger.KindVirtualNetwork, virtualNetworks, name); err != nil {
+		return vnet, err
+	}
 	c := vnetClient()
 	future, err := c.CreateOrUpdate(
 		ctx,
@@ -21,6 +25,7 @@ func CreateVirtualNetwork(ctx context.Context, name string) (vnet network.Virtua
 		name,
 		network.VirtualNetwork{
 			Location: to.StringPtr(azureutil.Location()),
+			Tags:     azureutil.Tags(ctx, nil),
 			VirtualNetworkPropertiesFormat: &network.VirtualNetworkPropertiesFormat{
 				AddressSpace: &network.AddressSpace{
 					AddressPrefixes: &[]string{"10.0.0.0/8"},
@@ -42,6 +47,9 @@ func CreateVirtualNetwork(ctx context.Context, name string) (vnet network.Virtua
 
 // CreateVirtualNetworkAndSubnets creates a Virtual Network with CIDR 10.0.0.0/8 and Subnets 10.0.0.0/16 and 10.1.0.0/16 in the Subscription configured by environment variable AZURE_SUBSCRIPTION_ID.
 func CreateVirtualNetworkAndSubnets(ctx context.Context, name, subnet1Name, subnet2Name string, tags map[string]*string) (vnet network.VirtualNetwork, err error) {
+	if err := record(ledger.KindVirtualNetwork, virtualNetworks, name); err != nil {
+		return vnet, err
+	}
 	c := vnetClient()
 	future, err := c.CreateOrUpdate(
 		ctx,
@@ -68,7 +76,7 @@ func CreateVirtualNetworkAndSubnets(ctx context.Context, name, subnet1Name, subn
 					},
 				},
 			},
-			Tags: tags,
+			Tags: azureutil.Tags(ctx, tags),
 		})
 
 	if err != nil {
@@ -83,10 +91,17 @@ func CreateVirtualNetworkAndSubnets(ctx context.Context, name, subnet1Name, subn
 	return future.Result(c)
 }
 
-// DeleteVirtualNetwork deletes a Virtual Network by name in the Subscription configured by environment variable AZURE_SUBSCRIPTION_ID.
+// DeleteVirtualNetwork deletes a Virtual Network by name in the Subscription configured by environment variable AZURE_SUBSCRIPTION_ID, and waits for its deletion to complete.
 func DeleteVirtualNetwork(ctx context.Context, name string) (network.VirtualNetworksDeleteFuture, error) {
 	vnetClient := vnetClient()
-	return vnetClient.Delete(ctx, azureutil.ResourceGroup(), name)
+	future, err := vnetClient.Delete(ctx, azureutil.ResourceGroup(), name)
+	if err == nil {
+		err = future.WaitForCompletionRef(ctx, vnetClient.Client)
+	}
+	if err == nil {
+		release(ledger.KindVirtualNetwork, virtualNetworks, name)
+	}
+	return future, err
 }
 
 // ListAllVNetByResourceGroup returns the VNets in the given Resource Group in the Subscription configured by environment variable AZURE_SUBSCRIPTION_ID.
@@ -5,6 +5,7 @@ import (
 	"log"
 
 	"citihub.com/compliance-as-code/internal/azureutil"
+	"citihub.com/compliance-as-code/internal/ledger"
 	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2019-08-01/network"
 	"github.com/Azure/go-autorest/autorest/azure/auth"
 	"github.com/Azure/go-autorest/autorest/to"
@@ -37,7 +38,7 @@ func CreateNIC(ctx context.Context, vnetName, subnetName, nsgName, ipName, nicNa
 				},
 			},
 		},
-		Tags: tags,
+		Tags: azureutil.Tags(ctx, tags),
 	}
 
 	if nsgName != "" {
@@ -48,6 +49,9 @@ func CreateNIC(ctx context.Context, vnetName, subnetName, nsgName, ipName, nicNa
 		nicParams.NetworkSecurityGroup = &nsg
 	}
 
+	if err := record(ledger.KindNetworkInterface, networkInterfaces, nicName); err != nil {
+		return nic, err
+	}
 	c := nicClient()
 	future, err := c.CreateOrUpdate(ctx, azureutil.ResourceGroup(), nicName, nicParams)
 	if err != nil {
@@ -67,9 +71,17 @@ func NIC(ctx context.Context, name string) (network.Interface, error) {
 	return nicClient().Get(ctx, azureutil.ResourceGroup(), name, "")
 }
 
-// DeleteNIC deletes an existing network interface by name.
+// DeleteNIC deletes an existing network interface by name, and waits for its deletion to complete.
 func DeleteNIC(ctx context.Context, name string) (network.InterfacesDeleteFuture, error) {
-	return nicClient().Delete(ctx, azureutil.ResourceGroup(), name)
+	c := nicClient()
+	future, err := c.Delete(ctx, azureutil.ResourceGroup(), name)
+	if err == nil {
+		err = future.WaitForCompletionRef(ctx, c.Client)
+	}
+	if err == nil {
+		release(ledger.KindNetworkInterface, networkInterfaces, name)
+	}
+	return future, err
 }
 
 func nicClient() network.InterfacesClient {
@@ -6,6 +6,7 @@ import (
 	"log"
 
 	"citihub.com/compliance-as-code/internal/azureutil"
+	"citihub.com/compliance-as-code/internal/ledger"
 	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2019-08-01/network"
 	"github.com/Azure/go-autorest/autorest/azure/auth"
 	"github.com/Azure/go-autorest/autorest/to"
@@ -18,6 +19,9 @@ func CreatePublicIP(ctx context.Context, ipName string, tags map[string]*string)
 		log.Fatalf("Unabled to get IPClient: %v", err)
 		return
 	}
+	if err := record(ledger.KindPublicIPAddress, publicIPAddresses, ipName); err != nil {
+		return ip, err
+	}
 	future, err := c.CreateOrUpdate(
 		ctx,
 		azureutil.ResourceGroup(),
@@ -29,7 +33,7 @@ func CreatePublicIP(ctx context.Context, ipName string, tags map[string]*string)
 				PublicIPAddressVersion:   network.IPv4,
 				PublicIPAllocationMethod: network.Static,
 			},
-			Tags: tags,
+			Tags: azureutil.Tags(ctx, tags),
 		},
 	)
 
@@ -62,6 +66,7 @@ func DeletePublicIP(ctx context.Context, ipName string) error {
 	if err != nil {
 		return fmt.Errorf("cannot get delete ip address future response: %v", err)
 	}
+	release(ledger.KindPublicIPAddress, publicIPAddresses, ipName)
 	log.Printf("[DEBUG] %v publicIP should be deleted", ipName)
 	return nil
 }
@@ -6,6 +6,7 @@ import (
 	"log"
 
 	"citihub.com/compliance-as-code/internal/azureutil"
+	"citihub.com/compliance-as-code/internal/ledger"
 	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2019-08-01/network"
 	"github.com/Azure/go-autorest/autorest/azure/auth"
 	"github.com/Azure/go-autorest/autorest/to"
@@ -13,6 +14,9 @@ import (
 
 // CreateNetworkSecurityGroup creates a new Network Security Group with rules set for allowing SSH and HTTPS use from all sources to all destinations.
 func CreateNetworkSecurityGroup(ctx context.Context, nsgName string, tags map[string]*string) (nsg network.SecurityGroup, err error) {
+	if err := record(ledger.KindNetworkSecurityGroup, securityGroups, nsgName); err != nil {
+		return nsg, err
+	}
 	c := nsgClient()
 	future, err := c.CreateOrUpdate(
 		ctx,
@@ -50,7 +54,7 @@ func CreateNetworkSecurityGroup(ctx context.Context, nsgName string, tags map[st
 					},
 				},
 			},
-			Tags: tags,
+			Tags: azureutil.Tags(ctx, tags),
 		},
 	)
 
@@ -73,6 +77,9 @@ func CreateCustomNetworkSecurityGroup(ctx context.Context, nsgName string, secur
 
 // CreateCustomNetworkSecurityGroupWithTags creates a new network security group with rules specified in 3rd argument
 func CreateCustomNetworkSecurityGroupWithTags(ctx context.Context, nsgName string, securityRules []network.SecurityRule, tags map[string]*string) (nsg network.SecurityGroup, err error) {
+	if err := record(ledger.KindNetworkSecurityGroup, securityGroups, nsgName); err != nil {
+		return nsg, err
+	}
 	c := nsgClient()
 	future, err := c.CreateOrUpdate(
 		ctx,
@@ -83,7 +90,7 @@ func CreateCustomNetworkSecurityGroupWithTags(ctx context.Context, nsgName strin
 			SecurityGroupPropertiesFormat: &network.SecurityGroupPropertiesFormat{
 				SecurityRules: &securityRules,
 			},
-			Tags: tags,
+			Tags: azureutil.Tags(ctx, tags),
 		},
 	)
 
@@ -101,6 +108,9 @@ func CreateCustomNetworkSecurityGroupWithTags(ctx context.Context, nsgName strin
 
 // CreateSimpleNetworkSecurityGroup creates a new network security group, without rules (rules can be set later)
 func CreateSimpleNetworkSecurityGroup(ctx context.Context, nsgName string) (nsg network.SecurityGroup, err error) {
+	if err := record(ledger.KindNetworkSecurityGroup, securityGroups, nsgName); err != nil {
+		return nsg, err
+	}
 	c := nsgClient()
 	future, err := c.CreateOrUpdate(
 		ctx,
@@ -108,6 +118,7 @@ func CreateSimpleNetworkSecurityGroup(ctx context.Context, nsgName string) (nsg
 		nsgName,
 		network.SecurityGroup{
 			Location: to.StringPtr(azureutil.Location()),
+			Tags:     azureutil.Tags(ctx, nil),
 		},
 	)
 
@@ -123,10 +134,17 @@ func CreateSimpleNetworkSecurityGroup(ctx context.Context, nsgName string) (nsg
 	return future.Result(c)
 }
 
-// DeleteNetworkSecurityGroup deletes an existing network security group
+// DeleteNetworkSecurityGroup deletes an existing network security group, and waits for its deletion to complete
 func DeleteNetworkSecurityGroup(ctx context.Context, nsgName string) (result network.SecurityGroupsDeleteFuture, err error) {
 	nsgClient := nsgClient()
-	return nsgClient.Delete(ctx, azureutil.ResourceGroup(), nsgName)
+	result, err = nsgClient.Delete(ctx, azureutil.ResourceGroup(), nsgName)
+	if err == nil {
+		err = result.WaitForCompletionRef(ctx, nsgClient.Client)
+	}
+	if err == nil {
+		release(ledger.KindNetworkSecurityGroup, securityGroups, nsgName)
+	}
+	return result, err
 }
 
 // SecurityGroup returns an existing network security group
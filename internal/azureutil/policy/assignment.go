@@ -2,27 +2,84 @@ package policy
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"time"
 
 	"citihub.com/compliance-as-code/internal/azureutil"
-	"github.com/Azure/azure-sdk-for-go/services/resources/mgmt/2019-01-01/policy"
+	"github.com/Azure/azure-sdk-for-go/services/resources/mgmt/2019-09-01/policy"
 	"github.com/Azure/go-autorest/autorest/azure/auth"
 )
 
+// SubscriptionScope returns the scope of a Subscription, e.g. for CreateAssignment.
+func SubscriptionScope(subscriptionID string) string {
+	return "/subscriptions/" + subscriptionID
+}
+
+// ManagementGroupScope returns the scope of a Management Group, e.g. for CreateAssignment.
+func ManagementGroupScope(managementGroup string) string {
+	return "/providers/Microsoft.Management/managementGroups/" + managementGroup
+}
+
 // AssignmentBySubscription gets a Policy Assignment by Policy Assignment name, scoped to a Subscription.
 func AssignmentBySubscription(ctx context.Context, subscriptionID, name string) (policy.Assignment, error) {
-	scope := "/subscriptions/" + subscriptionID
+	scope := SubscriptionScope(subscriptionID)
 	log.Printf("[DEBUG] Getting Policy Assignment with subscriptionID: %v", scope)
 	return assignmentClient().Get(ctx, scope, name)
 }
 
 // AssignmentByManagementGroup gets a Policy Assignment by Policy Assignment name, scoped to a Managed Group.
 func AssignmentByManagementGroup(ctx context.Context, managementGroup, name string) (policy.Assignment, error) {
-	scope := "/providers/Microsoft.Management/managementGroups/" + managementGroup
+	scope := ManagementGroupScope(managementGroup)
 	log.Printf("[DEBUG] Getting Policy Assignment with scope: %v", scope)
 	return assignmentClient().Get(ctx, scope, name)
 }
 
+// CreateAssignment creates or updates a Policy Assignment at a scope, e.g. a Subscription, Management Group or Resource Group.
+func CreateAssignment(ctx context.Context, scope, name string, a policy.Assignment) (policy.Assignment, error) {
+	log.Printf("[DEBUG] Creating Policy Assignment %v with scope: %v", name, scope)
+	return assignmentClient().Create(ctx, scope, name, a)
+}
+
+// DeleteAssignment deletes a Policy Assignment from a scope.
+func DeleteAssignment(ctx context.Context, scope, name string) error {
+	log.Printf("[DEBUG] Deleting Policy Assignment %v with scope: %v", name, scope)
+	_, err := assignmentClient().Delete(ctx, scope, name)
+	return err
+}
+
+// WaitForAssignment waits for a newly created Policy Assignment to propagate. It polls every interval until the assignment
+// can be read at the scope and, if probe is not nil, until probe reports that the assignment is enforced, e.g. because
+// a non-compliant deployment is denied. Azure may take up to 30 minutes to enforce a new assignment; pass a context with
+// a deadline to bound the wait.
+func WaitForAssignment(ctx context.Context, scope, name string, interval time.Duration, probe func(ctx context.Context) (bool, error)) (policy.Assignment, error) {
+	c := assignmentClient()
+	for {
+		a, err := c.Get(ctx, scope, name)
+		if err == nil {
+			if probe == nil {
+				return a, nil
+			}
+			enforced, err := probe(ctx)
+			if err != nil {
+				return a, fmt.Errorf("probe of Policy Assignment %v failed: %v", name, err)
+			}
+			if enforced {
+				log.Printf("[DEBUG] Policy Assignment %v with scope %v is enforced", name, scope)
+				return a, nil
+			}
+		} else {
+			log.Printf("[DEBUG] Policy Assignment %v with scope %v is not available yet: %v", name, scope, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return policy.Assignment{}, fmt.Errorf("timed out waiting for Policy Assignment %v with scope %v: %v", name, scope, ctx.Err())
+		case <-time.After(interval):
+		}
+	}
+}
+
 func assignmentClient() policy.AssignmentsClient {
 	c := policy.NewAssignmentsClient(azureutil.SubscriptionID())
 	a, err := auth.NewAuthorizerFromEnvironment()
@@ -2,11 +2,15 @@ package policy
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"log"
 
+	"citihub.com/compliance-as-code/internal/azurepolicy"
 	"citihub.com/compliance-as-code/internal/azureutil"
-	"github.com/Azure/azure-sdk-for-go/services/resources/mgmt/2019-01-01/policy"
+	"github.com/Azure/azure-sdk-for-go/services/resources/mgmt/2019-09-01/policy"
 	"github.com/Azure/go-autorest/autorest/azure/auth"
+	"github.com/Azure/go-autorest/autorest/to"
 )
 
 // DefinitionByName get a Policy Definition by name.
@@ -14,6 +18,52 @@ func DefinitionByName(ctx context.Context, name string) (policy.Definition, erro
 	return definitionClient().Get(ctx, name)
 }
 
+// DefinitionByManagementGroup gets a Policy Definition by name, scoped to a Management Group.
+func DefinitionByManagementGroup(ctx context.Context, managementGroup, name string) (policy.Definition, error) {
+	log.Printf("[DEBUG] Getting Policy Definition %v in Management Group: %v", name, managementGroup)
+	return definitionClient().GetAtManagementGroup(ctx, name, managementGroup)
+}
+
+// CreateOrUpdateDefinition creates or updates a custom Policy Definition in the Subscription.
+func CreateOrUpdateDefinition(ctx context.Context, d policy.Definition) (policy.Definition, error) {
+	log.Printf("[DEBUG] Creating Policy Definition %v in Subscription", to.String(d.Name))
+	return definitionClient().CreateOrUpdate(ctx, to.String(d.Name), d)
+}
+
+// CreateOrUpdateDefinitionAtManagementGroup creates or updates a custom Policy Definition in a Management Group.
+func CreateOrUpdateDefinitionAtManagementGroup(ctx context.Context, managementGroup string, d policy.Definition) (policy.Definition, error) {
+	log.Printf("[DEBUG] Creating Policy Definition %v in Management Group: %v", to.String(d.Name), managementGroup)
+	return definitionClient().CreateOrUpdateAtManagementGroup(ctx, to.String(d.Name), d, managementGroup)
+}
+
+// DeleteDefinition deletes a custom Policy Definition from the Subscription.
+func DeleteDefinition(ctx context.Context, name string) error {
+	log.Printf("[DEBUG] Deleting Policy Definition %v from Subscription", name)
+	_, err := definitionClient().Delete(ctx, name)
+	return err
+}
+
+// DeleteDefinitionAtManagementGroup deletes a custom Policy Definition from a Management Group.
+func DeleteDefinitionAtManagementGroup(ctx context.Context, managementGroup, name string) error {
+	log.Printf("[DEBUG] Deleting Policy Definition %v from Management Group: %v", name, managementGroup)
+	_, err := definitionClient().DeleteAtManagementGroup(ctx, name, managementGroup)
+	return err
+}
+
+// DefinitionFromSource returns the custom Policy Definition for a definition held in source control, ready to be created.
+func DefinitionFromSource(d azurepolicy.Definition) (policy.Definition, error) {
+	b, err := json.Marshal(d)
+	if err != nil {
+		return policy.Definition{}, fmt.Errorf("cannot encode Policy Definition %v: %v", d.Name, err)
+	}
+	var props policy.DefinitionProperties
+	if err := json.Unmarshal(b, &props); err != nil {
+		return policy.Definition{}, fmt.Errorf("cannot encode Policy Definition %v: %v", d.Name, err)
+	}
+	props.PolicyType = policy.Custom
+	return policy.Definition{Name: to.StringPtr(d.Name), DefinitionProperties: &props}, nil
+}
+
 func definitionClient() policy.DefinitionsClient {
 	c := policy.NewDefinitionsClient(azureutil.SubscriptionID())
 	a, err := auth.NewAuthorizerFromEnvironment()
@@ -0,0 +1,62 @@
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"citihub.com/compliance-as-code/internal/azurepolicy"
+	"citihub.com/compliance-as-code/internal/azureutil"
+	"github.com/Azure/azure-sdk-for-go/services/resources/mgmt/2019-09-01/policy"
+)
+
+// Drift is the result of comparing a Policy Definition in source control with the deployed Policy Definition of the same name.
+type Drift struct {
+	Definition  string
+	Scope       string
+	Differences []azurepolicy.Difference
+}
+
+// Drifted reports whether the deployed Policy Definition differs from source control.
+func (d Drift) Drifted() bool {
+	return len(d.Differences) > 0
+}
+
+// DefinitionDrift compares a Policy Definition in source control with the one deployed to the Management Group, or to the Subscription if managementGroup is empty.
+func DefinitionDrift(ctx context.Context, managementGroup string, source azurepolicy.Definition) (Drift, error) {
+	drift := Drift{Definition: source.Name}
+
+	var d policy.Definition
+	var err error
+	if managementGroup == "" {
+		drift.Scope = SubscriptionScope(azureutil.SubscriptionID())
+		d, err = DefinitionByName(ctx, source.Name)
+	} else {
+		drift.Scope = ManagementGroupScope(managementGroup)
+		d, err = DefinitionByManagementGroup(ctx, managementGroup, source.Name)
+	}
+	if err != nil {
+		return drift, fmt.Errorf("cannot get Policy Definition %v at %v: %v", source.Name, drift.Scope, err)
+	}
+
+	deployed, err := toDefinition(d)
+	if err != nil {
+		return drift, err
+	}
+	drift.Differences = azurepolicy.Diff(source, deployed)
+	log.Printf("[DEBUG] Policy Definition %v at %v has %d difference(s) from source control", source.Name, drift.Scope, len(drift.Differences))
+	return drift, nil
+}
+
+func toDefinition(d policy.Definition) (azurepolicy.Definition, error) {
+	b, err := json.Marshal(d.DefinitionProperties)
+	if err != nil {
+		return azurepolicy.Definition{}, fmt.Errorf("cannot read Policy Definition properties: %v", err)
+	}
+	def, err := azurepolicy.ParseDefinition(b)
+	if d.Name != nil {
+		def.Name = *d.Name
+	}
+	return def, err
+}
@@ -0,0 +1,217 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"citihub.com/compliance-as-code/internal/azureutil/managementgroup"
+	"github.com/Azure/azure-sdk-for-go/services/resources/mgmt/2019-09-01/policy"
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/Azure/go-autorest/autorest/to"
+)
+
+// assignmentsAPIVersion is the API version of the Policy Assignments listed at a Management Group, which the SDK client
+// cannot list.
+const assignmentsAPIVersion = "2019-09-01"
+
+// EffectiveAssignment is a Policy Assignment in effect at a scope, either made at the scope itself or inherited from a containing scope.
+type EffectiveAssignment struct {
+	policy.Assignment
+	// Target is the scope at which the assignment is in effect, e.g. a Resource Group.
+	Target string
+	// InheritedFrom is the scope at which the assignment is made, e.g. a parent Management Group. It equals Target if the assignment is not inherited.
+	InheritedFrom string
+}
+
+// Inherited reports whether the assignment is made at a containing scope rather than at the target itself.
+func (a EffectiveAssignment) Inherited() bool {
+	return !strings.EqualFold(a.Target, a.InheritedFrom)
+}
+
+func (a EffectiveAssignment) String() string {
+	if a.Inherited() {
+		return fmt.Sprintf("%v (inherited from %v)", to.String(a.Name), a.InheritedFrom)
+	}
+	return fmt.Sprintf("%v (assigned at %v)", to.String(a.Name), a.InheritedFrom)
+}
+
+// EffectiveAssignments returns the Policy Assignments in effect at a Subscription, or at one of its Resource Groups if resourceGroup is not empty.
+// It walks up from the target through the Subscription and every ancestor Management Group, see managementgroup.Ancestors, nearest scope first.
+// Assignments that exclude the target, or a Management Group above it, through their notScopes are not returned.
+func EffectiveAssignments(ctx context.Context, subscriptionID, resourceGroup string) ([]EffectiveAssignment, error) {
+	effective, _, err := resolve(ctx, subscriptionID, resourceGroup)
+	return effective, err
+}
+
+// EffectiveAssignmentByName returns the named Policy Assignment in effect at a Subscription, or at one of its Resource Groups if resourceGroup is not empty,
+// wherever in the hierarchy it is assigned.
+func EffectiveAssignmentByName(ctx context.Context, subscriptionID, resourceGroup, name string) (EffectiveAssignment, error) {
+	effective, excluded, err := resolve(ctx, subscriptionID, resourceGroup)
+	if err != nil {
+		return EffectiveAssignment{}, err
+	}
+
+	for _, a := range effective {
+		if strings.EqualFold(to.String(a.Name), name) {
+			log.Printf("[DEBUG] Policy Assignment in effect: %v", a)
+			return a, nil
+		}
+	}
+	for _, a := range excluded {
+		if strings.EqualFold(to.String(a.Name), name) {
+			return EffectiveAssignment{}, fmt.Errorf("policy assignment %v at %v excludes %v through its notScopes", name, a.InheritedFrom, a.Target)
+		}
+	}
+	return EffectiveAssignment{}, fmt.Errorf("policy assignment %v is not in effect at %v or any of its Management Groups", name, targetScope(subscriptionID, resourceGroup))
+}
+
+// resolve returns the effective assignments and those excluded by their notScopes.
+func resolve(ctx context.Context, subscriptionID, resourceGroup string) ([]EffectiveAssignment, []EffectiveAssignment, error) {
+	target := targetScope(subscriptionID, resourceGroup)
+
+	ancestors, err := managementgroup.Ancestors(ctx, subscriptionID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var effective, excluded []EffectiveAssignment
+	seen := map[string]bool{}
+	scopes := hierarchy(subscriptionID, resourceGroup, ancestors)
+	for _, scope := range scopes {
+		assignments, err := listAtScope(ctx, subscriptionID, resourceGroup, scope)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list Policy Assignments at %v: %v", scope, err)
+		}
+
+		for _, a := range assignments {
+			id := strings.ToLower(to.String(a.ID))
+			// Listing returns assignments of containing scopes too, which are attributed when the walk reaches them
+			if seen[id] || a.AssignmentProperties == nil || !strings.EqualFold(to.String(a.Scope), scope) {
+				continue
+			}
+			seen[id] = true
+
+			e := EffectiveAssignment{Assignment: a, Target: target, InheritedFrom: scope}
+			if excludes(a, target, scopes) {
+				log.Printf("[DEBUG] Policy Assignment %v at %v excludes %v through its notScopes", to.String(a.Name), scope, target)
+				excluded = append(excluded, e)
+				continue
+			}
+			effective = append(effective, e)
+		}
+	}
+	return effective, excluded, nil
+}
+
+// hierarchy returns the scopes from the target up to the root Management Group.
+func hierarchy(subscriptionID, resourceGroup string, ancestors []string) []string {
+	var scopes []string
+	if resourceGroup != "" {
+		scopes = append(scopes, targetScope(subscriptionID, resourceGroup))
+	}
+	scopes = append(scopes, SubscriptionScope(subscriptionID))
+	for _, mg := range ancestors {
+		scopes = append(scopes, ManagementGroupScope(mg))
+	}
+	return scopes
+}
+
+func listAtScope(ctx context.Context, subscriptionID, resourceGroup, scope string) ([]policy.Assignment, error) {
+	c := assignmentClient()
+
+	var it policy.AssignmentListResultIterator
+	var err error
+	switch {
+	case strings.EqualFold(scope, SubscriptionScope(subscriptionID)):
+		it, err = c.ListComplete(ctx, "atScope()")
+	case strings.HasPrefix(strings.ToLower(scope), strings.ToLower(ManagementGroupScope(""))):
+		return listAtManagementGroup(ctx, c, scope)
+	default:
+		it, err = c.ListForResourceGroupComplete(ctx, resourceGroup, "atScope()")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var assignments []policy.Assignment
+	for it.NotDone() {
+		assignments = append(assignments, it.Value())
+		if err := it.NextWithContext(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return assignments, nil
+}
+
+// listAtManagementGroup lists the Policy Assignments made at a Management Group scope, following the pages of the list.
+func listAtManagementGroup(ctx context.Context, c policy.AssignmentsClient, scope string) ([]policy.Assignment, error) {
+	req, err := autorest.Prepare((&http.Request{}).WithContext(ctx),
+		autorest.AsGet(),
+		autorest.WithBaseURL(c.BaseURI),
+		autorest.WithPath(scope+"/providers/Microsoft.Authorization/policyAssignments"),
+		autorest.WithQueryParameters(map[string]interface{}{"api-version": assignmentsAPIVersion, "$filter": autorest.Encode("query", "atScope()")}))
+	if err != nil {
+		return nil, err
+	}
+
+	var assignments []policy.Assignment
+	for req != nil {
+		resp, err := c.Send(req, autorest.DoRetryForStatusCodes(c.RetryAttempts, c.RetryDuration, autorest.StatusCodesForRetry...))
+		if err != nil {
+			return nil, err
+		}
+		var page policy.AssignmentListResult
+		err = autorest.Respond(resp,
+			azure.WithErrorUnlessStatusCode(http.StatusOK),
+			autorest.ByUnmarshallingJSON(&page),
+			autorest.ByClosing())
+		if err != nil {
+			return nil, err
+		}
+		if page.Value != nil {
+			assignments = append(assignments, *page.Value...)
+		}
+
+		req = nil
+		if next := to.String(page.NextLink); next != "" {
+			req, err = autorest.Prepare((&http.Request{}).WithContext(ctx), autorest.AsGet(), autorest.WithBaseURL(next))
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return assignments, nil
+}
+
+// excludes reports whether one of the assignment's notScopes contains the target: the target itself, a scope that the
+// target is under by its ID, e.g. its Subscription, or one of the scopes of the hierarchy above it, e.g. an intermediate
+// Management Group.
+func excludes(a policy.Assignment, target string, scopes []string) bool {
+	if a.NotScopes == nil {
+		return false
+	}
+	t := strings.ToLower(target)
+	for _, ns := range *a.NotScopes {
+		ns = strings.TrimSuffix(strings.ToLower(ns), "/")
+		if t == ns || strings.HasPrefix(t, ns+"/") {
+			return true
+		}
+		for _, s := range scopes {
+			if strings.EqualFold(s, ns) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func targetScope(subscriptionID, resourceGroup string) string {
+	if resourceGroup == "" {
+		return SubscriptionScope(subscriptionID)
+	}
+	return SubscriptionScope(subscriptionID) + "/resourceGroups/" + resourceGroup
+}
@@ -0,0 +1,297 @@
+package policy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"citihub.com/compliance-as-code/internal/azurepolicy"
+	"github.com/Azure/azure-sdk-for-go/services/resources/mgmt/2019-09-01/policy"
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/Azure/go-autorest/autorest/to"
+)
+
+// Enforcement modes of a Policy Assignment. An assignment in DoNotEnforce mode is evaluated for compliance but its effect is not applied.
+const (
+	EnforcementModeDefault      = "Default"
+	EnforcementModeDoNotEnforce = "DoNotEnforce"
+)
+
+// nonComplianceMessagesAPIVersion is the first API version of Policy Assignments with nonComplianceMessages, which the SDK models predate.
+const nonComplianceMessagesAPIVersion = "2020-09-01"
+
+// Inspection is what a Policy Assignment enforces: its enforcement mode, the effect of its Policy Definition and the parameter values it is evaluated with.
+type Inspection struct {
+	EffectiveAssignment
+	// DefinitionID is the ID of the assigned Policy Definition.
+	DefinitionID string
+	// DefinitionDisplayName is the display name of the assigned Policy Definition.
+	DefinitionDisplayName string
+	// NonComplianceMessages are the messages of the assignment that Azure reports when a request is denied or a resource is non-compliant.
+	NonComplianceMessages []string
+	// Effect is the effect of the Policy Definition with the assigned parameter values, e.g. "Deny".
+	Effect string
+	// EnforcementMode is either EnforcementModeDefault or EnforcementModeDoNotEnforce.
+	EnforcementMode string
+	// Parameters holds the value of every parameter of the Policy Definition, either assigned or its default value.
+	Parameters map[string]interface{}
+}
+
+// Enforced reports whether the effect of the assignment is applied.
+func (i Inspection) Enforced() bool {
+	return !strings.EqualFold(i.EnforcementMode, EnforcementModeDoNotEnforce)
+}
+
+// Messages returns the display names of the assignment and its Policy Definition and the non-compliance messages of the assignment,
+// i.e. the metadata that a denial can be matched against.
+func (i Inspection) Messages() []string {
+	var m []string
+	for _, s := range append([]string{to.String(i.DisplayName), i.DefinitionDisplayName}, i.NonComplianceMessages...) {
+		if s != "" {
+			m = append(m, s)
+		}
+	}
+	return m
+}
+
+// CheckEffect returns an error unless the assignment is enforced with the given effect, compared case-insensitively as Azure does.
+func (i Inspection) CheckEffect(effect string) error {
+	if !i.Enforced() {
+		return fmt.Errorf("policy assignment %v is not enforced, its enforcement mode is %v", to.String(i.Name), i.EnforcementMode)
+	}
+	if !strings.EqualFold(i.Effect, effect) {
+		return fmt.Errorf("policy assignment %v has effect %v, expected %v", to.String(i.Name), i.Effect, effect)
+	}
+	return nil
+}
+
+// CheckParameter returns an error unless the named parameter has the expected value. The expected value is read as JSON, e.g. ["10.0.0.0/24"],
+// falling back to a plain string. Strings are compared case-insensitively and arrays regardless of the order of their members.
+func (i Inspection) CheckParameter(name, expected string) error {
+	var want interface{}
+	d := json.NewDecoder(strings.NewReader(expected))
+	d.UseNumber()
+	if err := d.Decode(&want); err != nil || d.More() {
+		want = expected
+	}
+
+	for k, v := range i.Parameters {
+		if !strings.EqualFold(k, name) {
+			continue
+		}
+		if !sameValue(want, v) {
+			return fmt.Errorf("parameter %v of policy assignment %v is %v, expected %v", name, to.String(i.Name), jsonString(v), jsonString(want))
+		}
+		return nil
+	}
+	return fmt.Errorf("policy definition %v of policy assignment %v has no parameter %v", i.DefinitionID, to.String(i.Name), name)
+}
+
+// InspectAssignment reads the Policy Definition of an assignment and resolves its effect and parameter values.
+// Assignments of Policy Set Definitions (initiatives) are not supported, as each member definition has its own effect.
+func InspectAssignment(ctx context.Context, a EffectiveAssignment) (Inspection, error) {
+	i := Inspection{EffectiveAssignment: a}
+
+	// Read the properties as JSON so that values are decoded the same way as in source control
+	var props struct {
+		PolicyDefinitionID string `json:"policyDefinitionId"`
+		EnforcementMode    string `json:"enforcementMode"`
+		Parameters         map[string]struct {
+			Value interface{} `json:"value"`
+		} `json:"parameters"`
+	}
+	b, err := json.Marshal(a.AssignmentProperties)
+	if err != nil {
+		return i, fmt.Errorf("cannot read Policy Assignment %v properties: %v", to.String(a.Name), err)
+	}
+	if err := decode(b, &props); err != nil {
+		return i, fmt.Errorf("cannot read Policy Assignment %v properties: %v", to.String(a.Name), err)
+	}
+
+	i.DefinitionID = props.PolicyDefinitionID
+	i.EnforcementMode = props.EnforcementMode
+	if i.EnforcementMode == "" {
+		i.EnforcementMode = EnforcementModeDefault
+	}
+
+	d, err := definitionByID(ctx, props.PolicyDefinitionID)
+	if err != nil {
+		return i, fmt.Errorf("cannot get Policy Definition of Policy Assignment %v: %v", to.String(a.Name), err)
+	}
+	def, err := toDefinition(d)
+	if err != nil {
+		return i, err
+	}
+	i.DefinitionDisplayName = def.DisplayName
+
+	if i.NonComplianceMessages, err = nonComplianceMessages(ctx, to.String(a.ID)); err != nil {
+		return i, fmt.Errorf("cannot get non-compliance messages of Policy Assignment %v: %v", to.String(a.Name), err)
+	}
+
+	rule, params, err := ruleAndParameters(def)
+	if err != nil {
+		return i, fmt.Errorf("policy definition %v: %v", props.PolicyDefinitionID, err)
+	}
+
+	values := map[string]interface{}{}
+	for k, v := range props.Parameters {
+		values[k] = v.Value
+	}
+	i.Parameters = map[string]interface{}{}
+	for k, p := range params {
+		i.Parameters[k] = p.DefaultValue
+		for name, v := range values {
+			if strings.EqualFold(name, k) {
+				i.Parameters[k] = v
+			}
+		}
+	}
+
+	ev := azurepolicy.Evaluator{Parameters: params, Values: values}
+	if i.Effect, err = ev.Effect(rule); err != nil {
+		return i, fmt.Errorf("cannot resolve effect of Policy Assignment %v: %v", to.String(a.Name), err)
+	}
+
+	log.Printf("[DEBUG] Policy Assignment %v has effect %v, enforcement mode %v and parameters %v", to.String(a.Name), i.Effect, i.EnforcementMode, jsonString(i.Parameters))
+	return i, nil
+}
+
+// definitionByID gets a built-in or custom Policy Definition by its ID, e.g.
+// "/providers/Microsoft.Management/managementGroups/<group>/providers/Microsoft.Authorization/policyDefinitions/<name>".
+func definitionByID(ctx context.Context, id string) (policy.Definition, error) {
+	const definitions = "/providers/microsoft.authorization/policydefinitions/"
+
+	lower := strings.ToLower(id)
+	i := strings.LastIndex(lower, definitions)
+	if i < 0 {
+		if strings.Contains(lower, "/policysetdefinitions/") {
+			return policy.Definition{}, fmt.Errorf("%v is a Policy Set Definition, which cannot be inspected", id)
+		}
+		return policy.Definition{}, fmt.Errorf("%v is not a Policy Definition ID", id)
+	}
+	name, scope := id[i+len(definitions):], lower[:i]
+
+	switch {
+	case scope == "":
+		log.Printf("[DEBUG] Getting built-in Policy Definition %v", name)
+		return definitionClient().GetBuiltIn(ctx, name)
+	case strings.HasPrefix(scope, strings.ToLower(ManagementGroupScope(""))):
+		return DefinitionByManagementGroup(ctx, id[len(ManagementGroupScope("")):i], name)
+	}
+	return DefinitionByName(ctx, name)
+}
+
+// nonComplianceMessages gets the non-compliance messages of a Policy Assignment by its ID.
+func nonComplianceMessages(ctx context.Context, id string) ([]string, error) {
+	c := assignmentClient()
+	req, err := autorest.Prepare((&http.Request{}).WithContext(ctx),
+		autorest.AsGet(),
+		autorest.WithBaseURL(c.BaseURI),
+		autorest.WithPath(id),
+		autorest.WithQueryParameters(map[string]interface{}{"api-version": nonComplianceMessagesAPIVersion}))
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.Send(req, autorest.DoRetryForStatusCodes(c.RetryAttempts, c.RetryDuration, autorest.StatusCodesForRetry...))
+	if err != nil {
+		return nil, err
+	}
+
+	var a struct {
+		Properties struct {
+			NonComplianceMessages []struct {
+				Message string `json:"message"`
+			} `json:"nonComplianceMessages"`
+		} `json:"properties"`
+	}
+	err = autorest.Respond(resp,
+		azure.WithErrorUnlessStatusCode(http.StatusOK),
+		autorest.ByUnmarshallingJSON(&a),
+		autorest.ByClosing())
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []string
+	for _, m := range a.Properties.NonComplianceMessages {
+		messages = append(messages, m.Message)
+	}
+	return messages, nil
+}
+
+func ruleAndParameters(d azurepolicy.Definition) (azurepolicy.Rule, azurepolicy.Parameters, error) {
+	b, err := json.Marshal(d.PolicyRule)
+	if err != nil {
+		return azurepolicy.Rule{}, nil, err
+	}
+	rule, err := azurepolicy.ParseRule(b)
+	if err != nil {
+		return rule, nil, err
+	}
+
+	params := azurepolicy.Parameters{}
+	if d.Parameters != nil {
+		if b, err = json.Marshal(d.Parameters); err != nil {
+			return rule, nil, err
+		}
+		if params, err = azurepolicy.ParseParameters(b); err != nil {
+			return rule, nil, err
+		}
+	}
+	return rule, params, nil
+}
+
+// sameValue compares an expected parameter value with the assigned one.
+func sameValue(want, got interface{}) bool {
+	switch w := want.(type) {
+	case string:
+		g, ok := got.(string)
+		return ok && strings.EqualFold(w, g)
+	case json.Number:
+		g, ok := got.(json.Number)
+		if !ok {
+			return false
+		}
+		wf, werr := w.Float64()
+		gf, gerr := g.Float64()
+		return werr == nil && gerr == nil && wf == gf
+	case []interface{}:
+		g, ok := got.([]interface{})
+		if !ok || len(w) != len(g) {
+			return false
+		}
+		return reflect.DeepEqual(sortedJSON(w), sortedJSON(g))
+	}
+	return reflect.DeepEqual(want, got)
+}
+
+// sortedJSON returns the members of an array as sorted JSON strings, so that arrays can be compared regardless of order.
+func sortedJSON(values []interface{}) []string {
+	s := make([]string, len(values))
+	for i, v := range values {
+		s[i] = strings.ToLower(jsonString(v))
+	}
+	sort.Strings(s)
+	return s
+}
+
+func jsonString(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(b)
+}
+
+func decode(b []byte, v interface{}) error {
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	return d.Decode(v)
+}
@@ -0,0 +1,59 @@
+package policy
+
+import (
+	"context"
+	"log"
+
+	"citihub.com/compliance-as-code/internal/azureutil"
+	"github.com/Azure/azure-sdk-for-go/services/resources/mgmt/2019-09-01/policy"
+	"github.com/Azure/go-autorest/autorest/azure/auth"
+	"github.com/Azure/go-autorest/autorest/to"
+)
+
+// SetDefinitionByName gets a Policy Set Definition (initiative) by name.
+func SetDefinitionByName(ctx context.Context, name string) (policy.SetDefinition, error) {
+	return setDefinitionClient().Get(ctx, name)
+}
+
+// SetDefinitionByManagementGroup gets a Policy Set Definition by name, scoped to a Management Group.
+func SetDefinitionByManagementGroup(ctx context.Context, managementGroup, name string) (policy.SetDefinition, error) {
+	log.Printf("[DEBUG] Getting Policy Set Definition %v in Management Group: %v", name, managementGroup)
+	return setDefinitionClient().GetAtManagementGroup(ctx, name, managementGroup)
+}
+
+// CreateOrUpdateSetDefinition creates or updates a custom Policy Set Definition in the Subscription.
+func CreateOrUpdateSetDefinition(ctx context.Context, d policy.SetDefinition) (policy.SetDefinition, error) {
+	log.Printf("[DEBUG] Creating Policy Set Definition %v in Subscription", to.String(d.Name))
+	return setDefinitionClient().CreateOrUpdate(ctx, to.String(d.Name), d)
+}
+
+// CreateOrUpdateSetDefinitionAtManagementGroup creates or updates a custom Policy Set Definition in a Management Group.
+func CreateOrUpdateSetDefinitionAtManagementGroup(ctx context.Context, managementGroup string, d policy.SetDefinition) (policy.SetDefinition, error) {
+	log.Printf("[DEBUG] Creating Policy Set Definition %v in Management Group: %v", to.String(d.Name), managementGroup)
+	return setDefinitionClient().CreateOrUpdateAtManagementGroup(ctx, to.String(d.Name), d, managementGroup)
+}
+
+// DeleteSetDefinition deletes a custom Policy Set Definition from the Subscription.
+func DeleteSetDefinition(ctx context.Context, name string) error {
+	log.Printf("[DEBUG] Deleting Policy Set Definition %v from Subscription", name)
+	_, err := setDefinitionClient().Delete(ctx, name)
+	return err
+}
+
+// DeleteSetDefinitionAtManagementGroup deletes a custom Policy Set Definition from a Management Group.
+func DeleteSetDefinitionAtManagementGroup(ctx context.Context, managementGroup, name string) error {
+	log.Printf("[DEBUG] Deleting Policy Set Definition %v from Management Group: %v", name, managementGroup)
+	_, err := setDefinitionClient().DeleteAtManagementGroup(ctx, name, managementGroup)
+	return err
+}
+
+func setDefinitionClient() policy.SetDefinitionsClient {
+	c := policy.NewSetDefinitionsClient(azureutil.SubscriptionID())
+	a, err := auth.NewAuthorizerFromEnvironment()
+	if err == nil {
+		c.Authorizer = a
+	} else {
+		log.Fatalf("Unable to authorise Policy Set Definition client: %v", err)
+	}
+	return c
+}
@@ -7,9 +7,8 @@ import (
 	"citihub.com/compliance-as-code/internal/azureutil"
 )
 
-// Cleanup deletes the resource group created for the sample
+// Cleanup deletes the resource group created for the sample and waits for its deletion to complete
 func Cleanup(ctx context.Context) error {
 	log.Println("deleting resources")
-	_, err := DeleteGroup(ctx, azureutil.GetAzureResourceGP())
-	return err
+	return DeleteGroupAndWait(ctx, azureutil.ResourceGroup())
 }
@@ -4,17 +4,26 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net/http"
 	"strings"
 	"sync"
+	"time"
 
 	"citihub.com/compliance-as-code/internal/azureutil"
+	"citihub.com/compliance-as-code/internal/ledger"
 	"github.com/Azure/azure-sdk-for-go/services/resources/mgmt/2018-02-01/resources"
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/azure"
 	"github.com/Azure/go-autorest/autorest/azure/auth"
 	"github.com/Azure/go-autorest/autorest/to"
 )
 
+// createdTimeAPIVersion is an API version of resource groups that returns when they were created with $expand=createdTime,
+// which the SDK models predate.
+const createdTimeAPIVersion = "2019-08-01"
+
 func getGroupsClient() resources.GroupsClient {
-	groupsClient := resources.NewGroupsClient(azureutil.GetAzureSubscriptionID())
+	groupsClient := resources.NewGroupsClient(azureutil.SubscriptionID())
 	// create an authorizer from env vars or Azure Managed Service Identity
 	authorizer, err := auth.NewAuthorizerFromEnvironment()
 	if err == nil {
@@ -28,41 +37,117 @@ func getGroupsClient() resources.GroupsClient {
 // CreateGroup creates a new resource group named by groupName on default location
 func CreateGroup(ctx context.Context, groupName string) (resources.Group, error) {
 	groupsClient := getGroupsClient()
-	log.Println(fmt.Sprintf("creating resource group '%s' on location: %v", groupName, azureutil.GetAzureLocation()))
+	log.Println(fmt.Sprintf("creating resource group '%s' on location: %v", groupName, azureutil.Location()))
+	if err := ledger.Record(groupResource(groupName)); err != nil {
+		return resources.Group{}, err
+	}
 	return groupsClient.CreateOrUpdate(
 		ctx,
 		groupName,
 		resources.Group{
-			Location: to.StringPtr(azureutil.GetAzureLocation()),
+			Location: to.StringPtr(azureutil.Location()),
+			Tags:     azureutil.Tags(ctx, nil),
 		})
 }
 
-// CreateGroupWithTags creates a new resource group named by groupName with given tags on default location
+// CreateGroupWithTags creates a new resource group named by groupName with given tags, in addition to those of the tagging policy carried by ctx, on default location
 func CreateGroupWithTags(ctx context.Context, groupName string, tags map[string]*string) (resources.Group, error) {
 	groupsClient := getGroupsClient()
-	log.Println(fmt.Sprintf("creating resource group '%s' on location: %v", groupName, azureutil.GetAzureLocation()))
+	log.Println(fmt.Sprintf("creating resource group '%s' on location: %v", groupName, azureutil.Location()))
+	if err := ledger.Record(groupResource(groupName)); err != nil {
+		return resources.Group{}, err
+	}
 	return groupsClient.CreateOrUpdate(
 		ctx,
 		groupName,
 		resources.Group{
-			Location: to.StringPtr(azureutil.GetAzureLocation()),
-			Tags:     tags,
+			Location: to.StringPtr(azureutil.Location()),
+			Tags:     azureutil.Tags(ctx, tags),
 		})
 }
 
-// DeleteGroup removes the resource group named by env var
+// DeleteGroup removes the resource group named by env var. Its deletion is journaled once it completes, see
+// DeleteGroupAndWait and WaitForDeleteCompletion.
 func DeleteGroup(ctx context.Context, groupName string) (result resources.GroupsDeleteFuture, err error) {
 	return getGroupsClient().Delete(ctx, groupName)
 }
 
+// DeleteGroupAndWait removes the resource group named by groupName and waits for its deletion to complete
+func DeleteGroupAndWait(ctx context.Context, groupName string) error {
+	future, err := DeleteGroup(ctx, groupName)
+	if err != nil {
+		return err
+	}
+	if err := future.WaitForCompletionRef(ctx, getGroupsClient().Client); err != nil {
+		return err
+	}
+	ledger.Release(groupResource(groupName))
+	return nil
+}
+
+func groupResource(groupName string) ledger.Resource {
+	return azureutil.LedgerResource(ledger.KindResourceGroup, groupName, "", "")
+}
+
 // ListGroups gets an iterator that gets all resource groups in the subscription
 func ListGroups(ctx context.Context) (resources.GroupListResultIterator, error) {
 	return getGroupsClient().ListComplete(ctx, "", nil)
 }
 
+// GroupsCreated gets when the resource groups in the subscription were created, by lower-case name. Groups whose creation
+// time Azure does not return are absent.
+func GroupsCreated(ctx context.Context) (map[string]time.Time, error) {
+	c := getGroupsClient()
+	req, err := autorest.Prepare((&http.Request{}).WithContext(ctx),
+		autorest.AsGet(),
+		autorest.WithBaseURL(c.BaseURI),
+		autorest.WithPathParameters("/subscriptions/{subscriptionId}/resourcegroups", map[string]interface{}{
+			"subscriptionId": autorest.Encode("path", c.SubscriptionID)}),
+		autorest.WithQueryParameters(map[string]interface{}{"api-version": createdTimeAPIVersion, "$expand": "createdTime"}))
+	if err != nil {
+		return nil, err
+	}
+
+	created := map[string]time.Time{}
+	for req != nil {
+		resp, err := c.Send(req, autorest.DoRetryForStatusCodes(c.RetryAttempts, c.RetryDuration, autorest.StatusCodesForRetry...))
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Value []struct {
+				Name        string     `json:"name"`
+				CreatedTime *time.Time `json:"createdTime"`
+			} `json:"value"`
+			NextLink string `json:"nextLink"`
+		}
+		err = autorest.Respond(resp,
+			azure.WithErrorUnlessStatusCode(http.StatusOK),
+			autorest.ByUnmarshallingJSON(&page),
+			autorest.ByClosing())
+		if err != nil {
+			return nil, err
+		}
+		for _, g := range page.Value {
+			if g.CreatedTime != nil {
+				created[strings.ToLower(g.Name)] = *g.CreatedTime
+			}
+		}
+
+		req = nil
+		if page.NextLink != "" {
+			req, err = autorest.Prepare((&http.Request{}).WithContext(ctx), autorest.AsGet(), autorest.WithBaseURL(page.NextLink))
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return created, nil
+}
+
 // GetGroup gets info on the resource group in use
 func GetGroup(ctx context.Context) (resources.Group, error) {
-	return getGroupsClient().Get(ctx, azureutil.GetAzureResourceGP())
+	return getGroupsClient().Get(ctx, azureutil.ResourceGroup())
 }
 
 // DeleteAllGroupsWithPrefix deletes all resource groups that start with a certain prefix
@@ -94,6 +179,7 @@ func WaitForDeleteCompletion(ctx context.Context, wg *sync.WaitGroup, futures []
 			if err != nil {
 				log.Fatalf("got error: %s", err)
 			} else {
+				ledger.Release(groupResource(rg))
 				fmt.Printf("finished deleting group '%s'\n", rg)
 			}
 			wg.Done()
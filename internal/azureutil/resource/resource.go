@@ -13,7 +13,7 @@ import (
 )
 
 func getResourcesClient() resources.Client {
-	resourcesClient := resources.NewClient(azureutil.GetAzureSubscriptionID())
+	resourcesClient := resources.NewClient(azureutil.SubscriptionID())
 	// create an authorizer from env vars or Azure Managed Service Identity
 	authorizer, err := auth.NewAuthorizerFromEnvironment()
 	if err == nil {
@@ -53,7 +53,7 @@ func GetResource(ctx context.Context, resourceProvider, resourceType, resourceNa
 
 	return resourcesClient.Get(
 		ctx,
-		azureutil.GetAzureResourceGP(),
+		azureutil.ResourceGroup(),
 		resourceProvider,
 		"",
 		resourceType,
@@ -61,6 +61,19 @@ func GetResource(ctx context.Context, resourceProvider, resourceType, resourceNa
 	)
 }
 
+// DeleteByID deletes a resource, or a resource group, by its ID and waits for the deletion to complete. As for GetResource,
+// the API version overrides the API version in the SDK, as not all resources are supported on all API versions.
+func DeleteByID(ctx context.Context, resourceID, apiVersion string) error {
+	resourcesClient := getResourcesClient()
+	resourcesClient.RequestInspector = WithAPIVersion(apiVersion)
+
+	future, err := resourcesClient.DeleteByID(ctx, resourceID)
+	if err != nil {
+		return err
+	}
+	return future.WaitForCompletionRef(ctx, resourcesClient.Client)
+}
+
 // GetResourceByID gets a resource, the generic way.
 func GetResourceByID(ctx context.Context, resourceID string) (resources.GenericResource, error) {
 	resourcesClient := getResourcesClient()
@@ -5,6 +5,7 @@ import (
 	"log"
 
 	"citihub.com/compliance-as-code/internal/azureutil"
+	"citihub.com/compliance-as-code/internal/ledger"
 	"github.com/Azure/azure-sdk-for-go/services/preview/sql/mgmt/2015-05-01-preview/sql"
 	"github.com/Azure/go-autorest/autorest"
 	"github.com/Azure/go-autorest/autorest/azure/auth"
@@ -15,6 +16,9 @@ import (
 
 // CreateServer creates or updates a SQL Server instance and waits for request completion.
 func CreateServer(ctx context.Context, rgName, serverName, dbLogin, dbPassword string, tags map[string]*string) (server sql.Server, err error) {
+	if err := ledger.Record(azureutil.LedgerResource(ledger.KindSQLServer, rgName, "Microsoft.Sql/servers", serverName)); err != nil {
+		return server, err
+	}
 	c := serverClient()
 	future, err := c.CreateOrUpdate(
 		ctx,
@@ -26,7 +30,7 @@ func CreateServer(ctx context.Context, rgName, serverName, dbLogin, dbPassword s
 				AdministratorLogin:         to.StringPtr(dbLogin),
 				AdministratorLoginPassword: to.StringPtr(dbPassword),
 			},
-			Tags: tags,
+			Tags: azureutil.Tags(ctx, tags),
 		})
 
 	if err != nil {
@@ -64,6 +68,7 @@ func CreateDB(ctx context.Context, rgName, serverName, dbName string) (db sql.Da
 		dbName,
 		sql.Database{
 			Location: to.StringPtr(azureutil.Location()),
+			Tags:     azureutil.Tags(ctx, nil),
 		})
 	if err != nil {
 		return db, err
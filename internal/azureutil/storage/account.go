@@ -6,6 +6,7 @@ import (
 	"log"
 
 	"citihub.com/compliance-as-code/internal/azureutil"
+	"citihub.com/compliance-as-code/internal/ledger"
 
 	"github.com/Azure/azure-sdk-for-go/services/storage/mgmt/2019-04-01/storage"
 	"github.com/Azure/go-autorest/autorest/azure/auth"
@@ -39,6 +40,9 @@ func CreateWithNetworkRuleSet(ctx context.Context, accountName, accountGroupName
 		NetworkRuleSet:         networkRuleSet,
 	}
 
+	if err := ledger.Record(azureutil.LedgerResource(ledger.KindStorageAccount, accountGroupName, "Microsoft.Storage/storageAccounts", accountName)); err != nil {
+		return sa, err
+	}
 	future, err := c.Create(
 		ctx,
 		accountGroupName,
@@ -49,7 +53,7 @@ func CreateWithNetworkRuleSet(ctx context.Context, accountName, accountGroupName
 			Kind:                              storage.Storage,
 			Location:                          to.StringPtr(azureutil.Location()),
 			AccountPropertiesCreateParameters: networkRuleSetParam,
-			Tags:                              tags,
+			Tags:                              azureutil.Tags(ctx, tags),
 		})
 
 	if err != nil {
@@ -64,6 +68,16 @@ func CreateWithNetworkRuleSet(ctx context.Context, accountName, accountGroupName
 	return future.Result(c)
 }
 
+// Delete deletes a Storage Account
+func Delete(ctx context.Context, accountName, accountGroupName string) error {
+	// Unlike the other resources, Storage Accounts are deleted by the time the request returns, there is no future to wait for
+	_, err := accountClient().Delete(ctx, accountGroupName, accountName)
+	if err == nil {
+		ledger.Release(azureutil.LedgerResource(ledger.KindStorageAccount, accountGroupName, "Microsoft.Storage/storageAccounts", accountName))
+	}
+	return err
+}
+
 // AccountProperties returns the properties for the specified storage account including but not limited to name, SKU name, location, and account status
 func AccountProperties(ctx context.Context, rgName, accountName string) (storage.Account, error) {
 	return accountClient().GetProperties(ctx, rgName, accountName, "")
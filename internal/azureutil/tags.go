@@ -0,0 +1,18 @@
+package azureutil
+
+import (
+	"context"
+
+	"citihub.com/compliance-as-code/internal/tagging"
+	"github.com/Azure/go-autorest/autorest/to"
+)
+
+// Tags returns the tags of a resource created with the tagging policy carried by the context (see internal/tagging),
+// merged with additional tags, which may be nil.
+func Tags(ctx context.Context, additional map[string]*string) map[string]*string {
+	tags := map[string]*string{}
+	for k, v := range tagging.Tags(ctx, to.StringMap(additional)) {
+		tags[k] = to.StringPtr(v)
+	}
+	return tags
+}
@@ -0,0 +1,73 @@
+package clouderr
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+)
+
+// AWS error codes, see https://docs.aws.amazon.com/AmazonS3/latest/API/ErrorResponses.html and the retryer of aws-sdk-go
+var (
+	awsThrottling = []string{"Throttling", "ThrottlingException", "ThrottledException", "RequestThrottledException", "TooManyRequestsException",
+		"ProvisionedThroughputExceededException", "RequestLimitExceeded", "RequestThrottled", "PriorRequestNotComplete",
+		"TransactionInProgressException", "EC2ThrottledException", "SlowDown"}
+	awsAuth = []string{"AccessDenied", "AccessDeniedException", "UnauthorizedOperation", "UnrecognizedClientException", "InvalidClientTokenId",
+		"InvalidAccessKeyId", "SignatureDoesNotMatch", "ExpiredToken", "ExpiredTokenException", "RequestExpired", "AuthFailure", "NoCredentialProviders"}
+	awsNotFound = []string{"NotFound", "NoSuchBucket", "NoSuchKey", "NoSuchBucketPolicy", "NoSuchEntity", "ResourceNotFoundException",
+		"NoSuchConfigRuleException"}
+	// awsNetwork are the codes of aws-sdk-go for requests that failed to be sent or were cancelled
+	awsNetwork = []string{"RequestError", "RequestCanceled", "SerializationError"}
+)
+
+// awsExplicitDeny is in the message of a request denied by a policy, e.g.
+// "User: arn:aws:iam::123456789012:user/x is not authorized to perform: s3:CreateBucket with an explicit deny in a service control policy".
+const awsExplicitDeny = "explicit deny"
+
+func classifyAWS(err error) Kind {
+	for _, e := range chain(err) {
+		ae, ok := e.(awserr.Error)
+		if !ok {
+			continue
+		}
+
+		var status int
+		if rf, ok := e.(awserr.RequestFailure); ok {
+			status = rf.StatusCode()
+		}
+		switch code := ae.Code(); {
+		case containsFold(awsThrottling, code) || status == http.StatusTooManyRequests:
+			return KindThrottling
+		case containsFold(awsAuth, code) || status == http.StatusUnauthorized || status == http.StatusForbidden:
+			return KindAuth
+		case containsFold(awsNotFound, code) || status == http.StatusNotFound:
+			return KindNotFound
+		case containsFold(awsNetwork, code):
+			return KindNetwork
+		}
+	}
+	return KindUnknown
+}
+
+// awsDenials returns the denial of a request that a policy explicitly denied. AWS does not name the policy, only its kind.
+func awsDenials(err error) []PolicyDenial {
+	for _, e := range chain(err) {
+		ae, ok := e.(awserr.Error)
+		if !ok || !containsFold(awsAuth, ae.Code()) {
+			continue
+		}
+		msg := ae.Message()
+		i := strings.Index(strings.ToLower(msg), awsExplicitDeny)
+		if i < 0 {
+			continue
+		}
+
+		// "... with an explicit deny in a service control policy" or "... in an identity-based policy"
+		kind := strings.TrimSpace(msg[i+len(awsExplicitDeny):])
+		for _, prefix := range []string{"in an ", "in a "} {
+			kind = strings.TrimPrefix(kind, prefix)
+		}
+		return []PolicyDenial{{Code: ae.Code(), PolicySet: strings.TrimSuffix(kind, "."), Reason: msg}}
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package clouderr
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/adal"
+	"github.com/Azure/go-autorest/autorest/azure"
+)
+
+// azurePolicyDenial is the error code of a request denied by an Azure Policy.
+const azurePolicyDenial = "RequestDisallowedByPolicy"
+
+// Azure error codes, see https://docs.microsoft.com/en-us/azure/azure-resource-manager/templates/common-deployment-errors
+var (
+	azureThrottling = []string{"TooManyRequests", "SubscriptionRequestsThrottled", "ResourceRequestsThrottled", "ServerBusy"}
+	azureAuth       = []string{"AuthorizationFailed", "AuthenticationFailed", "LinkedAuthorizationFailed", "InvalidAuthenticationToken",
+		"InvalidAuthenticationTokenTenant", "ExpiredAuthenticationToken"}
+	azureNotFound = []string{"NotFound", "ResourceNotFound", "ResourceGroupNotFound", "SubscriptionNotFound", "ParentResourceNotFound",
+		"PolicyAssignmentNotFound", "PolicyDefinitionNotFound", "StorageAccountNotFound"}
+)
+
+func classifyAzure(err error) Kind {
+	se, status := azureServiceError(err)
+	var code string
+	if se != nil {
+		code = se.Code
+	}
+
+	switch {
+	case containsFold(azureThrottling, code) || status == http.StatusTooManyRequests:
+		return KindThrottling
+	case containsFold(azureAuth, code) || status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return KindAuth
+	case containsFold(azureNotFound, code) || status == http.StatusNotFound:
+		return KindNotFound
+	}
+
+	// A failure to get a token from Azure AD
+	for _, e := range chain(err) {
+		if _, ok := e.(adal.TokenRefreshError); ok {
+			return KindAuth
+		}
+	}
+	return KindUnknown
+}
+
+// azureServiceError returns the ARM error and the HTTP status code held by err, if any.
+func azureServiceError(err error) (*azure.ServiceError, int) {
+	var status int
+	for _, e := range chain(err) {
+		switch e := e.(type) {
+		case autorest.DetailedError:
+			status = statusCode(status, e.StatusCode)
+		case *autorest.DetailedError:
+			status = statusCode(status, e.StatusCode)
+		case *azure.RequestError:
+			status = statusCode(status, e.StatusCode)
+			if e.ServiceError != nil {
+				return e.ServiceError, status
+			}
+		case *azure.ServiceError:
+			return e, status
+		}
+	}
+	return nil, status
+}
+
+func statusCode(current int, code interface{}) int {
+	if c, ok := code.(int); ok && current == 0 {
+		return c
+	}
+	return current
+}
+
+// azureDenials returns a denial for each Policy Assignment that disallowed the request. The policies are read from the
+// "PolicyViolation" additional info of the error or, when it is missing, from the policy identifiers in its message.
+func azureDenials(err error) []PolicyDenial {
+	se, _ := azureServiceError(err)
+	if se == nil {
+		return nil
+	}
+
+	code, message, info := se.Code, se.Message, se.AdditionalInfo
+	if !strings.EqualFold(code, azurePolicyDenial) {
+		// A deployment reports the denial as one of the details of its own error
+		found := false
+		for _, d := range se.Details {
+			if c, _ := d["code"].(string); strings.EqualFold(c, azurePolicyDenial) {
+				code, found = c, true
+				message, _ = d["message"].(string)
+				info = additionalInfo(d["additionalInfo"])
+				break
+			}
+		}
+		if !found {
+			return nil
+		}
+	}
+
+	var denials []PolicyDenial
+	for _, i := range info {
+		if t, _ := i["type"].(string); !strings.EqualFold(t, "PolicyViolation") {
+			continue
+		}
+		v, _ := i["info"].(map[string]interface{})
+		denials = append(denials, PolicyDenial{
+			Code:           code,
+			AssignmentID:   str(v["policyAssignmentId"]),
+			AssignmentName: str(v["policyAssignmentName"]),
+			DefinitionID:   str(v["policyDefinitionId"]),
+			DefinitionName: str(v["policyDefinitionName"]),
+			PolicySet:      str(v["policySetDefinitionId"]),
+			Reason:         message,
+		})
+	}
+	if len(denials) == 0 {
+		denials = policyIdentifiers(code, message)
+	}
+	if len(denials) == 0 {
+		denials = []PolicyDenial{{Code: code, Reason: message}}
+	}
+	return denials
+}
+
+// policyIdentifiers reads the policies from a message such as
+// "Resource 'x' was disallowed by policy. Policy identifiers: '[{"policyAssignment":{"name":"...","id":"..."},"policyDefinition":{...}}]'."
+func policyIdentifiers(code, message string) []PolicyDenial {
+	const marker = "Policy identifiers: '"
+	i := strings.Index(message, marker)
+	if i < 0 {
+		return nil
+	}
+	ids := message[i+len(marker):]
+	if j := strings.Index(ids, "]'"); j >= 0 {
+		ids = ids[:j+1]
+	}
+
+	type identifier struct {
+		Name string `json:"name"`
+		ID   string `json:"id"`
+	}
+	var policies []struct {
+		PolicyAssignment    identifier `json:"policyAssignment"`
+		PolicyDefinition    identifier `json:"policyDefinition"`
+		PolicySetDefinition identifier `json:"policySetDefinition"`
+	}
+	if err := json.Unmarshal([]byte(ids), &policies); err != nil {
+		return nil
+	}
+
+	var denials []PolicyDenial
+	for _, p := range policies {
+		// The names in the message are display names, the IDs end with the names
+		denials = append(denials, PolicyDenial{
+			Code:           code,
+			AssignmentID:   p.PolicyAssignment.ID,
+			AssignmentName: lastSegment(p.PolicyAssignment.ID),
+			DefinitionID:   p.PolicyDefinition.ID,
+			DefinitionName: lastSegment(p.PolicyDefinition.ID),
+			PolicySet:      p.PolicySetDefinition.ID,
+			Reason:         message,
+		})
+	}
+	return denials
+}
+
+func additionalInfo(v interface{}) []map[string]interface{} {
+	list, _ := v.([]interface{})
+	var info []map[string]interface{}
+	for _, e := range list {
+		if m, ok := e.(map[string]interface{}); ok {
+			info = append(info, m)
+		}
+	}
+	return info
+}
+
+func str(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v", v)
+}
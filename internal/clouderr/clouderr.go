@@ -0,0 +1,182 @@
+// Package clouderr classifies the errors returned by the Azure and AWS SDKs, so that steps can tell a request denied by a
+// preventative control from a throttled, unauthorised or failed request without type-asserting on SDK internals.
+package clouderr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+)
+
+// Kind is the class of an error returned by a Cloud Service Provider.
+type Kind string
+
+// Kinds of errors.
+const (
+	// KindPolicyDenial is a request denied by a preventative control, e.g. an Azure Policy with the deny effect or an AWS Service Control Policy.
+	KindPolicyDenial Kind = "policy denial"
+	// KindThrottling is a request rejected because too many requests were made.
+	KindThrottling Kind = "throttling"
+	// KindAuth is a request made with invalid or expired credentials, or by an identity without the permission to make it.
+	KindAuth Kind = "auth"
+	// KindNotFound is a request for a resource that does not exist.
+	KindNotFound Kind = "not found"
+	// KindNetwork is a request that did not reach the provider or timed out.
+	KindNetwork Kind = "network"
+	// KindUnknown is any other error.
+	KindUnknown Kind = "unknown"
+)
+
+// PolicyDenial is a request denied by a preventative control.
+type PolicyDenial struct {
+	// Code is the error code returned by the provider, e.g. "RequestDisallowedByPolicy" or "AccessDenied".
+	Code string
+	// AssignmentID is the ID of the Azure Policy Assignment that denied the request.
+	AssignmentID string
+	// AssignmentName is the name of the Azure Policy Assignment that denied the request.
+	AssignmentName string
+	// DefinitionID is the ID of the Azure Policy Definition that denied the request.
+	DefinitionID string
+	// DefinitionName is the name of the Azure Policy Definition that denied the request.
+	DefinitionName string
+	// PolicySet is the ID of the Azure Policy Set Definition (initiative) holding the definition, if it was assigned through one.
+	// For AWS it is the kind of policy holding the explicit deny, e.g. "service control policy".
+	PolicySet string
+	// Reason is the message returned by the provider.
+	Reason string
+}
+
+func (d PolicyDenial) String() string {
+	if d.AssignmentName == "" {
+		return fmt.Sprintf("%s: %s", d.Code, d.Reason)
+	}
+	return fmt.Sprintf("%s by policy assignment %s (definition %s)", d.Code, d.AssignmentName, d.DefinitionName)
+}
+
+// Matches reports whether the denial was made by the named Azure Policy Assignment or Definition, compared case-insensitively.
+func (d PolicyDenial) Matches(name string) bool {
+	for _, n := range []string{d.AssignmentName, d.DefinitionName, lastSegment(d.AssignmentID), lastSegment(d.DefinitionID)} {
+		if n != "" && strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Match returns an error unless the regular expression pattern matches the reason of the denial or one of the metadata of the
+// policy that made it, e.g. its display name or non-compliance messages. An empty pattern matches any denial.
+func (d PolicyDenial) Match(pattern string, metadata ...string) error {
+	if pattern == "" {
+		return nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("invalid error description '%s': %v", pattern, err)
+	}
+
+	for _, s := range append([]string{d.Reason}, metadata...) {
+		if re.MatchString(s) {
+			return nil
+		}
+	}
+	return fmt.Errorf("expected a denial matching '%s', got '%s' from %s with policy metadata %q", pattern, d.Reason, d, metadata)
+}
+
+// Classify returns the kind of err, KindUnknown if it cannot be classified, or "" if err is nil.
+func Classify(err error) Kind {
+	if err == nil {
+		return ""
+	}
+	if len(PolicyDenials(err)) > 0 {
+		return KindPolicyDenial
+	}
+	if k := classifyAzure(err); k != KindUnknown {
+		return k
+	}
+	if k := classifyAWS(err); k != KindUnknown {
+		return k
+	}
+	if isNetwork(err) {
+		return KindNetwork
+	}
+	return KindUnknown
+}
+
+// PolicyDenials returns the denials held by err, one for each policy that denied the request, or nil if err is not a policy denial.
+func PolicyDenials(err error) []PolicyDenial {
+	if err == nil {
+		return nil
+	}
+	if d := azureDenials(err); len(d) > 0 {
+		return d
+	}
+	return awsDenials(err)
+}
+
+// DeniedBy returns the denial held by err that was made by the named Azure Policy Assignment or Definition.
+// Denials that do not name their policy, e.g. those of AWS, cannot be attributed to it and are not returned.
+func DeniedBy(err error, name string) (PolicyDenial, bool) {
+	for _, d := range PolicyDenials(err) {
+		if d.Matches(name) {
+			return d, true
+		}
+	}
+	return PolicyDenial{}, false
+}
+
+// chain returns err and the errors that it wraps, outermost first. The SDK errors are unwrapped explicitly as they predate errors.Unwrap.
+func chain(err error) []error {
+	var errs []error
+	for err != nil && len(errs) < maxChain {
+		errs = append(errs, err)
+		switch e := err.(type) {
+		case autorest.DetailedError:
+			err = e.Original
+		case *autorest.DetailedError:
+			err = e.Original
+		case *azure.RequestError:
+			err = e.Original
+		case awserr.Error:
+			err = e.OrigErr()
+		default:
+			err = errors.Unwrap(err)
+		}
+	}
+	return errs
+}
+
+// maxChain bounds chain in case an error wraps itself.
+const maxChain = 32
+
+// isNetwork reports whether err, or an error it wraps, is a network error or a timeout.
+func isNetwork(err error) bool {
+	for _, e := range chain(err) {
+		if e == context.DeadlineExceeded {
+			return true
+		}
+		if _, ok := e.(net.Error); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func lastSegment(id string) string {
+	return id[strings.LastIndex(id, "/")+1:]
+}
+
+func containsFold(list []string, s string) bool {
+	for _, e := range list {
+		if strings.EqualFold(e, s) {
+			return true
+		}
+	}
+	return false
+}
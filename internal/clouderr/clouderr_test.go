@@ -0,0 +1,183 @@
+package clouderr
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+)
+
+// azureError returns the error of an ARM response with the status and the body of the fixture in testdata, as
+// azure.WithErrorUnlessStatusCode returns it, and as the clients of the SDK return it, wrapped in an autorest.DetailedError.
+func azureError(t *testing.T, fixture string, status int) (*azure.RequestError, autorest.DetailedError) {
+	t.Helper()
+	body, err := ioutil.ReadFile(filepath.Join("testdata", fixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest(http.MethodPut, "https://management.azure.com/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/testabcdefresourceGP/providers/Microsoft.Storage/storageAccounts/testabcdefstorage", nil)
+	resp := &http.Response{
+		StatusCode: status,
+		Status:     http.StatusText(status),
+		Header:     http.Header{"Content-Type": []string{"application/json; charset=utf-8"}},
+		Body:       ioutil.NopCloser(bytes.NewReader(body)),
+		Request:    req,
+	}
+
+	err = autorest.Respond(resp, azure.WithErrorUnlessStatusCode(http.StatusOK), autorest.ByClosing())
+	re, ok := err.(*azure.RequestError)
+	if !ok {
+		t.Fatalf("error of %v is a %T, not an *azure.RequestError: %v", fixture, err, err)
+	}
+	return re, autorest.NewErrorWithError(re, "storage.AccountsClient", "Create", resp, "Failure responding to request")
+}
+
+const accessDenied = "User: arn:aws:iam::123456789012:user/bdd is not authorized to perform: s3:CreateBucket on resource: arn:aws:s3:::testabcdeunencbucket"
+
+func TestClassify(t *testing.T) {
+	deniedRequest, deniedDetailed := azureError(t, "request_disallowed_by_policy.json", http.StatusForbidden)
+	_, deployment := azureError(t, "deployment_disallowed_by_policy.json", http.StatusBadRequest)
+	_, identifiers := azureError(t, "policy_identifiers.json", http.StatusForbidden)
+	_, unauthorized := azureError(t, "authorization_failed.json", http.StatusForbidden)
+
+	tests := []struct {
+		name string
+		err  error
+		want Kind
+	}{
+		{"nil", nil, ""},
+		{"Azure policy denial", deniedRequest, KindPolicyDenial},
+		{"Azure policy denial of a client", deniedDetailed, KindPolicyDenial},
+		{"Azure policy denial of a client, wrapped", fmt.Errorf("cannot create storage account: %w", deniedDetailed), KindPolicyDenial},
+		{"Azure policy denial of a deployment", deployment, KindPolicyDenial},
+		{"Azure policy denial with policy identifiers", identifiers, KindPolicyDenial},
+		{"Azure authorization failure", unauthorized, KindAuth},
+		{"Azure throttling", autorest.DetailedError{Original: errors.New("too many requests"), StatusCode: http.StatusTooManyRequests}, KindThrottling},
+		{"Azure not found", autorest.DetailedError{Original: errors.New("not found"), StatusCode: http.StatusNotFound}, KindNotFound},
+		{"AWS explicit deny", awserr.NewRequestFailure(awserr.New("AccessDenied", accessDenied+" with an explicit deny in a service control policy", nil), http.StatusForbidden, "req"), KindPolicyDenial},
+		{"AWS access denied", awserr.NewRequestFailure(awserr.New("AccessDenied", accessDenied+" because no identity-based policy allows the s3:CreateBucket action", nil), http.StatusForbidden, "req"), KindAuth},
+		{"AWS throttling", awserr.NewRequestFailure(awserr.New("SlowDown", "Please reduce your request rate.", nil), http.StatusServiceUnavailable, "req"), KindThrottling},
+		{"AWS not found", awserr.NewRequestFailure(awserr.New("NoSuchBucket", "The specified bucket does not exist", nil), http.StatusNotFound, "req"), KindNotFound},
+		{"AWS request error", awserr.New("RequestError", "send request failed", errors.New("connection reset")), KindNetwork},
+		{"timeout", fmt.Errorf("cannot list buckets: %w", context.DeadlineExceeded), KindNetwork},
+		{"other", errors.New("boom"), KindUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Classify(tt.err); got != tt.want {
+				t.Errorf("Classify() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicyDenials(t *testing.T) {
+	deniedRequest, deniedDetailed := azureError(t, "request_disallowed_by_policy.json", http.StatusForbidden)
+	_, deployment := azureError(t, "deployment_disallowed_by_policy.json", http.StatusBadRequest)
+	_, identifiers := azureError(t, "policy_identifiers.json", http.StatusForbidden)
+	_, unauthorized := azureError(t, "authorization_failed.json", http.StatusForbidden)
+
+	httpStorage := PolicyDenial{
+		Code:           "RequestDisallowedByPolicy",
+		AssignmentID:   "/subscriptions/00000000-0000-0000-0000-000000000000/providers/Microsoft.Authorization/policyAssignments/deny_http_storage",
+		AssignmentName: "deny_http_storage",
+		DefinitionID:   "/providers/Microsoft.Authorization/policyDefinitions/404c3081-a854-4457-ae30-26a93ef643f9",
+		DefinitionName: "404c3081-a854-4457-ae30-26a93ef643f9",
+		Reason:         "Resource 'testabcdefstorage' was disallowed by policy. Reasons: 'Storage Buckets must not be accessible via plain HTTP'. See error details for policy resource IDs.",
+	}
+	identified := httpStorage
+	identified.Reason = identifiers.Original.(*azure.RequestError).ServiceError.Message
+
+	tests := []struct {
+		name string
+		err  error
+		want []PolicyDenial
+	}{
+		{"additional info", deniedRequest, []PolicyDenial{httpStorage}},
+		{"additional info of a client", deniedDetailed, []PolicyDenial{httpStorage}},
+		{"details of a deployment", deployment, []PolicyDenial{{
+			Code:           "RequestDisallowedByPolicy",
+			AssignmentID:   "/providers/Microsoft.Management/managementGroups/boxbank-root/providers/Microsoft.Authorization/policyAssignments/storage_encryption",
+			AssignmentName: "storage_encryption",
+			DefinitionID:   "/subscriptions/00000000-0000-0000-0000-000000000000/providers/Microsoft.Authorization/policyDefinitions/deny_non_cmk_storage_account",
+			DefinitionName: "deny_non_cmk_storage_account",
+			PolicySet:      "/subscriptions/00000000-0000-0000-0000-000000000000/providers/Microsoft.Authorization/policySetDefinitions/storage_encryption",
+			Reason:         "Resource 'testabcdefstorage' was disallowed by policy. Reasons: 'Storage accounts must be encrypted with customer-managed keys'.",
+		}}},
+		{"policy identifiers of the message", identifiers, []PolicyDenial{identified}},
+		{"AWS explicit deny", awserr.NewRequestFailure(awserr.New("AccessDenied", accessDenied+" with an explicit deny in a service control policy", nil), http.StatusForbidden, "req"), []PolicyDenial{{
+			Code:      "AccessDenied",
+			PolicySet: "service control policy",
+			Reason:    accessDenied + " with an explicit deny in a service control policy",
+		}}},
+		{"Azure authorization failure", unauthorized, nil},
+		{"AWS access denied", awserr.New("AccessDenied", accessDenied, nil), nil},
+		{"nil", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := PolicyDenials(tt.err)
+			if len(got) != len(tt.want) {
+				t.Fatalf("PolicyDenials() = %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("PolicyDenials()[%d] = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDeniedBy(t *testing.T) {
+	_, denied := azureError(t, "request_disallowed_by_policy.json", http.StatusForbidden)
+	for _, name := range []string{"deny_http_storage", "DENY_HTTP_STORAGE", "404c3081-a854-4457-ae30-26a93ef643f9"} {
+		if _, ok := DeniedBy(denied, name); !ok {
+			t.Errorf("DeniedBy(%q) did not find the denial", name)
+		}
+	}
+	if d, ok := DeniedBy(denied, "deny_non_cmk_storage_account"); ok {
+		t.Errorf("DeniedBy() = %v, want no denial of another policy", d)
+	}
+
+	aws := awserr.New("AccessDenied", accessDenied+" with an explicit deny in a service control policy", nil)
+	if d, ok := DeniedBy(aws, "service control policy"); ok {
+		t.Errorf("DeniedBy() = %v, want no denial as AWS does not name the policy", d)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	_, denied := azureError(t, "request_disallowed_by_policy.json", http.StatusForbidden)
+	d, ok := DeniedBy(denied, "deny_http_storage")
+	if !ok {
+		t.Fatal("DeniedBy() did not find the denial")
+	}
+	metadata := []string{"Secure transfer to storage accounts should be enabled"}
+
+	for _, pattern := range []string{"", "plain HTTP", "(?i)secure transfer", "^Resource 'testabcdefstorage' was disallowed"} {
+		if err := d.Match(pattern, metadata...); err != nil {
+			t.Errorf("Match(%q) = %v, want nil", pattern, err)
+		}
+	}
+
+	err := d.Match("must be encrypted", metadata...)
+	want := `expected a denial matching 'must be encrypted', got '` + d.Reason + `' from RequestDisallowedByPolicy by policy assignment deny_http_storage ` +
+		`(definition 404c3081-a854-4457-ae30-26a93ef643f9) with policy metadata ["Secure transfer to storage accounts should be enabled"]`
+	if err == nil || err.Error() != want {
+		t.Errorf("Match() = %v, want %v", err, want)
+	}
+
+	err = d.Match("plain (HTTP")
+	want = "invalid error description 'plain (HTTP': error parsing regexp: missing closing ): `plain (HTTP`"
+	if err == nil || err.Error() != want {
+		t.Errorf("Match() = %v, want %v", err, want)
+	}
+}
@@ -0,0 +1,81 @@
+// Package controls loads the catalog of common control objectives that the features implement, identified by the IDs of
+// their @CCO: tags, and reports which of them are tested.
+package controls
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Control is a common control objective, e.g. CHC2-SVD030.
+type Control struct {
+	ID    string `yaml:"id" json:"id"`
+	Title string `yaml:"title" json:"title"`
+	// Objective is what the control achieves.
+	Objective string `yaml:"objective" json:"objective"`
+	// Origin is the regulation or industry benchmark that the control traces back to.
+	Origin string `yaml:"origin" json:"origin"`
+	// OriginStatus is whether the control owner signed off the origin, OriginApproved, or not yet, OriginProposed.
+	OriginStatus string `yaml:"origin_status" json:"origin_status"`
+	// OriginSource is the document that the origin is taken from, e.g. the control owner's mapping, required once approved.
+	OriginSource string `yaml:"origin_source,omitempty" json:"origin_source,omitempty"`
+}
+
+// Statuses of the origin of a control.
+const (
+	OriginProposed = "proposed"
+	OriginApproved = "approved"
+)
+
+// Catalog is a catalog of controls, in the format of catalog/controls.yaml.
+type Catalog struct {
+	Controls []Control `yaml:"controls"`
+}
+
+// Load reads a catalog. Every control must have an ID and a title, and IDs must be unique, compared case-insensitively as tags are.
+// The origin of a control is either proposed or approved with the source it is taken from.
+func Load(path string) (*Catalog, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var c Catalog
+	if err := yaml.UnmarshalStrict(b, &c); err != nil {
+		return nil, fmt.Errorf("cannot parse control catalog %v: %v", path, err)
+	}
+
+	ids := map[string]bool{}
+	for i, ctl := range c.Controls {
+		switch {
+		case ctl.ID == "":
+			return nil, fmt.Errorf("control %d of catalog %v has no id", i+1, path)
+		case ctl.Title == "":
+			return nil, fmt.Errorf("control %v of catalog %v has no title", ctl.ID, path)
+		case ids[strings.ToUpper(ctl.ID)]:
+			return nil, fmt.Errorf("control %v is defined more than once in catalog %v", ctl.ID, path)
+		case ctl.OriginStatus != OriginProposed && ctl.OriginStatus != OriginApproved:
+			return nil, fmt.Errorf("control %v of catalog %v has origin_status '%v', expected %v or %v", ctl.ID, path, ctl.OriginStatus, OriginProposed, OriginApproved)
+		case ctl.OriginStatus == OriginApproved && ctl.OriginSource == "":
+			return nil, fmt.Errorf("control %v of catalog %v has an approved origin without an origin_source", ctl.ID, path)
+		}
+		ids[strings.ToUpper(ctl.ID)] = true
+	}
+
+	sort.Slice(c.Controls, func(i, j int) bool { return c.Controls[i].ID < c.Controls[j].ID })
+	return &c, nil
+}
+
+// Control returns the control with the ID, compared case-insensitively.
+func (c *Catalog) Control(id string) (Control, bool) {
+	for _, ctl := range c.Controls {
+		if strings.EqualFold(ctl.ID, id) {
+			return ctl, true
+		}
+	}
+	return Control{}, false
+}
@@ -0,0 +1,169 @@
+package controls
+
+import (
+	"sort"
+	"strings"
+
+	"citihub.com/compliance-as-code/internal/csp"
+	"citihub.com/compliance-as-code/internal/featurefile"
+	"citihub.com/compliance-as-code/internal/featurelint"
+)
+
+// capabilities are the kinds of control, in the order they are reported.
+var capabilities = []csp.Capability{csp.Preventative, csp.Detective, csp.Corrective}
+
+// Implementation is a feature that implements a control.
+type Implementation struct {
+	Feature string `json:"feature"`
+	Path    string `json:"path"`
+	// Capabilities holds, for each provider of the feature, the kinds of control that its scenarios test on the provider.
+	Capabilities map[string][]csp.Capability `json:"capabilities"`
+}
+
+// Coverage is how a control is tested.
+type Coverage struct {
+	Control
+	Implementations []Implementation `json:"features"`
+	// Capabilities holds, for each provider, the kinds of control tested by any of the features.
+	Capabilities map[string][]csp.Capability `json:"capabilities"`
+}
+
+// Report is the coverage of a catalog by the features.
+type Report struct {
+	Covered []Coverage `json:"covered"`
+	// Uncovered are the controls of the catalog that no feature implements.
+	Uncovered []Control `json:"uncovered"`
+	// Unknown holds the IDs of @CCO: tags that are not in the catalog, with the paths of the features tagged with them.
+	Unknown map[string][]string `json:"unknown,omitempty"`
+}
+
+// Feature is a feature to report on, with the step definitions of its suite, which tell which capabilities each provider does not support.
+type Feature struct {
+	*featurefile.Feature
+	Definitions *featurelint.Definitions
+}
+
+// Files reads the features under root, and the step definitions of their suites, for Cover.
+func Files(root string) ([]Feature, error) {
+	paths, err := featurefile.Find(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var features []Feature
+	suites := map[string]*featurelint.Definitions{}
+	for _, path := range paths {
+		f, err := featurefile.Parse(path)
+		if err != nil {
+			return nil, err
+		}
+		dir := featurefile.SuiteDir(path)
+		if _, ok := suites[dir]; !ok {
+			d, err := featurelint.LoadDefinitions(dir)
+			if err != nil {
+				return nil, err
+			}
+			suites[dir] = &d
+		}
+		features = append(features, Feature{Feature: f, Definitions: suites[dir]})
+	}
+	return features, nil
+}
+
+// Cover reports which controls of the catalog are implemented by the features, on which providers and with which kinds of control.
+// A kind of control is tested on a provider when a scenario of the feature is tagged with it, e.g. @preventative, and the
+// suite does not declare it unsupported on the provider.
+func Cover(c *Catalog, features []Feature) Report {
+	r := Report{Unknown: map[string][]string{}}
+
+	implementations := map[string][]Implementation{}
+	for _, f := range features {
+		if f.HasTag(featurelint.InternalTag) {
+			continue
+		}
+		impl := Implementation{Feature: f.Name, Path: f.Path, Capabilities: f.capabilities()}
+		for _, id := range f.TagValues(featurelint.ControlTagPrefix) {
+			ctl, ok := c.Control(id)
+			if !ok {
+				r.Unknown[id] = append(r.Unknown[id], f.Path)
+				continue
+			}
+			implementations[ctl.ID] = append(implementations[ctl.ID], impl)
+		}
+	}
+
+	for _, ctl := range c.Controls {
+		impls, ok := implementations[ctl.ID]
+		if !ok {
+			r.Uncovered = append(r.Uncovered, ctl)
+			continue
+		}
+
+		cov := Coverage{Control: ctl, Implementations: impls, Capabilities: map[string][]csp.Capability{}}
+		tested := map[string]map[csp.Capability]bool{}
+		for _, impl := range impls {
+			for p, cs := range impl.Capabilities {
+				if tested[p] == nil {
+					tested[p] = map[csp.Capability]bool{}
+				}
+				for _, c := range cs {
+					tested[p][c] = true
+				}
+			}
+		}
+		for p, cs := range tested {
+			cov.Capabilities[p] = ordered(cs)
+		}
+		r.Covered = append(r.Covered, cov)
+	}
+
+	if len(r.Unknown) == 0 {
+		r.Unknown = nil
+	}
+	return r
+}
+
+// Providers returns the providers of a coverage, sorted alphabetically.
+func (c Coverage) Providers() []string {
+	var p []string
+	for name := range c.Capabilities {
+		p = append(p, name)
+	}
+	sort.Strings(p)
+	return p
+}
+
+// capabilities returns, for each provider of the feature, the kinds of control tested on the provider.
+func (f Feature) capabilities() map[string][]csp.Capability {
+	tagged := map[csp.Capability]bool{}
+	for _, s := range f.Scenarios {
+		for _, c := range capabilities {
+			if s.HasTag(f.Feature, "@"+string(c)) {
+				tagged[c] = true
+			}
+		}
+	}
+
+	byProvider := map[string][]csp.Capability{}
+	for _, p := range f.TagValues(featurelint.CSPTagPrefix) {
+		p = strings.ToLower(p)
+		supported := map[csp.Capability]bool{}
+		for c := range tagged {
+			if f.Definitions == nil || f.Definitions.Supports(p, c) {
+				supported[c] = true
+			}
+		}
+		byProvider[p] = ordered(supported)
+	}
+	return byProvider
+}
+
+func ordered(set map[csp.Capability]bool) []csp.Capability {
+	cs := []csp.Capability{}
+	for _, c := range capabilities {
+		if set[c] {
+			cs = append(cs, c)
+		}
+	}
+	return cs
+}
@@ -0,0 +1,100 @@
+package controls
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Framework is an external control framework that the controls of the catalog are mapped to, e.g. NIST SP 800-53.
+type Framework struct {
+	// ID is the short name of the framework in the crosswalk, e.g. "nist-800-53".
+	ID   string `yaml:"id" json:"id"`
+	Name string `yaml:"name" json:"name"`
+}
+
+// Reference is a control of an external framework, e.g. SC-8 of NIST SP 800-53.
+type Reference struct {
+	Framework string `json:"framework"`
+	Control   string `json:"control"`
+}
+
+func (r Reference) String() string {
+	return fmt.Sprintf("%s %s", r.Framework, r.Control)
+}
+
+// Crosswalk maps the controls of the catalog to the controls of external frameworks, in the format of catalog/crosswalk.yaml.
+type Crosswalk struct {
+	Frameworks []Framework `yaml:"frameworks"`
+	// Controls holds, for each control of the catalog, the controls of each framework that it implements.
+	Controls map[string]map[string][]string `yaml:"controls"`
+}
+
+// LoadCrosswalk reads a crosswalk. Every framework it maps to must be declared, and, unless catalog is nil, every control it maps from must be in the catalog.
+func LoadCrosswalk(path string, catalog *Catalog) (*Crosswalk, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cw Crosswalk
+	if err := yaml.UnmarshalStrict(b, &cw); err != nil {
+		return nil, fmt.Errorf("cannot parse crosswalk %v: %v", path, err)
+	}
+
+	frameworks := map[string]bool{}
+	for _, f := range cw.Frameworks {
+		if f.ID == "" || frameworks[f.ID] {
+			return nil, fmt.Errorf("framework '%v' of crosswalk %v has no id or is declared more than once", f.Name, path)
+		}
+		frameworks[f.ID] = true
+	}
+	for id, refs := range cw.Controls {
+		if catalog != nil {
+			if _, ok := catalog.Control(id); !ok {
+				return nil, fmt.Errorf("control %v of crosswalk %v is not in the control catalog", id, path)
+			}
+		}
+		for f := range refs {
+			if !frameworks[f] {
+				return nil, fmt.Errorf("control %v of crosswalk %v is mapped to undeclared framework %v", id, path, f)
+			}
+		}
+	}
+	return &cw, nil
+}
+
+// Framework returns the framework with the ID.
+func (cw *Crosswalk) Framework(id string) (Framework, bool) {
+	for _, f := range cw.Frameworks {
+		if f.ID == id {
+			return f, true
+		}
+	}
+	return Framework{}, false
+}
+
+// References returns the framework controls that a control of the catalog is mapped to, ordered by framework and control.
+func (cw *Crosswalk) References(id string) []Reference {
+	var refs []Reference
+	for cco, m := range cw.Controls {
+		if !strings.EqualFold(cco, id) {
+			continue
+		}
+		for f, controls := range m {
+			for _, c := range controls {
+				refs = append(refs, Reference{Framework: f, Control: c})
+			}
+		}
+	}
+	sort.Slice(refs, func(i, j int) bool {
+		if refs[i].Framework != refs[j].Framework {
+			return refs[i].Framework < refs[j].Framework
+		}
+		return refs[i].Control < refs[j].Control
+	})
+	return refs
+}
@@ -0,0 +1,73 @@
+package csp
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Capability is a kind of security control, named after the scenario tag that exercises it, e.g. @preventative.
+type Capability string
+
+// Capabilities.
+const (
+	// Preventative controls stop a non-compliant resource from being created, e.g. an Azure Policy with the deny effect.
+	Preventative Capability = "preventative"
+	// Detective controls report a non-compliant resource once it exists, e.g. an AWS Config Rule.
+	Detective Capability = "detective"
+	// Corrective controls remediate a non-compliant resource once it is detected.
+	Corrective Capability = "corrective"
+)
+
+var unsupported = map[string]map[string]map[Capability]string{}
+
+// Unsupported declares that the named provider offers no control of the capability for the feature, giving the reason.
+// Scenarios tagged with the capability are then reported as not applicable on the provider rather than run.
+// Like Register, it is intended to be called from the init function of the file holding the CSP-specific implementation.
+func Unsupported(feature, provider string, c Capability, reason string) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	provider = strings.ToLower(provider)
+	if unsupported[feature] == nil {
+		unsupported[feature] = map[string]map[Capability]string{}
+	}
+	if unsupported[feature][provider] == nil {
+		unsupported[feature][provider] = map[Capability]string{}
+	}
+	unsupported[feature][provider][c] = reason
+}
+
+// Supports reports whether the named provider offers a control of the capability for the feature and, if it does not, why.
+func Supports(feature, provider string, c Capability) (bool, string) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	reason, ok := unsupported[feature][strings.ToLower(provider)][c]
+	return !ok, reason
+}
+
+// NotApplicableError is returned by a step that cannot be run on the selected provider, because it offers no such control.
+type NotApplicableError struct {
+	Provider string
+	Reason   string
+}
+
+func (e *NotApplicableError) Error() string {
+	return fmt.Sprintf("not applicable on %s: %s", DisplayName(e.Provider), e.Reason)
+}
+
+// NotApplicable returns the error of a step that cannot be run on the provider selected by the CSP environment variable.
+func NotApplicable(reason string) error {
+	return &NotApplicableError{Provider: Name(), Reason: reason}
+}
+
+// DisplayName returns the name of a provider as shown in reports, e.g. "AWS" for "aws".
+func DisplayName(provider string) string {
+	switch strings.ToLower(provider) {
+	case AWS:
+		return "AWS"
+	case Azure:
+		return "Azure"
+	}
+	return provider
+}
@@ -0,0 +1,86 @@
+package csp
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	// EnvVar is the environment variable that selects the Cloud Service Provider under test.
+	EnvVar = "CSP"
+
+	// Azure is the registry name of the Microsoft Azure provider.
+	Azure = "azure"
+	// AWS is the registry name of the Amazon Web Services provider.
+	AWS = "aws"
+)
+
+// Factory returns a new CSP-specific implementation of a feature.
+type Factory func() interface{}
+
+var (
+	mu       sync.RWMutex
+	registry = map[string]map[string]Factory{}
+)
+
+// Register makes the implementation of a feature available for the named provider.
+// It is intended to be called from the init function of the file holding the CSP-specific implementation.
+func Register(feature, provider string, f Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	provider = strings.ToLower(provider)
+	if f == nil {
+		log.Panicf("csp: Register factory for feature '%s' and provider '%s' is nil", feature, provider)
+	}
+	if registry[feature] == nil {
+		registry[feature] = map[string]Factory{}
+	}
+	if _, dup := registry[feature][provider]; dup {
+		log.Panicf("csp: Register called twice for feature '%s' and provider '%s'", feature, provider)
+	}
+	registry[feature][provider] = f
+}
+
+// Name returns the provider selected by the CSP environment variable, in lower case.
+func Name() string {
+	return strings.ToLower(os.Getenv(EnvVar))
+}
+
+// Lookup returns a new implementation of the feature for the provider selected by the CSP environment variable.
+func Lookup(feature string) (interface{}, error) {
+	return LookupProvider(feature, Name())
+}
+
+// LookupProvider returns a new implementation of the feature for the named provider.
+func LookupProvider(feature, provider string) (interface{}, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	f, ok := registry[feature][strings.ToLower(provider)]
+	if !ok {
+		return nil, fmt.Errorf("cloud provider '%s' not supported by feature '%s' - set environment variable '%s' to one of %v",
+			provider, feature, EnvVar, providers(feature))
+	}
+	return f(), nil
+}
+
+// Providers returns the names of the providers registered for the feature, sorted alphabetically.
+func Providers(feature string) []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return providers(feature)
+}
+
+func providers(feature string) []string {
+	var p []string
+	for name := range registry[feature] {
+		p = append(p, name)
+	}
+	sort.Strings(p)
+	return p
+}
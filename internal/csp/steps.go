@@ -0,0 +1,257 @@
+package csp
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"citihub.com/compliance-as-code/internal/evidence"
+	"citihub.com/compliance-as-code/internal/guardrail"
+	"citihub.com/compliance-as-code/internal/tagging"
+	"github.com/cucumber/godog"
+	messages "github.com/cucumber/messages-go/v10"
+)
+
+// Steps binds the steps of a feature to a suite. Scenarios that the selected provider cannot run, because it does not
+// support the capability they are tagged with (see Unsupported) or because a step returns a NotApplicableError, are
+// reported as pending with the reason rather than passed or failed, and listed once the suite has run.
+// The evidence of each scenario, i.e. when it ran and the resources it recorded, is kept with internal/evidence.
+// Scenarios tagged @intrusive_test are only run when internal/guardrail allows them on the environment under test. Those
+// it skips are reported as pending too, and the decision is recorded in their evidence, which tells them from the scenarios
+// that are not applicable as the pending steps of godog have no message (see results.SkippedGuardrail).
+// The resources created with the context given to setup are tagged with the feature and the scenario that creates them,
+// see internal/tagging. Each scenario has its own state and resources, see Scenario.
+type Steps struct {
+	suite    *godog.Suite
+	feature  string
+	provider string
+	ctx      context.Context
+
+	setup    func(ctx context.Context)
+	teardown func()
+	setUp    bool
+
+	begin, end func()
+	// begun is set when the current scenario was begun, and must be ended.
+	begun bool
+	// kept are the failed scenarios whose resources were kept, see KeepFailedEnvVar.
+	kept []string
+
+	scenario string
+	// tagged is the scenario the resources are tagged with, empty during setup as its resources outlive the scenario.
+	tagged string
+	// reason is why the current scenario is not applicable, empty if it is.
+	reason string
+	// refused is the error of the current scenario when the guardrail refuses it.
+	refused error
+	// skipped is why the guardrail skips the current scenario, empty if it does not.
+	skipped       string
+	notApplicable []string
+	// guarded are the scenarios that the guardrail skipped.
+	guarded []string
+	// guardrail is the decision of the guardrail, nil if no scenario is intrusive.
+	guardrail *guardrail.Decision
+}
+
+// NewSteps returns the step binder of a feature for the provider selected by the CSP environment variable.
+func NewSteps(s *godog.Suite, feature string) *Steps {
+	st := &Steps{suite: s, feature: feature, provider: Name()}
+	st.ctx = tagging.NewContextFunc(context.Background(), st.policy)
+	s.BeforeScenario(st.beforeScenario)
+	s.AfterScenario(st.afterScenario)
+	s.AfterSuite(st.afterSuite)
+	return st
+}
+
+// Setup sets the functions that set up and tear down the CSP-specific implementation. Rather than before the suite, setup
+// is run before the first scenario that is run, so that a suite whose scenarios are all not applicable or stopped by the
+// guardrail creates nothing, and teardown after the suite if setup was run. setup is given the context to create
+// resources with.
+func (st *Steps) Setup(setup func(ctx context.Context), teardown func()) {
+	st.setup, st.teardown = setup, teardown
+}
+
+// Scenario sets the functions that begin and end each scenario that is run, after setup: begin resets the state of the
+// scenario and end deletes the resources that it created, so that scenarios, e.g. the rows of a scenario outline, do not
+// share state or leave resources behind. end is not run for a failed scenario when KEEP_FAILED is set.
+func (st *Steps) Scenario(begin, end func()) {
+	st.begin, st.end = begin, end
+}
+
+// Context returns the context to create resources with, whose tagging policy is that of the current scenario.
+func (st *Steps) Context() context.Context {
+	return st.ctx
+}
+
+func (st *Steps) policy() tagging.Policy {
+	p := tagging.Run()
+	p.Feature, p.Scenario = st.feature, st.tagged
+	return p
+}
+
+// Step binds a step function, which must return an error, to the step expression as godog.Suite.Step does.
+func (st *Steps) Step(expr string, step interface{}) {
+	st.suite.Step(expr, st.wrap(step))
+}
+
+func (st *Steps) beforeScenario(p *messages.Pickle) {
+	st.scenario, st.tagged, st.reason, st.refused, st.skipped = p.Name, "", "", nil, ""
+	evidence.Begin(p.Uri, p.Name, st.provider)
+	intrusive := false
+	for _, t := range p.Tags {
+		c := Capability(strings.TrimPrefix(t.Name, "@"))
+		if ok, reason := Supports(st.feature, st.provider, c); !ok {
+			st.reason = reason
+			return
+		}
+		intrusive = intrusive || t.Name == guardrail.IntrusiveTag
+	}
+
+	if intrusive {
+		d := guardrail.Check(st.provider)
+		st.guardrail = &d
+		if !d.Allowed {
+			evidence.Guardrail(d)
+			if d.Mode == guardrail.ModeSkip {
+				st.skipped = d.Reason
+			} else {
+				st.refused = fmt.Errorf("guardrail: %s", d.Reason)
+			}
+			return
+		}
+	}
+
+	if !st.setUp && st.setup != nil {
+		st.setUp = true
+		st.setup(st.ctx)
+	}
+	st.tagged = p.Name
+	st.begun = true
+	if st.begin != nil {
+		st.begin()
+	}
+}
+
+func (st *Steps) afterScenario(p *messages.Pickle, err error) {
+	evidence.End()
+	if !st.begun {
+		return
+	}
+	st.begun = false
+	if err != nil && err != godog.ErrPending && KeepFailed() {
+		st.kept = append(st.kept, p.Name)
+		log.Printf("[WARN] Keeping the resources of failed scenario '%s' for debugging", p.Name)
+		return
+	}
+	if st.end != nil {
+		st.end()
+	}
+}
+
+// KeepFailedEnvVar is the environment variable that, when true, keeps the resources of the scenarios that fail for
+// debugging rather than deleting them after the scenario. The resources set up for the feature are then kept too, as
+// they may contain them, e.g. a resource group. They remain in the ledger for cmd/recover to delete.
+const KeepFailedEnvVar = "KEEP_FAILED"
+
+// KeepFailed tells whether the resources of the scenarios that fail are kept, see KeepFailedEnvVar.
+func KeepFailed() bool {
+	v := os.Getenv(KeepFailedEnvVar)
+	if v == "" {
+		return false
+	}
+	keep, err := strconv.ParseBool(v)
+	if err != nil {
+		log.Printf("[WARN] Ignoring %s=%s, not a boolean", KeepFailedEnvVar, v)
+	}
+	return keep
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+func (st *Steps) wrap(step interface{}) interface{} {
+	v := reflect.ValueOf(step)
+	t := v.Type()
+	if t.Kind() != reflect.Func || t.NumOut() != 1 || t.Out(0) != errorType {
+		log.Panicf("csp: step '%T' of feature '%s' must be a function returning an error", step, st.feature)
+	}
+
+	return reflect.MakeFunc(t, func(args []reflect.Value) []reflect.Value {
+		if st.refused != nil {
+			return []reflect.Value{reflect.ValueOf(&st.refused).Elem()}
+		}
+		if st.skipped != "" {
+			return st.skip(st.skipped)
+		}
+		if st.reason != "" {
+			return st.pending(st.reason)
+		}
+		out := v.Call(args)
+		if err, ok := out[0].Interface().(*NotApplicableError); ok {
+			return st.pending(err.Reason)
+		}
+		return out
+	}).Interface()
+}
+
+// pending records that the current scenario is not applicable and returns godog.ErrPending, which skips its remaining steps.
+func (st *Steps) pending(reason string) []reflect.Value {
+	entry := fmt.Sprintf("%s: %s", st.scenario, reason)
+	if len(st.notApplicable) == 0 || st.notApplicable[len(st.notApplicable)-1] != entry {
+		st.notApplicable = append(st.notApplicable, entry)
+	}
+	log.Printf("[WARN] Scenario '%s' is not applicable on %s: %s", st.scenario, DisplayName(st.provider), reason)
+
+	err := godog.ErrPending
+	return []reflect.Value{reflect.ValueOf(&err).Elem()}
+}
+
+// skip records that the guardrail skipped the current scenario and returns godog.ErrPending, which skips its remaining steps.
+func (st *Steps) skip(reason string) []reflect.Value {
+	entry := fmt.Sprintf("%s: %s", st.scenario, reason)
+	if len(st.guarded) == 0 || st.guarded[len(st.guarded)-1] != entry {
+		st.guarded = append(st.guarded, entry)
+	}
+	log.Printf("[WARN] Scenario '%s' is skipped by the guardrail on %s: %s", st.scenario, DisplayName(st.provider), reason)
+
+	err := godog.ErrPending
+	return []reflect.Value{reflect.ValueOf(&err).Elem()}
+}
+
+func (st *Steps) afterSuite() {
+	switch {
+	case len(st.kept) > 0:
+		log.Printf("[WARN] Not tearing down feature '%s', it may hold the resources of failed scenarios", st.feature)
+	case st.setUp && st.teardown != nil:
+		st.teardown()
+	}
+	st.report()
+}
+
+func (st *Steps) report() {
+	if st.guardrail != nil {
+		fmt.Printf("\nGuardrail: %v\n", *st.guardrail)
+	}
+	if len(st.kept) > 0 {
+		fmt.Printf("\nResources kept for %d failed scenario(s), run cmd/recover to delete them:\n", len(st.kept))
+		for _, s := range st.kept {
+			fmt.Printf("  %s\n", s)
+		}
+	}
+	if len(st.guarded) > 0 {
+		fmt.Printf("\n%d scenario(s) skipped by the guardrail on %s:\n", len(st.guarded), DisplayName(st.provider))
+		for _, e := range st.guarded {
+			fmt.Printf("  %s\n", e)
+		}
+	}
+	if len(st.notApplicable) == 0 {
+		return
+	}
+	fmt.Printf("\n%d scenario(s) not applicable on %s:\n", len(st.notApplicable), DisplayName(st.provider))
+	for _, e := range st.notApplicable {
+		fmt.Printf("  %s\n", e)
+	}
+}
@@ -0,0 +1,158 @@
+// Package evidence records, for each scenario that is run, when it was run and the cloud resources its steps inspected or
+// created, e.g. the storage accounts and policy assignments, which the results of godog do not hold. It is read back by
+// internal/oscal to evidence the results for auditors.
+package evidence
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"citihub.com/compliance-as-code/internal/guardrail"
+)
+
+// EnvVar is the environment variable naming the file that the evidence of each scenario is appended to, as a line of JSON.
+// Evidence is not recorded when it is not set.
+const EnvVar = "EVIDENCE_FILE"
+
+// Kinds of resources.
+const (
+	KindBucket           = "bucket"
+	KindStorageAccount   = "storage-account"
+	KindPolicyAssignment = "policy-assignment"
+	KindConfigRule       = "config-rule"
+)
+
+// Resource is a cloud resource inspected or created by a scenario.
+type Resource struct {
+	Kind string `json:"kind"`
+	// ID is the ID of the resource in the provider, e.g. the ARM ID of a storage account, or its name when it has no ID, e.g. an S3 bucket.
+	ID string `json:"id"`
+}
+
+// Scenario is the evidence of a scenario that was run.
+type Scenario struct {
+	// URI is the path of the feature file, as in the results of godog.
+	URI       string     `json:"uri"`
+	Name      string     `json:"name"`
+	Provider  string     `json:"provider"`
+	Start     time.Time  `json:"start"`
+	End       time.Time  `json:"end"`
+	Resources []Resource `json:"resources,omitempty"`
+	// Guardrail is the decision of the guardrail when it stopped the scenario, which then created nothing.
+	Guardrail *guardrail.Decision `json:"guardrail,omitempty"`
+}
+
+var (
+	mu      sync.Mutex
+	current *Scenario
+	// ended is the evidence of the scenarios run in this process, in the order they were run.
+	ended []Scenario
+)
+
+// Begin starts recording the evidence of a scenario. It is called by csp.Steps before each scenario.
+func Begin(uri, name, provider string) {
+	mu.Lock()
+	defer mu.Unlock()
+	current = &Scenario{URI: uri, Name: name, Provider: strings.ToLower(provider), Start: time.Now().UTC()}
+}
+
+// Record adds a resource to the evidence of the current scenario. Resources are recorded once, however often they are inspected.
+func Record(kind, id string) {
+	mu.Lock()
+	defer mu.Unlock()
+	if current == nil || id == "" {
+		return
+	}
+	r := Resource{Kind: kind, ID: id}
+	for _, e := range current.Resources {
+		if e == r {
+			return
+		}
+	}
+	current.Resources = append(current.Resources, r)
+}
+
+// Guardrail records that the guardrail stopped the current scenario with the decision.
+func Guardrail(d guardrail.Decision) {
+	mu.Lock()
+	defer mu.Unlock()
+	if current != nil {
+		current.Guardrail = &d
+	}
+}
+
+// End appends the evidence of the current scenario to the file named by the EVIDENCE_FILE environment variable.
+// It is called by csp.Steps after each scenario.
+func End() {
+	mu.Lock()
+	defer mu.Unlock()
+	if current == nil {
+		return
+	}
+	s := *current
+	current = nil
+	s.End = time.Now().UTC()
+	ended = append(ended, s)
+
+	path := os.Getenv(EnvVar)
+	if path == "" {
+		return
+	}
+	if err := appendLine(path, s); err != nil {
+		log.Printf("[ERROR] Cannot record evidence of scenario '%s' in %v: %v", s.Name, path, err)
+	}
+}
+
+func appendLine(path string, s Scenario) error {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Recorded returns the evidence of the scenarios run in this process, in the order they were run, whether or not it is
+// appended to a file.
+func Recorded() []Scenario {
+	mu.Lock()
+	defer mu.Unlock()
+	return append([]Scenario{}, ended...)
+}
+
+// Load reads the evidence recorded in a file, in the order the scenarios were run.
+func Load(path string) ([]Scenario, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var scenarios []Scenario
+	s := bufio.NewScanner(f)
+	s.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; s.Scan(); line++ {
+		if strings.TrimSpace(s.Text()) == "" {
+			continue
+		}
+		var sc Scenario
+		if err := json.Unmarshal(s.Bytes(), &sc); err != nil {
+			return nil, fmt.Errorf("cannot parse line %d of evidence %v: %v", line, path, err)
+		}
+		scenarios = append(scenarios, sc)
+	}
+	return scenarios, s.Err()
+}
@@ -0,0 +1,213 @@
+// Package featurefile reads the Gherkin features under test/features into a simple model of their tags, rules,
+// scenarios and steps, for the tools that check and report on the features without running them.
+package featurefile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	gherkin "github.com/cucumber/gherkin-go/v11"
+	messages "github.com/cucumber/messages-go/v10"
+)
+
+// Ext is the extension of Gherkin feature files.
+const Ext = ".feature"
+
+// Tag is a tag of a feature or scenario, including its @, e.g. "@CCO:CHC2-SVD030".
+type Tag struct {
+	Name string
+	Line int
+}
+
+// Step is a step of a scenario as it is run: the steps of a Scenario Outline are repeated for each row of its examples, with the placeholders replaced.
+type Step struct {
+	Keyword string
+	Text    string
+	Line    int
+}
+
+// Rule is a Rule of a feature, e.g. "CHC2-SVD030 - protect cloud service network access by limiting access from the appropriate source network only".
+type Rule struct {
+	Name string
+	Line int
+}
+
+// Scenario is a Scenario or Scenario Outline of a feature.
+type Scenario struct {
+	Name string
+	Line int
+	// Tags are the tags of the scenario itself, without those of its feature.
+	Tags []Tag
+	// Rule is the name of the rule holding the scenario, empty if it is not in a rule.
+	Rule  string
+	Steps []Step
+}
+
+// Feature is a feature file.
+type Feature struct {
+	Path string
+	Name string
+	Line int
+	Tags []Tag
+	// Rules are the rules of the feature, in the order of the file.
+	Rules []Rule
+	// Scenarios are the scenarios of the feature, including those in rules, in the order of the file.
+	Scenarios []Scenario
+}
+
+// HasTag reports whether the feature is tagged with name, e.g. "@internal".
+func (f *Feature) HasTag(name string) bool {
+	return hasTag(f.Tags, name)
+}
+
+// TagValues returns the values of the feature tags with the prefix, e.g. ["aws", "azure"] for "@csp." and the tags "@csp.aws" and "@csp.azure".
+func (f *Feature) TagValues(prefix string) []string {
+	return tagValues(f.Tags, prefix)
+}
+
+// HasTag reports whether the scenario, or its feature, is tagged with name.
+func (s Scenario) HasTag(f *Feature, name string) bool {
+	return hasTag(s.Tags, name) || f.HasTag(name)
+}
+
+// Find returns the paths of the feature files under root, including its sub-directories, in lexical order.
+func Find(root string) ([]string, error) {
+	var files []string
+	err := filepath.Walk(root, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !fi.IsDir() && filepath.Ext(path) == Ext {
+			files = append(files, path)
+		}
+		return nil
+	})
+	sort.Strings(files)
+	return files, err
+}
+
+// SuiteDir returns the directory of the godog suite that runs a feature file: the parent of its "features" directory.
+func SuiteDir(path string) string {
+	dir := filepath.Dir(path)
+	if filepath.Base(dir) == "features" {
+		return filepath.Dir(dir)
+	}
+	return dir
+}
+
+// Parse reads a feature file.
+func Parse(path string) (*Feature, error) {
+	r, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	newID := (&messages.Incrementing{}).NewId
+	doc, err := gherkin.ParseGherkinDocument(r, newID)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse %v: %v", path, err)
+	}
+	if doc.Feature == nil {
+		return nil, fmt.Errorf("%v has no feature", path)
+	}
+
+	f := &Feature{
+		Path: path,
+		Name: doc.Feature.Name,
+		Line: line(doc.Feature.Location),
+		Tags: tags(doc.Feature.Tags),
+	}
+
+	// The scenarios and steps of the document, by ID, so that the pickles can be traced back to them
+	scenarios := map[string]int{}
+	steps := map[string]*messages.GherkinDocument_Feature_Step{}
+	addScenario := func(s *messages.GherkinDocument_Feature_Scenario, rule string) {
+		scenarios[s.Id] = len(f.Scenarios)
+		f.Scenarios = append(f.Scenarios, Scenario{Name: s.Name, Line: line(s.Location), Tags: tags(s.Tags), Rule: rule})
+		for _, st := range s.Steps {
+			steps[st.Id] = st
+		}
+	}
+	addBackground := func(b *messages.GherkinDocument_Feature_Background) {
+		for _, st := range b.Steps {
+			steps[st.Id] = st
+		}
+	}
+
+	for _, c := range doc.Feature.Children {
+		switch {
+		case c.GetScenario() != nil:
+			addScenario(c.GetScenario(), "")
+		case c.GetBackground() != nil:
+			addBackground(c.GetBackground())
+		case c.GetRule() != nil:
+			r := c.GetRule()
+			f.Rules = append(f.Rules, Rule{Name: r.Name, Line: line(r.Location)})
+			for _, rc := range r.Children {
+				if rc.GetScenario() != nil {
+					addScenario(rc.GetScenario(), r.Name)
+				} else if rc.GetBackground() != nil {
+					addBackground(rc.GetBackground())
+				}
+			}
+		}
+	}
+
+	for _, p := range gherkin.Pickles(*doc, path, newID) {
+		if len(p.AstNodeIds) == 0 {
+			continue
+		}
+		i, ok := scenarios[p.AstNodeIds[0]]
+		if !ok {
+			continue
+		}
+		for _, ps := range p.Steps {
+			s := Step{Text: ps.Text}
+			if len(ps.AstNodeIds) > 0 {
+				if st, ok := steps[ps.AstNodeIds[0]]; ok {
+					s.Keyword, s.Line = strings.TrimSpace(st.Keyword), line(st.Location)
+				}
+			}
+			f.Scenarios[i].Steps = append(f.Scenarios[i].Steps, s)
+		}
+	}
+	return f, nil
+}
+
+func tags(t []*messages.GherkinDocument_Feature_Tag) []Tag {
+	var r []Tag
+	for _, tag := range t {
+		r = append(r, Tag{Name: tag.Name, Line: line(tag.Location)})
+	}
+	return r
+}
+
+func hasTag(t []Tag, name string) bool {
+	for _, tag := range t {
+		if strings.EqualFold(tag.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func tagValues(t []Tag, prefix string) []string {
+	var v []string
+	for _, tag := range t {
+		if len(tag.Name) > len(prefix) && strings.EqualFold(tag.Name[:len(prefix)], prefix) {
+			v = append(v, tag.Name[len(prefix):])
+		}
+	}
+	return v
+}
+
+func line(l *messages.Location) int {
+	if l == nil {
+		return 0
+	}
+	return int(l.Line)
+}
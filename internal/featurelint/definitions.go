@@ -0,0 +1,330 @@
+package featurelint
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"citihub.com/compliance-as-code/internal/csp"
+	"citihub.com/compliance-as-code/internal/steps"
+)
+
+// Definitions are the step expressions that a godog suite binds, found by reading its Go source rather than by running it,
+// as running a suite needs the credentials of every provider.
+type Definitions struct {
+	// Common are the steps bound whichever provider is selected, i.e. the expressions passed to Step.
+	Common []*regexp.Regexp
+	// Providers holds, for each provider that the suite registers an implementation for with csp.Register, the shared steps
+	// of internal/steps that the implementation defines.
+	Providers map[string][]*regexp.Regexp
+	// Unsupported holds, for each provider, the capabilities that the suite declares unsupported with csp.Unsupported and why.
+	Unsupported map[string]map[csp.Capability]string
+}
+
+// Supports reports whether the suite runs the scenarios of the capability on the provider.
+func (d Definitions) Supports(provider string, c csp.Capability) bool {
+	_, unsupported := d.Unsupported[strings.ToLower(provider)][c]
+	return d.Registers(provider) && !unsupported
+}
+
+// Match reports whether a step text matches a step bound for the provider, or for any provider if provider is empty.
+func (d Definitions) Match(provider, text string) bool {
+	for _, r := range d.Common {
+		if r.MatchString(text) {
+			return true
+		}
+	}
+	for p, defs := range d.Providers {
+		if provider != "" && !strings.EqualFold(p, provider) {
+			continue
+		}
+		for _, r := range defs {
+			if r.MatchString(text) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Registers reports whether the suite registers an implementation for the provider. Suites that register no implementation
+// run the same steps for every provider.
+func (d Definitions) Registers(provider string) bool {
+	if len(d.Providers) == 0 {
+		return true
+	}
+	for p := range d.Providers {
+		if strings.EqualFold(p, provider) {
+			return true
+		}
+	}
+	return false
+}
+
+// source is the parsed Go source of a suite.
+type source struct {
+	// values are the package-level constants and variables
+	values map[string]ast.Expr
+	// methods are the method names of each type
+	methods map[string]map[string]bool
+}
+
+// LoadDefinitions reads the step definitions of the suite in dir from calls to Step, steps.Bind, csp.Register and csp.Unsupported.
+// Step expressions must be string constants, as in the existing suites.
+func LoadDefinitions(dir string) (Definitions, error) {
+	d := Definitions{Providers: map[string][]*regexp.Regexp{}, Unsupported: map[string]map[csp.Capability]string{}}
+
+	pkgs, err := parser.ParseDir(token.NewFileSet(), dir, nil, 0)
+	if err != nil {
+		return d, err
+	}
+
+	src := source{values: map[string]ast.Expr{}, methods: map[string]map[string]bool{}}
+	var files []*ast.File
+	for _, p := range pkgs {
+		for _, f := range p.Files {
+			files = append(files, f)
+			src.collect(f)
+		}
+	}
+
+	var controls []steps.Control
+	implementations := map[string]string{}
+	for _, f := range files {
+		var ierr error
+		ast.Inspect(f, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok || ierr != nil {
+				return ierr == nil
+			}
+			sel, ok := call.Fun.(*ast.SelectorExpr)
+			if !ok {
+				return true
+			}
+
+			switch {
+			case sel.Sel.Name == "Step" && len(call.Args) == 2:
+				expr, ok := src.stringValue(call.Args[0])
+				if !ok {
+					ierr = fmt.Errorf("step expression %v is not a string constant", src.describe(call.Args[0]))
+					return false
+				}
+				r, err := regexp.Compile(expr)
+				if err != nil {
+					ierr = fmt.Errorf("invalid step expression %v: %v", expr, err)
+					return false
+				}
+				d.Common = append(d.Common, r)
+
+			case isPackage(sel, "steps", "Bind") && len(call.Args) == 3:
+				c, err := src.control(call.Args[1])
+				if err != nil {
+					ierr = err
+					return false
+				}
+				controls = append(controls, c)
+
+			case isPackage(sel, "csp", "Register") && len(call.Args) == 3:
+				provider, ok := src.provider(call.Args[1])
+				if !ok {
+					ierr = fmt.Errorf("provider %v of csp.Register is not a constant", src.describe(call.Args[1]))
+					return false
+				}
+				implementations[provider] = implementationType(call.Args[2])
+
+			case isPackage(sel, "csp", "Unsupported") && len(call.Args) == 4:
+				provider, ok := src.provider(call.Args[1])
+				c, cok := src.capability(call.Args[2])
+				if !ok || !cok {
+					ierr = fmt.Errorf("provider %v or capability %v of csp.Unsupported is not a constant", src.describe(call.Args[1]), src.describe(call.Args[2]))
+					return false
+				}
+				reason, _ := src.stringValue(call.Args[3])
+				if d.Unsupported[provider] == nil {
+					d.Unsupported[provider] = map[csp.Capability]string{}
+				}
+				d.Unsupported[provider][c] = reason
+			}
+			return true
+		})
+		if ierr != nil {
+			return d, fmt.Errorf("cannot read the step definitions of %v: %v", dir, ierr)
+		}
+	}
+
+	for provider, typ := range implementations {
+		d.Providers[provider] = nil
+		for _, c := range controls {
+			for _, def := range steps.Definitions(c) {
+				if !src.methods[typ][def.Method] {
+					continue
+				}
+				r, err := regexp.Compile(def.Expr)
+				if err != nil {
+					return d, fmt.Errorf("invalid shared step expression %v: %v", def.Expr, err)
+				}
+				d.Providers[provider] = append(d.Providers[provider], r)
+			}
+		}
+	}
+	return d, nil
+}
+
+func (src source) collect(f *ast.File) {
+	for _, decl := range f.Decls {
+		switch decl := decl.(type) {
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				vs, ok := spec.(*ast.ValueSpec)
+				if !ok {
+					continue
+				}
+				for i, name := range vs.Names {
+					if i < len(vs.Values) {
+						src.values[name.Name] = vs.Values[i]
+					}
+				}
+			}
+		case *ast.FuncDecl:
+			if decl.Recv == nil || len(decl.Recv.List) == 0 {
+				continue
+			}
+			typ := typeName(decl.Recv.List[0].Type)
+			if src.methods[typ] == nil {
+				src.methods[typ] = map[string]bool{}
+			}
+			src.methods[typ][decl.Name.Name] = true
+		}
+	}
+}
+
+// stringValue evaluates a string constant: a literal, a concatenation or a package-level constant or variable.
+func (src source) stringValue(e ast.Expr) (string, bool) {
+	switch e := e.(type) {
+	case *ast.BasicLit:
+		if e.Kind != token.STRING {
+			return "", false
+		}
+		s, err := strconv.Unquote(e.Value)
+		return s, err == nil
+	case *ast.BinaryExpr:
+		if e.Op != token.ADD {
+			return "", false
+		}
+		x, ok := src.stringValue(e.X)
+		if !ok {
+			return "", false
+		}
+		y, ok := src.stringValue(e.Y)
+		return x + y, ok
+	case *ast.ParenExpr:
+		return src.stringValue(e.X)
+	case *ast.Ident:
+		if v, ok := src.values[e.Name]; ok {
+			return src.stringValue(v)
+		}
+	}
+	return "", false
+}
+
+// control evaluates a steps.Control composite literal, or a package-level variable holding one.
+func (src source) control(e ast.Expr) (steps.Control, error) {
+	var c steps.Control
+	if id, ok := e.(*ast.Ident); ok {
+		v, ok := src.values[id.Name]
+		if !ok {
+			return c, fmt.Errorf("control %v of steps.Bind is not a package-level variable", id.Name)
+		}
+		e = v
+	}
+	if u, ok := e.(*ast.UnaryExpr); ok && u.Op == token.AND {
+		e = u.X
+	}
+	lit, ok := e.(*ast.CompositeLit)
+	if !ok {
+		return c, fmt.Errorf("control %v of steps.Bind is not a steps.Control literal", src.describe(e))
+	}
+
+	for _, elt := range lit.Elts {
+		kv, ok := elt.(*ast.KeyValueExpr)
+		if !ok {
+			return c, fmt.Errorf("steps.Control literal must name its fields")
+		}
+		key, _ := kv.Key.(*ast.Ident)
+		value, ok := src.stringValue(kv.Value)
+		if key == nil || !ok {
+			return c, fmt.Errorf("field %v of steps.Control literal is not a string constant", src.describe(kv.Key))
+		}
+		switch key.Name {
+		case "Prevents":
+			c.Prevents = value
+		case "NonCompliance":
+			c.NonCompliance = value
+		case "Remediation":
+			c.Remediation = value
+		default:
+			return c, fmt.Errorf("unknown field %v of steps.Control", key.Name)
+		}
+	}
+	return c, nil
+}
+
+// provider evaluates the provider argument of csp.Register, e.g. csp.AWS or "aws".
+func (src source) provider(e ast.Expr) (string, bool) {
+	if sel, ok := e.(*ast.SelectorExpr); ok && isPackage(sel, "csp", sel.Sel.Name) {
+		return strings.ToLower(sel.Sel.Name), true
+	}
+	p, ok := src.stringValue(e)
+	return strings.ToLower(p), ok
+}
+
+// capability evaluates the capability argument of csp.Unsupported, e.g. csp.Preventative.
+func (src source) capability(e ast.Expr) (csp.Capability, bool) {
+	if sel, ok := e.(*ast.SelectorExpr); ok && isPackage(sel, "csp", sel.Sel.Name) {
+		return csp.Capability(strings.ToLower(sel.Sel.Name)), true
+	}
+	c, ok := src.stringValue(e)
+	return csp.Capability(strings.ToLower(c)), ok
+}
+
+func (src source) describe(e ast.Expr) string {
+	switch e := e.(type) {
+	case *ast.Ident:
+		return e.Name
+	case *ast.SelectorExpr:
+		return src.describe(e.X) + "." + e.Sel.Name
+	}
+	return fmt.Sprintf("%T", e)
+}
+
+// implementationType returns the type of the implementation returned by the factory of csp.Register, e.g.
+// EncryptionInFlightAWS for func() interface{} { return &EncryptionInFlightAWS{} }.
+func implementationType(factory ast.Expr) string {
+	var typ string
+	ast.Inspect(factory, func(n ast.Node) bool {
+		if lit, ok := n.(*ast.CompositeLit); ok && typ == "" {
+			typ = typeName(lit.Type)
+		}
+		return typ == ""
+	})
+	return typ
+}
+
+func typeName(e ast.Expr) string {
+	switch e := e.(type) {
+	case *ast.StarExpr:
+		return typeName(e.X)
+	case *ast.Ident:
+		return e.Name
+	}
+	return ""
+}
+
+func isPackage(sel *ast.SelectorExpr, pkg, name string) bool {
+	id, ok := sel.X.(*ast.Ident)
+	return ok && id.Name == pkg && sel.Sel.Name == name
+}
@@ -0,0 +1,215 @@
+// Package featurelint checks that the Gherkin features under test/features follow the conventions of the repository:
+// each control feature traces back to its control objectives, declares the providers it is implemented for and whether it
+// is intrusive, tags its scenarios with the kind of control they test and has a step definition for every step.
+package featurelint
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"citihub.com/compliance-as-code/internal/csp"
+	"citihub.com/compliance-as-code/internal/featurefile"
+)
+
+// Lint checks.
+const (
+	CheckParse         = "parse"
+	CheckControlTag    = "control-tag"
+	CheckCSPTag        = "csp-tag"
+	CheckIntrusiveTag  = "intrusive-tag"
+	CheckCapabilityTag = "capability-tag"
+	CheckRule          = "rule"
+	CheckUndefinedStep = "undefined-step"
+)
+
+// Tags of the conventions.
+const (
+	// InternalTag marks features that check the repository itself offline, e.g. its policy documents, rather than a
+	// control in the environment under test. They are exempt from the control checks, so must not be tagged
+	// IntrusiveTag or NonIntrusiveTag, which mark the features that check the environment under test.
+	InternalTag = "@internal"
+	// ControlTagPrefix prefixes the IDs of the common control objectives that a feature implements, e.g. "@CCO:CHC2-SVD030".
+	ControlTagPrefix = "@CCO:"
+	// CSPTagPrefix prefixes the providers that a feature is implemented for, e.g. "@csp.azure".
+	CSPTagPrefix = "@csp."
+	// IntrusiveTag marks features that create resources in the environment under test, or the scenarios that do of a
+	// feature tagged NonIntrusiveTag.
+	IntrusiveTag = "@intrusive_test"
+	// NonIntrusiveTag marks features that only read the environment under test.
+	NonIntrusiveTag = "@non_intrusive_test"
+)
+
+// capabilityTags are the scenario tags naming the kind of control a scenario tests.
+var capabilityTags = []string{"@" + string(csp.Preventative), "@" + string(csp.Detective), "@" + string(csp.Corrective)}
+
+// createStep matches the steps that create resources in the environment under test, e.g. "we provision an Object Storage
+// bucket" or "Object Storage is created without encryption at rest".
+var createStep = regexp.MustCompile(`(?i)^we provision\b|\bis created\b`)
+
+// ruleID is the control ID that a Rule name starts with, e.g. "CHC2-SVD030" in "CHC2-SVD030 - protect cloud service network access ...".
+var ruleID = regexp.MustCompile(`^\s*([A-Za-z0-9]+-[A-Za-z0-9]+)`)
+
+// Finding is a problem reported by Lint.
+type Finding struct {
+	File  string `json:"file"`
+	Line  int    `json:"line"`
+	Check string `json:"check"`
+	// Provider is the provider that a step is undefined for, empty for the other checks.
+	Provider string `json:"provider,omitempty"`
+	Message  string `json:"message"`
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s:%d: %s: %s", f.File, f.Line, f.Check, f.Message)
+}
+
+// Lint checks a feature. The step definitions check is skipped when defs is nil.
+func Lint(f *featurefile.Feature, defs *Definitions) []Finding {
+	var findings []Finding
+	add := func(line int, check, format string, a ...interface{}) {
+		findings = append(findings, Finding{File: f.Path, Line: line, Check: check, Message: fmt.Sprintf(format, a...)})
+	}
+
+	providers := f.TagValues(CSPTagPrefix)
+	if f.HasTag(InternalTag) {
+		for _, t := range []string{IntrusiveTag, NonIntrusiveTag} {
+			if f.HasTag(t) {
+				add(f.Line, CheckIntrusiveTag, "feature is tagged %s, for offline checks of the repository, but also %s, which marks the checks of the environment under test",
+					InternalTag, t)
+			}
+		}
+	} else {
+		controls := f.TagValues(ControlTagPrefix)
+		if len(controls) == 0 {
+			add(f.Line, CheckControlTag, "feature has no %s tag naming the control objective it implements", ControlTagPrefix)
+		}
+		if len(providers) == 0 {
+			add(f.Line, CheckCSPTag, "feature has no %s tag naming a provider it is implemented for", CSPTagPrefix)
+		}
+
+		switch intrusive, nonIntrusive := f.HasTag(IntrusiveTag), f.HasTag(NonIntrusiveTag); {
+		case intrusive && nonIntrusive:
+			add(f.Line, CheckIntrusiveTag, "feature is tagged both %s and %s", IntrusiveTag, NonIntrusiveTag)
+		case !intrusive && !nonIntrusive:
+			add(f.Line, CheckIntrusiveTag, "feature is tagged neither %s nor %s", IntrusiveTag, NonIntrusiveTag)
+		}
+
+		if len(f.Rules) == 0 {
+			add(f.Line, CheckRule, "feature has no Rule naming a control objective of its %s tags", ControlTagPrefix)
+		}
+		for _, r := range f.Rules {
+			m := ruleID.FindStringSubmatch(r.Name)
+			if m == nil {
+				add(r.Line, CheckRule, "rule '%s' does not start with a control objective ID, e.g. 'CHC2-SVD030 - ...'", r.Name)
+			} else if !containsFold(controls, m[1]) {
+				add(r.Line, CheckRule, "rule '%s' names control objective %s, which is not one of the %s tags %v", r.Name, m[1], ControlTagPrefix, controls)
+			}
+		}
+
+		for _, s := range f.Scenarios {
+			if len(f.Rules) > 0 && s.Rule == "" {
+				add(s.Line, CheckRule, "scenario '%s' is not in a Rule", s.Name)
+			}
+			if !hasAnyTag(f, s, capabilityTags) {
+				add(s.Line, CheckCapabilityTag, "scenario '%s' is not tagged with any of %v", s.Name, capabilityTags)
+			}
+			// The guardrail only stops the scenarios tagged intrusive
+			if !s.HasTag(f, IntrusiveTag) {
+				for _, st := range s.Steps {
+					if createStep.MatchString(st.Text) {
+						add(s.Line, CheckIntrusiveTag, "scenario '%s' creates resources in step '%s %s', but is not tagged %s",
+							s.Name, st.Keyword, st.Text, IntrusiveTag)
+						break
+					}
+				}
+			}
+		}
+	}
+
+	if defs == nil {
+		return findings
+	}
+
+	for _, t := range f.Tags {
+		if !strings.HasPrefix(strings.ToLower(t.Name), strings.ToLower(CSPTagPrefix)) {
+			continue
+		}
+		if p := strings.ToLower(t.Name[len(CSPTagPrefix):]); !defs.Registers(p) {
+			add(t.Line, CheckCSPTag, "feature is tagged %s, but its suite registers no %s implementation", t.Name, p)
+		}
+	}
+
+	// Features that do not name a provider are checked against the steps of every provider
+	if len(providers) == 0 {
+		providers = []string{""}
+	}
+	reported := map[string]bool{}
+	for _, s := range f.Scenarios {
+		for _, st := range s.Steps {
+			for _, p := range providers {
+				key := fmt.Sprintf("%d/%s", st.Line, strings.ToLower(p))
+				if reported[key] || defs.Match(p, st.Text) {
+					continue
+				}
+				reported[key] = true
+				finding := Finding{File: f.Path, Line: st.Line, Check: CheckUndefinedStep, Provider: strings.ToLower(p),
+					Message: fmt.Sprintf("step '%s %s' of scenario '%s' has no step definition", st.Keyword, st.Text, s.Name)}
+				if p != "" {
+					finding.Message += " for " + csp.DisplayName(p)
+				}
+				findings = append(findings, finding)
+			}
+		}
+	}
+	return findings
+}
+
+// Files lints the feature files under root, reading the step definitions of the suite of each feature once.
+// Files that cannot be parsed are reported as findings, other errors are returned.
+func Files(root string) ([]Finding, error) {
+	paths, err := featurefile.Find(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var findings []Finding
+	suites := map[string]*Definitions{}
+	for _, path := range paths {
+		f, err := featurefile.Parse(path)
+		if err != nil {
+			findings = append(findings, Finding{File: path, Check: CheckParse, Message: err.Error()})
+			continue
+		}
+
+		dir := featurefile.SuiteDir(path)
+		defs, ok := suites[dir]
+		if !ok {
+			d, err := LoadDefinitions(dir)
+			if err != nil {
+				return findings, err
+			}
+			defs, suites[dir] = &d, &d
+		}
+		findings = append(findings, Lint(f, defs)...)
+	}
+	return findings, nil
+}
+
+func hasAnyTag(f *featurefile.Feature, s featurefile.Scenario, tags []string) bool {
+	for _, t := range tags {
+		if s.HasTag(f, t) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(list []string, s string) bool {
+	for _, l := range list {
+		if strings.EqualFold(l, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,227 @@
+// Package guardrail stops the scenarios tagged @intrusive_test, which create real resources, from running against protected
+// environments, e.g. the production AWS accounts and Azure subscriptions listed in guardrail.yaml. The environment under
+// test is that of the identity the suites run with, resolved once per provider.
+package guardrail
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// IntrusiveTag marks the features and scenarios that create real resources.
+	IntrusiveTag = "@intrusive_test"
+
+	// ConfigEnvVar is the environment variable naming the guardrail config. When it is not set, ConfigFile is looked for in
+	// the working directory and its parents, so that the suites find the config of the repository from their directories.
+	ConfigEnvVar = "GUARDRAIL_CONFIG"
+	// ConfigFile is the name of the guardrail config.
+	ConfigFile = "guardrail.yaml"
+
+	// OverrideEnvVar is the environment variable listing, comma-separated, the protected environments that intrusive
+	// scenarios may run against nonetheless, e.g. "123456789012". The ID must be given so that an override is explicit.
+	OverrideEnvVar = "GUARDRAIL_OVERRIDE"
+)
+
+// Modes, i.e. what happens to intrusive scenarios that may not run.
+const (
+	// ModeRefuse fails the scenarios.
+	ModeRefuse = "refuse"
+	// ModeSkip skips the scenarios, which godog reports as pending like those of a control the provider does not offer, and
+	// internal/results as skipped by the guardrail with the decision, which is recorded in their evidence.
+	ModeSkip = "skip"
+)
+
+// Config lists the protected environments.
+type Config struct {
+	// Mode is what happens to intrusive scenarios that may not run, refuse unless given.
+	Mode string `yaml:"mode"`
+	// Protected are the IDs of the protected environments of each provider, as named by the CSP environment variable,
+	// i.e. AWS account IDs for "aws" and Azure subscription IDs for "azure".
+	Protected map[string][]string `yaml:"protected"`
+}
+
+// LoadConfig reads and validates a guardrail config.
+func LoadConfig(path string) (*Config, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &Config{}
+	if err := yaml.UnmarshalStrict(b, c); err != nil {
+		return nil, fmt.Errorf("cannot parse guardrail config %v: %v", path, err)
+	}
+	if c.Mode == "" {
+		c.Mode = ModeRefuse
+	}
+	if c.Mode != ModeRefuse && c.Mode != ModeSkip {
+		return nil, fmt.Errorf("mode '%s' of guardrail config %v is not %s or %s", c.Mode, path, ModeRefuse, ModeSkip)
+	}
+	for p := range c.Protected {
+		if _, ok := identities[strings.ToLower(p)]; !ok {
+			return nil, fmt.Errorf("guardrail config %v protects environments of '%s', which is not one of %v", path, p, providers())
+		}
+	}
+	return c, nil
+}
+
+// Decision is whether intrusive scenarios may run against the environment of a provider, and why.
+type Decision struct {
+	Provider string `json:"provider"`
+	// Identity is the ID of the environment under test, e.g. the AWS account ID, empty if it was not resolved.
+	Identity  string `json:"identity,omitempty"`
+	Protected bool   `json:"protected"`
+	// Overridden is set when the environment is protected but was given in the GUARDRAIL_OVERRIDE environment variable.
+	Overridden bool   `json:"overridden,omitempty"`
+	Allowed    bool   `json:"allowed"`
+	Mode       string `json:"mode"`
+	Reason     string `json:"reason"`
+	Config     string `json:"config,omitempty"`
+}
+
+func (d Decision) String() string {
+	verdict := "allowed"
+	if !d.Allowed {
+		verdict = "refused"
+		if d.Mode == ModeSkip {
+			verdict = "skipped"
+		}
+	}
+	return fmt.Sprintf("intrusive scenarios %s on %s: %s", verdict, d.Provider, d.Reason)
+}
+
+var (
+	mu        sync.Mutex
+	decisions = map[string]Decision{}
+)
+
+// Check decides whether intrusive scenarios may run against the environment of the provider. The decision is made once per
+// provider, on the first call, and logged. Intrusive scenarios are refused when the environment cannot be told apart from
+// a protected one, e.g. because the identity cannot be resolved or the config cannot be read.
+func Check(provider string) Decision {
+	mu.Lock()
+	defer mu.Unlock()
+
+	provider = strings.ToLower(provider)
+	if d, ok := decisions[provider]; ok {
+		return d
+	}
+	d := decide(provider)
+	decisions[provider] = d
+	if d.Allowed && !d.Overridden {
+		log.Printf("[DEBUG] Guardrail: %v", d)
+	} else {
+		log.Printf("[WARN] Guardrail: %v", d)
+	}
+	return d
+}
+
+// Decisions returns the decisions made, sorted by provider.
+func Decisions() []Decision {
+	mu.Lock()
+	defer mu.Unlock()
+	var ds []Decision
+	for _, d := range decisions {
+		ds = append(ds, d)
+	}
+	sort.Slice(ds, func(i, j int) bool { return ds[i].Provider < ds[j].Provider })
+	return ds
+}
+
+func decide(provider string) Decision {
+	d := Decision{Provider: provider, Mode: ModeRefuse}
+
+	path, err := findConfig()
+	if err != nil {
+		d.Reason = err.Error()
+		return d
+	}
+	if path == "" {
+		d.Allowed, d.Reason = true, fmt.Sprintf("no %s found and %s is not set", ConfigFile, ConfigEnvVar)
+		return d
+	}
+	d.Config = path
+	c, err := LoadConfig(path)
+	if err != nil {
+		d.Reason = err.Error()
+		return d
+	}
+	d.Mode = c.Mode
+
+	var protected []string
+	for p, ids := range c.Protected {
+		if strings.EqualFold(p, provider) {
+			protected = ids
+		}
+	}
+	if len(protected) == 0 {
+		d.Allowed, d.Reason = true, fmt.Sprintf("no environment of %s is protected", provider)
+		return d
+	}
+
+	identity, ok := identities[provider]
+	if !ok {
+		d.Reason = fmt.Sprintf("the environment of %s cannot be identified", provider)
+		return d
+	}
+	if d.Identity, err = identity(); err != nil {
+		d.Reason = fmt.Sprintf("cannot identify the environment under test: %v", err)
+		return d
+	}
+
+	if !contains(protected, d.Identity) {
+		d.Allowed, d.Reason = true, fmt.Sprintf("%s is not protected", d.Identity)
+		return d
+	}
+	d.Protected = true
+	if contains(strings.Split(os.Getenv(OverrideEnvVar), ","), d.Identity) {
+		d.Allowed, d.Overridden = true, true
+		d.Reason = fmt.Sprintf("%s is protected, overridden by %s", d.Identity, OverrideEnvVar)
+		return d
+	}
+	d.Reason = fmt.Sprintf("%s is protected by %s, set %s=%s to override", d.Identity, path, OverrideEnvVar, d.Identity)
+	return d
+}
+
+// findConfig returns the path of the guardrail config, empty if there is none.
+func findConfig() (string, error) {
+	if path := os.Getenv(ConfigEnvVar); path != "" {
+		if _, err := os.Stat(path); err != nil {
+			return "", fmt.Errorf("cannot read guardrail config named by %s: %v", ConfigEnvVar, err)
+		}
+		return path, nil
+	}
+
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	for {
+		path := filepath.Join(dir, ConfigFile)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+func contains(ids []string, id string) bool {
+	for _, e := range ids {
+		if strings.EqualFold(strings.TrimSpace(e), id) {
+			return true
+		}
+	}
+	return false
+}
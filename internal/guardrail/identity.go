@@ -0,0 +1,50 @@
+package guardrail
+
+import (
+	"errors"
+	"os"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+// identities resolve the ID of the environment under test of each provider, as named by the CSP environment variable.
+var identities = map[string]func() (string, error){
+	"aws":   awsAccount,
+	"azure": azureSubscription,
+}
+
+// awsAccount returns the account of the caller, as the suites create resources in the account of their credentials.
+func awsAccount() (string, error) {
+	sess, err := session.NewSession()
+	if err != nil {
+		return "", err
+	}
+	out, err := sts.New(sess).GetCallerIdentity(&sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", err
+	}
+	if out.Account == nil {
+		return "", errors.New("STS GetCallerIdentity returned no account")
+	}
+	return *out.Account, nil
+}
+
+// azureSubscription returns the subscription that the suites create resources in, given by AZURE_SUBSCRIPTION_ID.
+func azureSubscription() (string, error) {
+	id := os.Getenv("AZURE_SUBSCRIPTION_ID")
+	if id == "" {
+		return "", errors.New("environment variable AZURE_SUBSCRIPTION_ID is not set")
+	}
+	return id, nil
+}
+
+func providers() []string {
+	var p []string
+	for name := range identities {
+		p = append(p, name)
+	}
+	sort.Strings(p)
+	return p
+}
@@ -0,0 +1,122 @@
+package janitor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	"citihub.com/compliance-as-code/internal/awsutil"
+	"citihub.com/compliance-as-code/internal/clouderr"
+	"citihub.com/compliance-as-code/internal/ledger"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+var (
+	clientsMu sync.Mutex
+	clients   = map[string]*s3.S3{}
+)
+
+// s3Client returns a client of the region, the region of the environment, i.e. AWS_REGION, if empty. Requests on a
+// bucket must be made in its region.
+func s3Client(region string) (*s3.S3, error) {
+	clientsMu.Lock()
+	defer clientsMu.Unlock()
+
+	if c, ok := clients[region]; ok {
+		return c, nil
+	}
+	cfg := aws.NewConfig()
+	if region != "" {
+		cfg = cfg.WithRegion(region)
+	}
+	sess, err := session.NewSession(cfg)
+	if err != nil {
+		return nil, err
+	}
+	clients[region] = s3.New(sess)
+	return clients[region], nil
+}
+
+func findAWS(ctx context.Context, o Options) ([]Candidate, error) {
+	svc, err := s3Client("")
+	if err != nil {
+		return nil, err
+	}
+	out, err := svc.ListBucketsWithContext(ctx, &s3.ListBucketsInput{})
+	if err != nil {
+		return nil, fmt.Errorf("cannot list buckets: %v", err)
+	}
+
+	var found []Candidate
+	for _, b := range out.Buckets {
+		c := Candidate{
+			Provider: ledger.AWS,
+			Kind:     ledger.KindBucket,
+			Name:     aws.StringValue(b.Name),
+			Created:  aws.TimeValue(b.CreationDate),
+		}
+		// Buckets of other teams may not be readable, they are not ours then. Tags are only read in the region of a bucket
+		c.Region, err = bucketRegion(ctx, svc, c.Name)
+		if err != nil {
+			log.Printf("[WARN] Ignoring bucket %s: %v", c.Name, err)
+			continue
+		}
+		ok, err := o.match(&c, func() (map[string]string, error) { return bucketTags(ctx, c.Region, c.Name) })
+		if err != nil {
+			log.Printf("[WARN] Ignoring bucket %s: %v", c.Name, err)
+			continue
+		}
+		if ok {
+			found = append(found, c)
+		}
+	}
+	return found, nil
+}
+
+func bucketRegion(ctx context.Context, svc *s3.S3, bucket string) (string, error) {
+	out, err := svc.GetBucketLocationWithContext(ctx, &s3.GetBucketLocationInput{Bucket: aws.String(bucket)})
+	if err != nil {
+		return "", fmt.Errorf("cannot get location: %v", err)
+	}
+	return s3.NormalizeBucketLocation(aws.StringValue(out.LocationConstraint)), nil
+}
+
+func bucketTags(ctx context.Context, region, bucket string) (map[string]string, error) {
+	svc, err := s3Client(region)
+	if err != nil {
+		return nil, err
+	}
+	out, err := svc.GetBucketTaggingWithContext(ctx, &s3.GetBucketTaggingInput{Bucket: aws.String(bucket)})
+	// NoSuchTagSet
+	if clouderr.Classify(err) == clouderr.KindNotFound {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot get tags: %v", err)
+	}
+	tags := map[string]string{}
+	for _, t := range out.TagSet {
+		tags[aws.StringValue(t.Key)] = aws.StringValue(t.Value)
+	}
+	return tags, nil
+}
+
+// removeAWS empties a bucket, including the versions of its objects and their delete markers, and deletes it.
+func removeAWS(ctx context.Context, c Candidate) error {
+	if c.Kind != ledger.KindBucket {
+		return fmt.Errorf("cannot delete resources of kind %s of %s", c.Kind, c.Provider)
+	}
+	svc, err := s3Client(c.Region)
+	if err != nil {
+		return err
+	}
+	err = awsutil.DeleteBucket(ctx, svc, c.Name)
+	if err != nil && clouderr.Classify(err) != clouderr.KindNotFound {
+		return err
+	}
+	ledger.Release(ledger.Resource{Provider: ledger.AWS, Kind: ledger.KindBucket, ID: c.Name, Region: c.Region})
+	return nil
+}
@@ -0,0 +1,75 @@
+package janitor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"citihub.com/compliance-as-code/internal/azureutil/resource"
+	"citihub.com/compliance-as-code/internal/clouderr"
+	"citihub.com/compliance-as-code/internal/ledger"
+	"github.com/Azure/go-autorest/autorest/to"
+)
+
+func findAzure(ctx context.Context, o Options) ([]Candidate, error) {
+	list, err := resource.ListGroups(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("cannot list resource groups: %v", err)
+	}
+
+	// created is read once, when a group has no expires-at tag
+	var created map[string]time.Time
+	var found []Candidate
+	for ; list.NotDone(); err = list.Next() {
+		if err != nil {
+			return nil, fmt.Errorf("cannot list resource groups: %v", err)
+		}
+		g := list.Value()
+		if g.Properties != nil && strings.EqualFold(to.String(g.Properties.ProvisioningState), "Deleting") {
+			continue
+		}
+		c := Candidate{
+			Provider: ledger.Azure,
+			Kind:     ledger.KindResourceGroup,
+			Name:     to.String(g.Name),
+			Region:   to.String(g.Location),
+			Tags:     map[string]string{},
+		}
+		for k, v := range g.Tags {
+			c.Tags[k] = to.String(v)
+		}
+		if ok, _ := o.match(&c, nil); !ok {
+			continue
+		}
+		if c.Expires.IsZero() {
+			if created == nil {
+				var cerr error
+				if created, cerr = resource.GroupsCreated(ctx); cerr != nil {
+					log.Printf("[WARN] Cannot tell the age of resource groups: %v", cerr)
+					created = map[string]time.Time{}
+				}
+			}
+			c.Created = created[strings.ToLower(c.Name)]
+		}
+		found = append(found, c)
+	}
+	// The last page may fail too, ending the loop
+	if err != nil {
+		return nil, fmt.Errorf("cannot list resource groups: %v", err)
+	}
+	return found, nil
+}
+
+// removeAzure deletes a resource group, with its resources.
+func removeAzure(ctx context.Context, c Candidate) error {
+	if c.Kind != ledger.KindResourceGroup {
+		return fmt.Errorf("cannot delete resources of kind %s of %s", c.Kind, c.Provider)
+	}
+	err := resource.DeleteGroupAndWait(ctx, c.Name)
+	if err != nil && clouderr.Classify(err) != clouderr.KindNotFound {
+		return err
+	}
+	return nil
+}
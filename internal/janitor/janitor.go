@@ -0,0 +1,209 @@
+// Package janitor finds the resources that the suites left behind in the shared accounts and subscriptions, by their tags
+// (see internal/tagging) or the names the suites give them, and deletes those that expired or, without an expiry, are old
+// enough not to belong to a run in progress.
+package janitor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"citihub.com/compliance-as-code/internal/ledger"
+	"citihub.com/compliance-as-code/internal/tagging"
+)
+
+// patterns are the names the suites give the resources they create, by kind.
+var patterns = map[string]*regexp.Regexp{
+	// e.g. "testabcdeunencbucket", see the encryption suites of Object Storage
+	ledger.KindBucket: regexp.MustCompile(`^test[a-z]{5}unencbucket$`),
+	// e.g. "testabcdefresourceGP", see azureutil.ResourceGroup
+	ledger.KindResourceGroup: regexp.MustCompile(`^test[a-z]{6}resourceGP$`),
+}
+
+// Candidate is a resource left behind by the suites.
+type Candidate struct {
+	Provider string `json:"provider"`
+	Kind     string `json:"kind"`
+	Name     string `json:"name"`
+	Region   string `json:"region,omitempty"`
+	// Created is when the resource was created, zero when it is not known.
+	Created time.Time `json:"created,omitempty"`
+	// Expires is when the resource may be deleted, from its expires-at tag, zero when it has none.
+	Expires time.Time         `json:"expires,omitempty"`
+	Tags    map[string]string `json:"tags,omitempty"`
+	// Reason is why the resource is taken for one left behind by the suites.
+	Reason string `json:"reason"`
+}
+
+func (c Candidate) String() string {
+	age := "age unknown"
+	switch {
+	case !c.Expires.IsZero():
+		age = "expires " + c.Expires.UTC().Format(time.RFC3339)
+	case !c.Created.IsZero():
+		age = "created " + c.Created.UTC().Format(time.RFC3339)
+	}
+	where := ""
+	if c.Region != "" {
+		where = " in " + c.Region
+	}
+	return fmt.Sprintf("%s %s %s%s, %s, %s", c.Provider, c.Kind, c.Name, where, age, c.Reason)
+}
+
+// Options select the resources to delete.
+type Options struct {
+	// OlderThan is the age below which the resources without an expiry are kept, as they may belong to a run in progress.
+	OlderThan time.Duration
+	// Tags, when set, select the resources of the suites that have all of them, e.g. the run ID of a run, rather than all
+	// of them.
+	Tags map[string]string
+	// UnknownAge selects the resources whose age is not known, which are kept otherwise.
+	UnknownAge bool
+	// Now is the time the age of the resources is measured at, the current time if zero.
+	Now time.Time
+}
+
+// match tells whether a resource is selected and sets why: the resources of the suites, tagged with a run ID in the
+// namespace of the suites or named as the suites name them, and when tags are selected, those that have all of them. The
+// selected tags alone do not make a resource one of the suites, as other teams may tag theirs alike. tags returns the
+// tags of the resource when they are not set.
+func (o Options) match(c *Candidate, tags func() (map[string]string, error)) (bool, error) {
+	if c.Tags == nil {
+		t, err := tags()
+		if err != nil {
+			return false, err
+		}
+		c.Tags = t
+	}
+	c.Expires = tagging.Expires(c.Tags)
+
+	if id, ok := c.Tags[tagging.KeyRunID]; ok {
+		c.Reason = "tagged " + tagging.KeyRunID + "=" + id
+	} else if p, ok := patterns[c.Kind]; ok && p.MatchString(c.Name) {
+		c.Reason = "named " + p.String()
+	} else {
+		return false, nil
+	}
+	if len(o.Tags) == 0 {
+		return true, nil
+	}
+	var selected []string
+	for k, v := range o.Tags {
+		if c.Tags[k] != v {
+			return false, nil
+		}
+		if k != tagging.KeyRunID {
+			selected = append(selected, k+"="+v)
+		}
+	}
+	if len(selected) > 0 {
+		sort.Strings(selected)
+		c.Reason += ", tagged " + strings.Join(selected, ",")
+	}
+	return true, nil
+}
+
+// old tells whether a resource may be deleted: when it expired or, without an expiry, is old enough.
+func (o Options) old(c Candidate) bool {
+	now := o.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	switch {
+	case !c.Expires.IsZero():
+		return !now.Before(c.Expires)
+	case c.Created.IsZero():
+		return o.UnknownAge
+	}
+	return now.Sub(c.Created) >= o.OlderThan
+}
+
+// provider finds and deletes the resources of a provider.
+type provider struct {
+	// find lists the resources that match the options, whatever their age, with their creation time when it is known.
+	find func(ctx context.Context, o Options) ([]Candidate, error)
+	// remove deletes a resource and waits for the deletion to complete.
+	remove func(ctx context.Context, c Candidate) error
+}
+
+var providers = map[string]provider{
+	ledger.AWS:   {find: findAWS, remove: removeAWS},
+	ledger.Azure: {find: findAzure, remove: removeAzure},
+}
+
+// Find returns the resources of the provider, as named by the CSP environment variable, that the options select, oldest
+// first.
+func Find(ctx context.Context, name string, o Options) ([]Candidate, error) {
+	p, ok := providers[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("no janitor for provider '%s'", name)
+	}
+	found, err := p.find(ctx, o)
+	if err != nil {
+		return nil, err
+	}
+	var candidates []Candidate
+	for _, c := range found {
+		switch {
+		case o.old(c):
+			candidates = append(candidates, c)
+		case !c.Expires.IsZero():
+			log.Printf("[DEBUG] Keeping %v, not expired", c)
+		case c.Created.IsZero():
+			log.Printf("[DEBUG] Keeping %v", c)
+		default:
+			log.Printf("[DEBUG] Keeping %v, too recent", c)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Created.Before(candidates[j].Created)
+	})
+	return candidates, nil
+}
+
+// Result is the outcome of the deletion of a resource.
+type Result struct {
+	Candidate
+	Err error
+}
+
+// Delete deletes the resources with at most workers deletions at a time, and returns the outcome of each in the order of
+// the resources. Resources that no longer exist are deleted.
+func Delete(ctx context.Context, candidates []Candidate, workers int) []Result {
+	if workers < 1 {
+		workers = 1
+	}
+	results := make([]Result, len(candidates))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				c := candidates[i]
+				log.Printf("[DEBUG] Deleting %v", c)
+				results[i] = Result{Candidate: c, Err: remove(ctx, c)}
+			}
+		}()
+	}
+	for i := range candidates {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return results
+}
+
+func remove(ctx context.Context, c Candidate) error {
+	p, ok := providers[c.Provider]
+	if !ok {
+		return fmt.Errorf("no janitor for provider '%s'", c.Provider)
+	}
+	return p.remove(ctx, c)
+}
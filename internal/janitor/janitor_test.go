@@ -0,0 +1,180 @@
+package janitor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"citihub.com/compliance-as-code/internal/ledger"
+	"citihub.com/compliance-as-code/internal/tagging"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name       string
+		kind       string
+		resource   string
+		tags       map[string]string
+		selected   map[string]string
+		want       bool
+		wantReason string
+	}{
+		{
+			name: "namespaced run ID", kind: ledger.KindBucket, resource: "audit-logs",
+			tags: map[string]string{tagging.KeyRunID: "run1"},
+			want: true, wantReason: "tagged " + tagging.KeyRunID + "=run1",
+		},
+		{
+			name: "run ID of another tool", kind: ledger.KindBucket, resource: "audit-logs",
+			tags: map[string]string{"run-id": "run1"},
+		},
+		{
+			name: "named as the suites name it", kind: ledger.KindBucket, resource: "testabcdeunencbucket",
+			want: true, wantReason: "named " + patterns[ledger.KindBucket].String(),
+		},
+		{
+			name: "named as the suites name another kind", kind: ledger.KindBucket, resource: "testabcdefresourceGP",
+		},
+		{
+			name: "resource group named as the suites name it", kind: ledger.KindResourceGroup, resource: "testabcdefresourceGP",
+			want: true, wantReason: "named " + patterns[ledger.KindResourceGroup].String(),
+		},
+		{
+			name: "selected run ID", kind: ledger.KindBucket, resource: "testabcdeunencbucket",
+			tags:     map[string]string{tagging.KeyRunID: "run1"},
+			selected: map[string]string{tagging.KeyRunID: "run1"},
+			want:     true, wantReason: "tagged " + tagging.KeyRunID + "=run1",
+		},
+		{
+			name: "other run ID", kind: ledger.KindBucket, resource: "testabcdeunencbucket",
+			tags:     map[string]string{tagging.KeyRunID: "run2"},
+			selected: map[string]string{tagging.KeyRunID: "run1"},
+		},
+		{
+			name: "selected tag on a resource of the suites", kind: ledger.KindBucket, resource: "testabcdeunencbucket",
+			tags:     map[string]string{"team": "security"},
+			selected: map[string]string{"team": "security"},
+			want:     true, wantReason: "named " + patterns[ledger.KindBucket].String() + ", tagged team=security",
+		},
+		{
+			name: "selected tag on a foreign resource", kind: ledger.KindBucket, resource: "audit-logs",
+			tags:     map[string]string{"team": "security"},
+			selected: map[string]string{"team": "security"},
+		},
+		{
+			name: "selected run ID on a foreign resource", kind: ledger.KindBucket, resource: "audit-logs",
+			tags:     map[string]string{"run-id": "run1"},
+			selected: map[string]string{"run-id": "run1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tags := tt.tags
+			if tags == nil {
+				tags = map[string]string{}
+			}
+			c := Candidate{Provider: ledger.AWS, Kind: tt.kind, Name: tt.resource}
+			got, err := Options{Tags: tt.selected}.match(&c, func() (map[string]string, error) { return tags, nil })
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("match() = %v, want %v", got, tt.want)
+			}
+			if got && c.Reason != tt.wantReason {
+				t.Errorf("Reason = %q, want %q", c.Reason, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestMatchReadsTagsOnce(t *testing.T) {
+	c := Candidate{Provider: ledger.AWS, Kind: ledger.KindBucket, Name: "audit-logs", Tags: map[string]string{tagging.KeyRunID: "run1"}}
+	got, err := Options{}.match(&c, func() (map[string]string, error) { return nil, errors.New("tags read again") })
+	if err != nil || !got {
+		t.Errorf("match() = %v, %v, want true, nil", got, err)
+	}
+
+	c = Candidate{Provider: ledger.AWS, Kind: ledger.KindBucket, Name: "testabcdeunencbucket"}
+	if _, err := (Options{}).match(&c, func() (map[string]string, error) { return nil, errors.New("access denied") }); err == nil {
+		t.Error("match() did not return the error reading the tags")
+	}
+}
+
+func TestOld(t *testing.T) {
+	now := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		created time.Time
+		expires time.Time
+		unknown bool
+		want    bool
+	}{
+		{name: "expired", expires: now.Add(-time.Minute), want: true},
+		{name: "expires now", expires: now, want: true},
+		{name: "not expired", expires: now.Add(time.Minute)},
+		{name: "not expired though old", created: now.Add(-48 * time.Hour), expires: now.Add(time.Minute)},
+		{name: "expired though recent", created: now.Add(-time.Minute), expires: now.Add(-time.Second), want: true},
+		{name: "old", created: now.Add(-48 * time.Hour), want: true},
+		{name: "recent", created: now.Add(-time.Hour)},
+		{name: "unknown age", unknown: false},
+		{name: "unknown age selected", unknown: true, want: true},
+		{name: "recent with unknown age selected", created: now.Add(-time.Hour), unknown: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := Options{OlderThan: 24 * time.Hour, UnknownAge: tt.unknown, Now: now}
+			if got := o.old(Candidate{Created: tt.created, Expires: tt.expires}); got != tt.want {
+				t.Errorf("old() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFind(t *testing.T) {
+	now := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	found := []Candidate{
+		{Name: "recent", Created: now.Add(-time.Hour)},
+		{Name: "old", Created: now.Add(-72 * time.Hour)},
+		{Name: "unknown"},
+		{Name: "expired", Created: now.Add(-time.Minute), Expires: now.Add(-time.Second)},
+		{Name: "not-expired", Created: now.Add(-96 * time.Hour), Expires: now.Add(time.Hour)},
+		{Name: "older", Created: now.Add(-96 * time.Hour)},
+	}
+	defer func(p provider) { providers[ledger.AWS] = p }(providers[ledger.AWS])
+	providers[ledger.AWS] = provider{find: func(ctx context.Context, o Options) ([]Candidate, error) { return found, nil }}
+
+	tests := []struct {
+		name    string
+		unknown bool
+		want    []string
+	}{
+		{name: "known age", want: []string{"older", "old", "expired"}},
+		{name: "unknown age selected", unknown: true, want: []string{"unknown", "older", "old", "expired"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Find(context.Background(), "AWS", Options{OlderThan: 24 * time.Hour, UnknownAge: tt.unknown, Now: now})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, c := range got {
+				names = append(names, c.Name)
+			}
+			if len(names) != len(tt.want) {
+				t.Fatalf("Find() = %v, want %v", names, tt.want)
+			}
+			for i := range names {
+				if names[i] != tt.want[i] {
+					t.Fatalf("Find() = %v, want %v", names, tt.want)
+				}
+			}
+		})
+	}
+
+	if _, err := Find(context.Background(), "gcp", Options{}); err == nil {
+		t.Error("Find() found the resources of a provider without a janitor")
+	}
+}
@@ -0,0 +1,248 @@
+// Package ledger journals the cloud resources that the suites create, on disk and before creating them, so that the
+// resources left behind by a run that panicked or was killed, e.g. by one of the log.Fatalf in a step, can be deleted by
+// cmd/recover rather than depend on the in-memory state of the run. Each entry carries the run that wrote it (see
+// tagging.RunID) and its process, so that cmd/recover tells the resources of the runs that ended from those of the runs
+// still in progress sharing the journal.
+package ledger
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"citihub.com/compliance-as-code/internal/tagging"
+)
+
+// EnvVar is the environment variable naming the journal. When it is not set, the journal is DefaultFile in the temporary
+// directory, which every suite of a run, and cmd/recover, then share whatever their working directory.
+const EnvVar = "LEDGER_FILE"
+
+// DefaultFile is the name of the journal in the temporary directory.
+const DefaultFile = "compliance-as-code-ledger.jsonl"
+
+// Providers of the resources, as named by the CSP environment variable.
+const (
+	AWS   = "aws"
+	Azure = "azure"
+)
+
+// Kinds of resources.
+const (
+	KindBucket               = "bucket"
+	KindResourceGroup        = "resource-group"
+	KindStorageAccount       = "storage-account"
+	KindSQLServer            = "sql-server"
+	KindVirtualNetwork       = "virtual-network"
+	KindNetworkSecurityGroup = "network-security-group"
+	KindNetworkInterface     = "network-interface"
+	KindPublicIPAddress      = "public-ip-address"
+)
+
+// Operations of the entries of the journal.
+const (
+	OpCreate = "create"
+	OpDelete = "delete"
+)
+
+// Resource is a resource created by the suites.
+type Resource struct {
+	Provider string `json:"provider"`
+	Kind     string `json:"kind"`
+	// ID identifies the resource in the provider: the ARM ID of an Azure resource (see AzureID) and the name of a bucket.
+	ID string `json:"id"`
+	// Region is the region of an AWS resource, whose ID does not give it.
+	Region string `json:"region,omitempty"`
+}
+
+func (r Resource) String() string {
+	if r.Region != "" {
+		return fmt.Sprintf("%s %s %s in %s", r.Provider, r.Kind, r.ID, r.Region)
+	}
+	return fmt.Sprintf("%s %s %s", r.Provider, r.Kind, r.ID)
+}
+
+// Entry is a line of the journal.
+type Entry struct {
+	Time time.Time `json:"time"`
+	Op   string    `json:"op"`
+	// RunID is the ID of the run that wrote the entry, and Host and PID its process. They are empty in the entries written
+	// before runs were journaled.
+	RunID string `json:"run_id,omitempty"`
+	Host  string `json:"host,omitempty"`
+	PID   int    `json:"pid,omitempty"`
+	Resource
+}
+
+// Alive tells whether the process that wrote the entry is still running, as far as can be told: the process of another
+// host is taken for running, and that of an entry without a process for ended.
+func (e Entry) Alive() bool {
+	if e.PID == 0 {
+		return false
+	}
+	if !e.Local() {
+		return true
+	}
+	return alive(e.PID)
+}
+
+// Local tells whether the entry was written on this host, e.g. not by a run sharing the journal on a network drive.
+func (e Entry) Local() bool {
+	return e.Host == hostname()
+}
+
+func hostname() string {
+	h, _ := os.Hostname()
+	return h
+}
+
+// AzureID returns the ARM ID of the resource of the type, e.g. "Microsoft.Storage/storageAccounts", in the resource group,
+// or of the resource group itself when resourceType is empty.
+func AzureID(subscription, group, resourceType, name string) string {
+	id := fmt.Sprintf("/subscriptions/%s/resourceGroups/%s", subscription, group)
+	if resourceType == "" {
+		return id
+	}
+	return fmt.Sprintf("%s/providers/%s/%s", id, resourceType, name)
+}
+
+// Path returns the path of the journal.
+func Path() string {
+	if path := os.Getenv(EnvVar); path != "" {
+		return path
+	}
+	return filepath.Join(os.TempDir(), DefaultFile)
+}
+
+var mu sync.Mutex
+
+// Record journals a resource that is about to be created. It must be called, and succeed, before the resource is
+// created, so that the resource is journaled however the run ends.
+func Record(r Resource) error {
+	return write(Entry{Time: time.Now().UTC(), Op: OpCreate, Resource: r})
+}
+
+// Release journals that a resource was deleted. It must only be called once the deletion completed, as a resource whose
+// deletion failed after it was accepted is otherwise left out of cmd/recover. It logs rather than returns an error, as a
+// resource that is not released is only deleted again by cmd/recover.
+func Release(r Resource) {
+	if err := write(Entry{Time: time.Now().UTC(), Op: OpDelete, Resource: r}); err != nil {
+		log.Printf("[ERROR] Cannot release %v: %v", r, err)
+	}
+}
+
+func write(e Entry) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	e.RunID, e.Host, e.PID = tagging.RunID(), hostname(), os.Getpid()
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	path := Path()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("cannot open ledger %v: %v", path, err)
+	}
+	// The processes of concurrent runs append to the same journal
+	if err := lock(f); err != nil {
+		f.Close()
+		return fmt.Errorf("cannot lock ledger %v: %v", path, err)
+	}
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("cannot write to ledger %v: %v", path, err)
+	}
+	// The entry must be on disk before the resource is created
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("cannot write to ledger %v: %v", path, err)
+	}
+	return f.Close()
+}
+
+// Pending replays a journal and returns the entries of the resources that were created and not deleted, whichever run
+// deleted them, most recently created first so that they can be deleted in that order. Azure resources created in a
+// resource group before it was deleted are not pending, as deleting a resource group deletes its resources, unlike those
+// created in it once it was created again.
+func Pending(path string) ([]Entry, error) {
+	entries, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var created []Entry
+	deleted := map[Resource]bool{}
+	for _, e := range entries {
+		switch e.Op {
+		case OpCreate:
+			created = append(created, e)
+			delete(deleted, e.Resource)
+		case OpDelete:
+			deleted[e.Resource] = true
+			if e.Provider == Azure && e.Kind == KindResourceGroup {
+				for _, c := range created {
+					if inGroup(c.Resource, e.ID) {
+						deleted[c.Resource] = true
+					}
+				}
+			}
+		}
+	}
+
+	var pending []Entry
+	seen := map[Resource]bool{}
+	for i := len(created) - 1; i >= 0; i-- {
+		e := created[i]
+		if deleted[e.Resource] || seen[e.Resource] {
+			continue
+		}
+		seen[e.Resource] = true
+		pending = append(pending, e)
+	}
+	return pending, nil
+}
+
+// inGroup tells whether a resource is an Azure resource in the resource group of the ARM ID.
+func inGroup(r Resource, group string) bool {
+	return r.Provider == Azure && strings.HasPrefix(strings.ToLower(r.ID), strings.ToLower(group)+"/")
+}
+
+// Load reads the entries of a journal, in the order they were written. A journal that does not exist has no entries.
+func Load(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []Entry
+	s := bufio.NewScanner(f)
+	for line := 1; s.Scan(); line++ {
+		text := strings.TrimSpace(s.Text())
+		if text == "" {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal([]byte(text), &e); err != nil {
+			// A run killed while writing an entry, before creating its resource, leaves it incomplete and without a
+			// line break, so the first entry of the next run follows it on the same line
+			i := strings.LastIndex(text, `{"time":`)
+			if i <= 0 || json.Unmarshal([]byte(text[i:]), &e) != nil {
+				log.Printf("[WARN] Ignoring line %d of ledger %v: %v", line, path, err)
+				continue
+			}
+		}
+		entries = append(entries, e)
+	}
+	return entries, s.Err()
+}
@@ -0,0 +1,187 @@
+package ledger
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// journal sets LEDGER_FILE to a journal in a temporary directory for the duration of the test and returns its path.
+func journal(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), DefaultFile)
+	prev, set := os.LookupEnv(EnvVar)
+	os.Setenv(EnvVar, path)
+	t.Cleanup(func() {
+		if set {
+			os.Setenv(EnvVar, prev)
+		} else {
+			os.Unsetenv(EnvVar)
+		}
+	})
+	return path
+}
+
+func record(t *testing.T, r Resource) {
+	t.Helper()
+	if err := Record(r); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func pendingIDs(t *testing.T, path string) []string {
+	t.Helper()
+	pending, err := Pending(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, e := range pending {
+		if e.Op != OpCreate {
+			t.Errorf("pending entry of %v is a %s", e.Resource, e.Op)
+		}
+		ids = append(ids, e.ID)
+	}
+	return ids
+}
+
+func assertIDs(t *testing.T, got []string, want ...string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("pending %v, want %v", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("pending %v, want %v", got, want)
+		}
+	}
+}
+
+func TestPending(t *testing.T) {
+	group := AzureID("sub", "testabcdefresourceGP", "", "")
+	account := AzureID("sub", "testabcdefresourceGP", "Microsoft.Storage/storageAccounts", "testaccount")
+	other := AzureID("sub", "testghijklresourceGP", "Microsoft.Storage/storageAccounts", "testaccount")
+	bucket := Resource{Provider: AWS, Kind: KindBucket, ID: "testabcdeunencbucket", Region: "eu-west-2"}
+	rg := Resource{Provider: Azure, Kind: KindResourceGroup, ID: group}
+	sa := Resource{Provider: Azure, Kind: KindStorageAccount, ID: account}
+
+	t.Run("created", func(t *testing.T) {
+		path := journal(t)
+		record(t, bucket)
+		record(t, rg)
+		record(t, sa)
+		assertIDs(t, pendingIDs(t, path), account, group, bucket.ID)
+	})
+
+	t.Run("deleted", func(t *testing.T) {
+		path := journal(t)
+		record(t, bucket)
+		Release(bucket)
+		assertIDs(t, pendingIDs(t, path))
+	})
+
+	t.Run("created again", func(t *testing.T) {
+		path := journal(t)
+		record(t, bucket)
+		Release(bucket)
+		record(t, bucket)
+		assertIDs(t, pendingIDs(t, path), bucket.ID)
+	})
+
+	t.Run("created twice", func(t *testing.T) {
+		path := journal(t)
+		record(t, bucket)
+		record(t, bucket)
+		assertIDs(t, pendingIDs(t, path), bucket.ID)
+	})
+
+	t.Run("bucket of the same name in another region", func(t *testing.T) {
+		path := journal(t)
+		record(t, bucket)
+		moved := bucket
+		moved.Region = "us-east-1"
+		Release(moved)
+		assertIDs(t, pendingIDs(t, path), bucket.ID)
+	})
+
+	t.Run("in a deleted resource group", func(t *testing.T) {
+		path := journal(t)
+		record(t, rg)
+		record(t, sa)
+		record(t, Resource{Provider: Azure, Kind: KindStorageAccount, ID: other})
+		Release(rg)
+		assertIDs(t, pendingIDs(t, path), other)
+	})
+
+	t.Run("resource group name is a prefix", func(t *testing.T) {
+		path := journal(t)
+		longer := AzureID("sub", "testabcdefresourceGP2", "Microsoft.Storage/storageAccounts", "testaccount")
+		record(t, rg)
+		record(t, Resource{Provider: Azure, Kind: KindStorageAccount, ID: longer})
+		Release(rg)
+		assertIDs(t, pendingIDs(t, path), longer)
+	})
+
+	t.Run("in a resource group created again", func(t *testing.T) {
+		path := journal(t)
+		record(t, rg)
+		record(t, sa)
+		Release(rg)
+		record(t, rg)
+		sql := AzureID("sub", "testabcdefresourceGP", "Microsoft.Sql/servers", "testsql")
+		record(t, Resource{Provider: Azure, Kind: KindSQLServer, ID: sql})
+		assertIDs(t, pendingIDs(t, path), sql, group)
+	})
+
+	t.Run("no journal", func(t *testing.T) {
+		path := journal(t)
+		assertIDs(t, pendingIDs(t, path))
+	})
+}
+
+func TestLoadTornLine(t *testing.T) {
+	path := journal(t)
+	first := Resource{Provider: AWS, Kind: KindBucket, ID: "testabcdeunencbucket", Region: "eu-west-2"}
+	record(t, first)
+
+	// A run killed while writing an entry leaves it without a line break
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString(`{"time":"2020-06-01T12:00:00Z","op":"create","provider":"aws","kind":"bucket","id":"testfghijunenc`); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Resource != first {
+		t.Fatalf("Load() = %v, want the entry of %v", entries, first)
+	}
+
+	// The first entry of the next run follows it on the same line
+	next := Resource{Provider: AWS, Kind: KindBucket, ID: "testklmnounencbucket", Region: "eu-west-2"}
+	record(t, next)
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(string(b), "\n"); lines != 2 {
+		t.Fatalf("journal has %d lines, want 2:\n%s", lines, b)
+	}
+
+	entries, err = Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[1].Resource != next || entries[1].RunID == "" || entries[1].PID != os.Getpid() {
+		t.Fatalf("Load() = %v, want the entries of %v and %v", entries, first, next)
+	}
+	assertIDs(t, pendingIDs(t, path), next.ID, first.ID)
+}
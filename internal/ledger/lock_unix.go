@@ -0,0 +1,21 @@
+//go:build !windows
+// +build !windows
+
+package ledger
+
+import (
+	"os"
+	"syscall"
+)
+
+// lock locks the journal for writing until it is closed.
+func lock(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_EX)
+}
+
+// alive tells whether a process of this host is running.
+func alive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	// EPERM: it runs as another user
+	return err == nil || err == syscall.EPERM
+}
@@ -0,0 +1,20 @@
+package ledger
+
+import (
+	"os"
+)
+
+// lock does nothing, as Windows appends each write to a file opened for appending in one piece.
+func lock(f *os.File) error {
+	return nil
+}
+
+// alive tells whether a process of this host is running: Windows only finds the processes that are.
+func alive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	p.Release()
+	return true
+}
@@ -0,0 +1,252 @@
+package oscal
+
+import (
+	"crypto/rand"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"citihub.com/compliance-as-code/internal/controls"
+	"citihub.com/compliance-as-code/internal/csp"
+	"citihub.com/compliance-as-code/internal/evidence"
+	"citihub.com/compliance-as-code/internal/featurelint"
+	"citihub.com/compliance-as-code/internal/guardrail"
+	"citihub.com/compliance-as-code/internal/results"
+)
+
+// Finding target types and states.
+const (
+	TargetObjective   = "objective-id"
+	StateSatisfied    = "satisfied"
+	StateNotSatisfied = "not-satisfied"
+)
+
+// Options describe the document to export.
+type Options struct {
+	Title string
+	// Version is the version of the document, e.g. the build that ran the suites.
+	Version string
+	// Catalog gives the titles of the controls, and CatalogHref its location, e.g. "catalog/controls.yaml".
+	Catalog     *controls.Catalog
+	CatalogHref string
+	// AssessmentPlanHref is the location of the OSCAL Assessment Plan document that the suites were run for, which the
+	// results import.
+	AssessmentPlanHref string
+	// Now is when the document is exported, the current time if zero.
+	Now time.Time
+}
+
+// Export turns the results of runs into an Assessment Results document with a result per run. Each scenario becomes an
+// observation, with the outcome of each of its steps as evidence and the resources recorded in its evidence as subjects,
+// and a finding for each control objective of its @CCO: tags when it passed or failed. Scenarios that are not applicable
+// or were skipped, including by the guardrail, assessed nothing, so they have no findings; the decision of the guardrail
+// that skipped a scenario is evidence of its observation.
+// The evidence of a scenario is the one recorded for the same provider, feature and name, in the order they were run.
+// It tells when the scenario was run, so the export fails when a scenario has none rather than make a time up.
+func Export(opts Options, runs []results.Run, recorded []evidence.Scenario) (Document, error) {
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+
+	catalog := Resource{UUID: newUUID(), Title: "Common control objectives", Description: "The control catalog that the @CCO: tags of the features reference."}
+	if opts.CatalogHref != "" {
+		catalog.RLinks = []RLink{{Href: opts.CatalogHref}}
+	}
+
+	ar := AssessmentResults{
+		UUID: newUUID(),
+		Metadata: Metadata{
+			Title:        opts.Title,
+			LastModified: timestamp(opts.Now),
+			Version:      opts.Version,
+			OSCALVersion: Version,
+		},
+		ImportAP:   ImportAP{Href: opts.AssessmentPlanHref},
+		BackMatter: &BackMatter{Resources: []Resource{catalog}},
+	}
+
+	ev := map[string][]evidence.Scenario{}
+	for _, s := range recorded {
+		k := results.EvidenceKey(s.Provider, s.URI, s.Name)
+		ev[k] = append(ev[k], s)
+	}
+	for _, r := range runs {
+		res, err := export(opts, r, ev)
+		if err != nil {
+			return Document{}, err
+		}
+		ar.Results = append(ar.Results, res)
+	}
+	return Document{AssessmentResults: ar}, nil
+}
+
+func export(opts Options, r results.Run, ev map[string][]evidence.Scenario) (Result, error) {
+	name := csp.DisplayName(r.Provider)
+	res := Result{
+		UUID:        newUUID(),
+		Title:       fmt.Sprintf("Compliance as code results on %s", name),
+		Description: fmt.Sprintf("Results of the Gherkin features run against %s.", name),
+		Props:       []Property{prop("csp", r.Provider)},
+	}
+
+	var start, end time.Time
+	items := map[evidence.Resource]string{}
+	reviewed := map[string]bool{}
+	for _, f := range r.Features {
+		for _, s := range f.Elements {
+			if s.Type != "" && s.Type != "scenario" {
+				continue
+			}
+
+			// Take the evidence of this run of the scenario, the runs of a Scenario Outline share its name
+			k := results.EvidenceKey(r.Provider, f.URI, s.Name)
+			e := ev[k]
+			if len(e) == 0 {
+				return Result{}, fmt.Errorf("no evidence of when scenario '%s' of feature '%s' was run on %s", s.Name, f.URI, name)
+			}
+			ev[k] = e[1:]
+			collected, resources := e[0].End, e[0].Resources
+			if s.Guardrail == nil {
+				s.Guardrail = e[0].Guardrail
+			}
+			if start.IsZero() || e[0].Start.Before(start) {
+				start = e[0].Start
+			}
+			if e[0].End.After(end) {
+				end = e[0].End
+			}
+
+			outcome := s.Outcome()
+			o := Observation{
+				UUID:        newUUID(),
+				Title:       s.Name,
+				Description: fmt.Sprintf("Scenario '%s' of feature '%s' on %s: %s.", s.Name, f.Name, name, outcome),
+				Props:       []Property{prop("csp", r.Provider), prop("outcome", outcome), prop("feature", f.URI)},
+				Methods:     []string{"TEST"},
+				Collected:   timestamp(collected),
+			}
+			for _, st := range s.Steps {
+				d := fmt.Sprintf("%s%s: %s", st.Keyword, st.Name, st.Result.Status)
+				if st.Result.ErrorMessage != "" {
+					d += ": " + st.Result.ErrorMessage
+				}
+				o.RelevantEvidence = append(o.RelevantEvidence, RelevantEvidence{Description: d})
+			}
+			if d := s.Guardrail; d != nil {
+				o.RelevantEvidence = append(o.RelevantEvidence, guardrailEvidence(*d))
+			}
+			for _, rs := range resources {
+				id, ok := items[rs]
+				if !ok {
+					id = newUUID()
+					items[rs] = id
+				}
+				o.Subjects = append(o.Subjects, SubjectReference{SubjectUUID: id, Type: "inventory-item"})
+			}
+			res.Observations = append(res.Observations, o)
+
+			for _, cco := range f.TagValues(s, featurelint.ControlTagPrefix) {
+				reviewed[cco] = true
+				if outcome != results.Passed && outcome != results.Failed {
+					continue
+				}
+				state := StateSatisfied
+				if outcome == results.Failed {
+					state = StateNotSatisfied
+				}
+				res.Findings = append(res.Findings, Finding{
+					UUID:                newUUID(),
+					Title:               fmt.Sprintf("%s: %s", cco, s.Name),
+					Description:         fmt.Sprintf("Control objective %s is %s on %s, as scenario '%s' of feature '%s' %s.", cco, strings.Replace(state, "-", " ", 1), name, s.Name, f.Name, outcome),
+					Props:               []Property{prop("csp", r.Provider)},
+					Target:              Target{Type: TargetObjective, TargetID: cco, Title: controlTitle(opts.Catalog, cco), Status: Status{State: state}},
+					RelatedObservations: []RelatedObservation{{ObservationUUID: o.UUID}},
+				})
+			}
+		}
+	}
+
+	if start.IsZero() {
+		return Result{}, fmt.Errorf("no scenario was run on %s", name)
+	}
+	res.Start, res.End = timestamp(start), timestamp(end)
+
+	var ids []string
+	for id := range reviewed {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	sel := ControlSelection{}
+	for _, id := range ids {
+		sel.IncludeControls = append(sel.IncludeControls, ControlID{ControlID: id})
+	}
+	if len(ids) == 0 {
+		sel.Description = "No scenario is tagged with a control."
+	}
+	res.ReviewedControls = ReviewedControls{ControlSelections: []ControlSelection{sel}}
+
+	if len(items) > 0 {
+		res.LocalDefinitions = &LocalDefinitions{}
+		for rs, id := range items {
+			res.LocalDefinitions.InventoryItems = append(res.LocalDefinitions.InventoryItems, InventoryItem{
+				UUID:        id,
+				Description: fmt.Sprintf("%s %s", rs.Kind, rs.ID),
+				Props:       []Property{prop("resource-type", rs.Kind), prop("resource-id", rs.ID), prop("csp", r.Provider)},
+			})
+		}
+		sort.Slice(res.LocalDefinitions.InventoryItems, func(i, j int) bool {
+			return res.LocalDefinitions.InventoryItems[i].Description < res.LocalDefinitions.InventoryItems[j].Description
+		})
+	}
+	return res, nil
+}
+
+// guardrailEvidence returns the decision of the guardrail that stopped a scenario as evidence.
+func guardrailEvidence(d guardrail.Decision) RelevantEvidence {
+	e := RelevantEvidence{
+		Description: fmt.Sprintf("Guardrail: %v.", d),
+		Props: []Property{
+			prop("guardrail-mode", d.Mode),
+			prop("guardrail-protected", strconv.FormatBool(d.Protected)),
+			prop("guardrail-allowed", strconv.FormatBool(d.Allowed)),
+		},
+	}
+	if d.Identity != "" {
+		e.Props = append(e.Props, prop("guardrail-identity", d.Identity))
+	}
+	if d.Config != "" {
+		e.Props = append(e.Props, prop("guardrail-config", d.Config))
+	}
+	return e
+}
+
+func controlTitle(c *controls.Catalog, id string) string {
+	if c == nil {
+		return ""
+	}
+	ctl, _ := c.Control(id)
+	return ctl.Title
+}
+
+func prop(name, value string) Property {
+	return Property{Name: name, NS: Namespace, Value: value}
+}
+
+// timestamp formats a time as an OSCAL date and time with a time zone.
+func timestamp(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// newUUID returns a random (version 4) UUID.
+func newUUID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		log.Panicf("oscal: cannot generate UUID: %v", err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
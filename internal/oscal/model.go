@@ -0,0 +1,156 @@
+// Package oscal exports the results of the suites as an OSCAL Assessment Results document, for auditors who ask for evidence
+// in the Open Security Controls Assessment Language of NIST. Only the assemblies that the export writes are modelled.
+package oscal
+
+// Version is the OSCAL version of the documents.
+const Version = "1.0.0"
+
+// Namespace is the namespace of the properties that are specific to this repository.
+const Namespace = "https://citihub.com/ns/compliance-as-code"
+
+// Document is an OSCAL Assessment Results document.
+type Document struct {
+	AssessmentResults AssessmentResults `json:"assessment-results"`
+}
+
+// AssessmentResults is the root assembly of the document.
+type AssessmentResults struct {
+	UUID       string      `json:"uuid"`
+	Metadata   Metadata    `json:"metadata"`
+	ImportAP   ImportAP    `json:"import-ap"`
+	Results    []Result    `json:"results"`
+	BackMatter *BackMatter `json:"back-matter,omitempty"`
+}
+
+// Metadata describes the document.
+type Metadata struct {
+	Title        string     `json:"title"`
+	LastModified string     `json:"last-modified"`
+	Version      string     `json:"version"`
+	OSCALVersion string     `json:"oscal-version"`
+	Props        []Property `json:"props,omitempty"`
+}
+
+// ImportAP references the assessment plan that the results are for.
+type ImportAP struct {
+	Href string `json:"href"`
+}
+
+// Property is a name and value pair, in Namespace unless it is an OSCAL property.
+type Property struct {
+	Name  string `json:"name"`
+	NS    string `json:"ns,omitempty"`
+	Value string `json:"value"`
+}
+
+// BackMatter holds the resources that the document references.
+type BackMatter struct {
+	Resources []Resource `json:"resources"`
+}
+
+// Resource is a document referenced by the results, e.g. the control catalog.
+type Resource struct {
+	UUID        string  `json:"uuid"`
+	Title       string  `json:"title,omitempty"`
+	Description string  `json:"description,omitempty"`
+	RLinks      []RLink `json:"rlinks,omitempty"`
+}
+
+// RLink is the location of a resource.
+type RLink struct {
+	Href string `json:"href"`
+}
+
+// Result is the results of the suites run against a provider.
+type Result struct {
+	UUID             string            `json:"uuid"`
+	Title            string            `json:"title"`
+	Description      string            `json:"description"`
+	Start            string            `json:"start"`
+	End              string            `json:"end,omitempty"`
+	Props            []Property        `json:"props,omitempty"`
+	LocalDefinitions *LocalDefinitions `json:"local-definitions,omitempty"`
+	ReviewedControls ReviewedControls  `json:"reviewed-controls"`
+	Observations     []Observation     `json:"observations,omitempty"`
+	Findings         []Finding         `json:"findings,omitempty"`
+}
+
+// LocalDefinitions holds the resources that the scenarios of a result inspected or created.
+type LocalDefinitions struct {
+	InventoryItems []InventoryItem `json:"inventory-items"`
+}
+
+// InventoryItem is a cloud resource, e.g. a storage account.
+type InventoryItem struct {
+	UUID        string     `json:"uuid"`
+	Description string     `json:"description"`
+	Props       []Property `json:"props,omitempty"`
+}
+
+// ReviewedControls are the controls that a result assessed.
+type ReviewedControls struct {
+	ControlSelections []ControlSelection `json:"control-selections"`
+}
+
+// ControlSelection selects controls by ID.
+type ControlSelection struct {
+	Description     string      `json:"description,omitempty"`
+	IncludeControls []ControlID `json:"include-controls,omitempty"`
+}
+
+// ControlID identifies a control.
+type ControlID struct {
+	ControlID string `json:"control-id"`
+}
+
+// Observation is what a scenario observed.
+type Observation struct {
+	UUID             string             `json:"uuid"`
+	Title            string             `json:"title,omitempty"`
+	Description      string             `json:"description"`
+	Props            []Property         `json:"props,omitempty"`
+	Methods          []string           `json:"methods"`
+	Subjects         []SubjectReference `json:"subjects,omitempty"`
+	RelevantEvidence []RelevantEvidence `json:"relevant-evidence,omitempty"`
+	Collected        string             `json:"collected"`
+}
+
+// SubjectReference references a subject of an observation, e.g. an inventory item.
+type SubjectReference struct {
+	SubjectUUID string `json:"subject-uuid"`
+	Type        string `json:"type"`
+}
+
+// RelevantEvidence is evidence supporting an observation, e.g. the result of a step.
+type RelevantEvidence struct {
+	Description string     `json:"description"`
+	Props       []Property `json:"props,omitempty"`
+}
+
+// Finding is whether a control objective is satisfied.
+type Finding struct {
+	UUID                string               `json:"uuid"`
+	Title               string               `json:"title"`
+	Description         string               `json:"description"`
+	Props               []Property           `json:"props,omitempty"`
+	Target              Target               `json:"target"`
+	RelatedObservations []RelatedObservation `json:"related-observations,omitempty"`
+}
+
+// Target is the control objective of a finding.
+type Target struct {
+	Type     string `json:"type"`
+	TargetID string `json:"target-id"`
+	Title    string `json:"title,omitempty"`
+	Status   Status `json:"status"`
+}
+
+// Status is whether the target of a finding is satisfied.
+type Status struct {
+	State string `json:"state"`
+}
+
+// RelatedObservation references the observation that a finding is based on.
+type RelatedObservation struct {
+	ObservationUUID string `json:"observation-uuid"`
+}
@@ -0,0 +1,47 @@
+package oscal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// SchemaURL is where NIST publishes the JSON schema of the Assessment Results of Version. The schema is vendored in
+// SchemaFile with cmd/schemas so that documents are validated against it offline.
+const SchemaURL = "https://raw.githubusercontent.com/usnistgov/OSCAL/v" + Version + "/json/schema/oscal_assessment-results_schema.json"
+
+// SchemaFile is the path of the vendored NIST schema, relative to the root of the repository.
+var SchemaFile = filepath.Join("catalog", "nist", "oscal-"+Version, "oscal_assessment-results_schema.json")
+
+// Validate validates a JSON document against the Assessment Results schema in schemaFile, which is kept in the
+// repository so that validation runs offline: the NIST schema vendored in SchemaFile, or the schema of the repository,
+// which only covers what Export writes, see catalog/README.md.
+func Validate(schemaFile string, doc []byte) error {
+	abs, err := filepath.Abs(schemaFile)
+	if err != nil {
+		return fmt.Errorf("cannot resolve path of %v: %v", schemaFile, err)
+	}
+	if _, err := os.Stat(abs); os.IsNotExist(err) {
+		return fmt.Errorf("schema %v does not exist, the NIST schema is vendored with go run ./cmd/schemas", schemaFile)
+	}
+	schema, err := gojsonschema.NewSchema(gojsonschema.NewReferenceLoader("file://" + filepath.ToSlash(abs)))
+	if err != nil {
+		return fmt.Errorf("cannot load schema %v: %v", schemaFile, err)
+	}
+
+	result, err := schema.Validate(gojsonschema.NewBytesLoader(doc))
+	if err != nil {
+		return fmt.Errorf("cannot validate against %v: %v", schemaFile, err)
+	}
+	if !result.Valid() {
+		var errs []string
+		for _, e := range result.Errors() {
+			errs = append(errs, e.String())
+		}
+		return fmt.Errorf("document is not valid against %v: %v", schemaFile, strings.Join(errs, "; "))
+	}
+	return nil
+}
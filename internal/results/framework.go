@@ -0,0 +1,140 @@
+package results
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"citihub.com/compliance-as-code/internal/controls"
+	"citihub.com/compliance-as-code/internal/featurelint"
+)
+
+// Tally counts the outcomes of scenarios.
+type Tally struct {
+	Passed           int `json:"passed"`
+	Failed           int `json:"failed"`
+	NotApplicable    int `json:"notApplicable"`
+	SkippedGuardrail int `json:"skippedGuardrail"`
+	Skipped          int `json:"skipped"`
+}
+
+// Add counts a scenario with the outcome.
+func (t *Tally) Add(outcome string) {
+	switch outcome {
+	case Passed:
+		t.Passed++
+	case Failed:
+		t.Failed++
+	case NotApplicable:
+		t.NotApplicable++
+	case SkippedGuardrail:
+		t.SkippedGuardrail++
+	default:
+		t.Skipped++
+	}
+}
+
+// Applicable is the number of scenarios that the provider offers a control for, including those that the guardrail skipped.
+func (t Tally) Applicable() int {
+	return t.Passed + t.Failed + t.SkippedGuardrail + t.Skipped
+}
+
+// String returns the tally as e.g. "2/4 scenarios passing, 1 skipped by the guardrail, 1 not applicable".
+func (t Tally) String() string {
+	s := fmt.Sprintf("%d/%d scenarios passing", t.Passed, t.Applicable())
+	if t.SkippedGuardrail > 0 {
+		s += fmt.Sprintf(", %d skipped by the guardrail", t.SkippedGuardrail)
+	}
+	if t.NotApplicable > 0 {
+		s += fmt.Sprintf(", %d not applicable", t.NotApplicable)
+	}
+	return s
+}
+
+// FrameworkControl is the results of the scenarios mapped to a control of an external framework.
+type FrameworkControl struct {
+	controls.Reference
+	// Controls are the controls of the catalog that are mapped to it.
+	Controls []string `json:"controls"`
+	// Providers holds the tally of each provider that was run.
+	Providers map[string]Tally `json:"providers"`
+}
+
+// ProviderNames returns the providers of the results, sorted alphabetically.
+func (fc FrameworkControl) ProviderNames() []string {
+	var p []string
+	for name := range fc.Providers {
+		p = append(p, name)
+	}
+	sort.Strings(p)
+	return p
+}
+
+// ByFramework aggregates the results of the runs by the framework controls that the @CCO: tags of each scenario are mapped to
+// in the crosswalk, for all frameworks or, if framework is not empty, for that framework. A scenario counts once towards a
+// framework control, even when several of its controls are mapped to it.
+// The framework controls are ordered as the frameworks of the crosswalk, then by control.
+func ByFramework(cw *controls.Crosswalk, framework string, runs []Run) []FrameworkControl {
+	byRef := map[controls.Reference]*FrameworkControl{}
+	for _, r := range runs {
+		for _, f := range r.Features {
+			for _, s := range f.Elements {
+				if s.Type != "" && s.Type != "scenario" {
+					continue
+				}
+
+				refs := map[controls.Reference][]string{}
+				for _, id := range f.TagValues(s, featurelint.ControlTagPrefix) {
+					for _, ref := range cw.References(id) {
+						if framework == "" || ref.Framework == framework {
+							refs[ref] = append(refs[ref], strings.ToUpper(id))
+						}
+					}
+				}
+
+				outcome := s.Outcome()
+				for ref, ids := range refs {
+					fc, ok := byRef[ref]
+					if !ok {
+						fc = &FrameworkControl{Reference: ref, Providers: map[string]Tally{}}
+						byRef[ref] = fc
+					}
+					fc.Controls = union(fc.Controls, ids)
+					t := fc.Providers[r.Provider]
+					t.Add(outcome)
+					fc.Providers[r.Provider] = t
+				}
+			}
+		}
+	}
+
+	order := map[string]int{}
+	for i, f := range cw.Frameworks {
+		order[f.ID] = i
+	}
+	var results []FrameworkControl
+	for _, fc := range byRef {
+		results = append(results, *fc)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Framework != results[j].Framework {
+			return order[results[i].Framework] < order[results[j].Framework]
+		}
+		return results[i].Control < results[j].Control
+	})
+	return results
+}
+
+func union(list, add []string) []string {
+	for _, a := range add {
+		found := false
+		for _, l := range list {
+			found = found || l == a
+		}
+		if !found {
+			list = append(list, a)
+		}
+	}
+	sort.Strings(list)
+	return list
+}
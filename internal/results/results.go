@@ -0,0 +1,185 @@
+// Package results reads the results of the suites, as written by the cucumber formatter of godog (-godog.format=cucumber),
+// and aggregates them by the controls of external frameworks that the features are mapped to.
+package results
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"citihub.com/compliance-as-code/internal/evidence"
+	"citihub.com/compliance-as-code/internal/guardrail"
+)
+
+// Step statuses of the cucumber format.
+const (
+	StatusPassed    = "passed"
+	StatusFailed    = "failed"
+	StatusSkipped   = "skipped"
+	StatusPending   = "pending"
+	StatusUndefined = "undefined"
+)
+
+// Outcomes of a scenario.
+const (
+	Passed = "passed"
+	Failed = "failed"
+	// NotApplicable scenarios were reported as pending, as the provider does not offer such a control (see csp.Unsupported).
+	NotApplicable = "not applicable"
+	// SkippedGuardrail scenarios were reported as pending, as the guardrail skipped them on a protected environment.
+	SkippedGuardrail = "skipped: guardrail"
+	Skipped          = "skipped"
+)
+
+// Tag is a tag of a feature or scenario.
+type Tag struct {
+	Name string `json:"name"`
+	Line int    `json:"line"`
+}
+
+// Result is the result of a step.
+type Result struct {
+	Status       string `json:"status"`
+	ErrorMessage string `json:"error_message,omitempty"`
+	Duration     int64  `json:"duration,omitempty"`
+}
+
+// Step is a step that was run.
+type Step struct {
+	Keyword string `json:"keyword"`
+	Name    string `json:"name"`
+	Line    int    `json:"line"`
+	Result  Result `json:"result"`
+}
+
+// Scenario is a scenario that was run, or a row of the examples of a Scenario Outline.
+type Scenario struct {
+	ID      string `json:"id"`
+	Keyword string `json:"keyword"`
+	Name    string `json:"name"`
+	Line    int    `json:"line"`
+	Type    string `json:"type"`
+	Tags    []Tag  `json:"tags"`
+	Steps   []Step `json:"steps"`
+	// Guardrail is the decision of the guardrail when it stopped the scenario, taken from its evidence (see Annotate), as the
+	// cucumber format does not hold it.
+	Guardrail *guardrail.Decision `json:"guardrail,omitempty"`
+}
+
+// Feature is a feature that was run.
+type Feature struct {
+	URI      string     `json:"uri"`
+	ID       string     `json:"id"`
+	Name     string     `json:"name"`
+	Line     int        `json:"line"`
+	Tags     []Tag      `json:"tags"`
+	Elements []Scenario `json:"elements"`
+}
+
+// Run is the results of the suites run against a provider.
+type Run struct {
+	Provider string    `json:"provider"`
+	Features []Feature `json:"features"`
+}
+
+// Load reads the results of a run against the provider from a file in the cucumber format. The file may hold the results of
+// several suites, as written by go test, and the output of go test around them is ignored.
+func Load(provider, path string) (Run, error) {
+	r := Run{Provider: strings.ToLower(provider)}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return r, err
+	}
+
+	found := false
+	for i := bytes.IndexByte(b, '['); i >= 0; i = bytes.IndexByte(b, '[') {
+		d := json.NewDecoder(bytes.NewReader(b[i:]))
+		var features []Feature
+		if err := d.Decode(&features); err != nil {
+			b = b[i+1:]
+			continue
+		}
+		found = true
+		r.Features = append(r.Features, features...)
+		b = b[i+int(d.InputOffset()):]
+	}
+	if !found {
+		return r, fmt.Errorf("no results in the cucumber format in %v", path)
+	}
+	return r, nil
+}
+
+// Annotate adds to the scenarios of the run the decision of the guardrail recorded in their evidence. The evidence of a
+// scenario is the one recorded for the same provider, feature and name, in the order they were run.
+func (r *Run) Annotate(recorded []evidence.Scenario) {
+	ev := map[string][]evidence.Scenario{}
+	for _, s := range recorded {
+		k := EvidenceKey(s.Provider, s.URI, s.Name)
+		ev[k] = append(ev[k], s)
+	}
+	for i := range r.Features {
+		f := &r.Features[i]
+		for j := range f.Elements {
+			s := &f.Elements[j]
+			k := EvidenceKey(r.Provider, f.URI, s.Name)
+			if e := ev[k]; len(e) > 0 {
+				ev[k] = e[1:]
+				if s.Guardrail == nil {
+					s.Guardrail = e[0].Guardrail
+				}
+			}
+		}
+	}
+}
+
+// EvidenceKey returns the key that matches the results of a scenario with its evidence.
+func EvidenceKey(provider, uri, name string) string {
+	return strings.ToLower(provider) + "|" + filepath.ToSlash(uri) + "|" + name
+}
+
+// Outcome returns the outcome of the scenario: failed if a step failed or has no step definition, skipped by the guardrail
+// if a step is pending and the guardrail stopped it, not applicable if a step is pending otherwise, passed if every step
+// passed and skipped otherwise.
+func (s Scenario) Outcome() string {
+	pending, passed := false, len(s.Steps) > 0
+	for _, st := range s.Steps {
+		switch st.Result.Status {
+		case StatusFailed, StatusUndefined:
+			return Failed
+		case StatusPending:
+			pending = true
+		case StatusPassed:
+		default:
+			passed = false
+		}
+	}
+	switch {
+	case pending && s.Guardrail != nil:
+		return SkippedGuardrail
+	case pending:
+		return NotApplicable
+	case passed:
+		return Passed
+	}
+	return Skipped
+}
+
+// TagValues returns the values of the tags of the scenario, or its feature, with the prefix, e.g. the control IDs of "@CCO:" tags.
+func (f Feature) TagValues(s Scenario, prefix string) []string {
+	var v []string
+	seen := map[string]bool{}
+	for _, t := range append(append([]Tag{}, f.Tags...), s.Tags...) {
+		if len(t.Name) <= len(prefix) || !strings.EqualFold(t.Name[:len(prefix)], prefix) {
+			continue
+		}
+		value := t.Name[len(prefix):]
+		if !seen[strings.ToUpper(value)] {
+			seen[strings.ToUpper(value)] = true
+			v = append(v, value)
+		}
+	}
+	return v
+}
@@ -0,0 +1,116 @@
+// Package steps holds the step definitions shared by the Object Storage features, so that a new control such as
+// versioning reuses the wording of existing features and only implements the interfaces below for each CSP.
+package steps
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"citihub.com/compliance-as-code/internal/csp"
+	messages "github.com/cucumber/messages-go/v10"
+)
+
+// Results of the "creation will" step.
+const (
+	Succeed = "Succeed"
+	Fail    = "Fail"
+)
+
+// Control describes the control of a feature, completing the wording of the shared steps.
+type Control struct {
+	// Prevents completes "security controls that ... are applied", e.g. "restrict data from being unencrypted in flight".
+	Prevents string
+	// NonCompliance describes non-compliant Object Storage, completing "Object Storage is created ..." and
+	// "the detective capability detects the creation of Object Storage ...", e.g. "with unencrypted data transfer enabled".
+	NonCompliance string
+	// Remediation completes "the detective capability enforces ... on the Object Storage Bucket", e.g. "encrypted data transfer".
+	Remediation string
+}
+
+// Preventative is implemented by the CSP implementations of a feature whose control prevents non-compliant Object Storage from being created.
+type Preventative interface {
+	// SecurityControlsApplied checks that the preventative control is in place.
+	SecurityControlsApplied() error
+	// ProvisionObjectStorage prepares to create Object Storage, whose options are set by the steps of the feature.
+	ProvisionObjectStorage() error
+	// CreationWill creates the Object Storage and checks that creation has the result, Succeed or Fail, and that a failure
+	// matches the regular expression errDescription.
+	CreationWill(result, errDescription string) error
+}
+
+// EnforcedControls is implemented by preventative controls whose effect can be inspected, e.g. Azure Policy Assignments.
+type EnforcedControls interface {
+	SecurityControlsEnforcedWithEffect(effect string) error
+}
+
+// ConfiguredControls is implemented by preventative controls whose parameters can be inspected, e.g. Azure Policy Assignments.
+type ConfiguredControls interface {
+	// SecurityControlsConfiguredWithParameters checks the parameters in a table of Parameter and Value.
+	SecurityControlsConfiguredWithParameters(table *messages.PickleStepArgument_PickleTable) error
+}
+
+// Detective is implemented by the CSP implementations of a feature whose control detects non-compliant Object Storage once it exists.
+type Detective interface {
+	DetectiveCapabilityAvailable() error
+	DetectiveCapabilityActive() error
+	CreateNonCompliantObjectStorage() error
+	DetectsNonCompliantObjectStorage() error
+}
+
+// Corrective is implemented by the CSP implementations of a feature whose control remediates the non-compliant Object Storage it detects.
+type Corrective interface {
+	RemediatesNonCompliantObjectStorage() error
+}
+
+// Definition is a shared step: its expression, worded for a control, and the method of a CSP implementation that it is bound to.
+type Definition struct {
+	Expr   string
+	Method string
+}
+
+// Definitions returns the shared steps worded for the control, in the order of the interfaces above.
+func Definitions(c Control) []Definition {
+	nc := phrase(c.NonCompliance)
+	return []Definition{
+		{fmt.Sprintf(`^security controls that %s are applied$`, phrase(c.Prevents)), "SecurityControlsApplied"},
+		{`^we provision an Object Storage bucket$`, "ProvisionObjectStorage"},
+		{`^creation will "([^"]*)" with an error matching "([^"]*)"$`, "CreationWill"},
+		{`^the security controls are enforced with effect "([^"]*)"$`, "SecurityControlsEnforcedWithEffect"},
+		{`^the security controls are configured with parameters:$`, "SecurityControlsConfiguredWithParameters"},
+		{fmt.Sprintf(`^there is a detective capability for creation of Object Storage %s$`, nc), "DetectiveCapabilityAvailable"},
+		{fmt.Sprintf(`^the capability for detecting the creation of Object Storage %s is active$`, nc), "DetectiveCapabilityActive"},
+		{fmt.Sprintf(`^Object Storage is created %s$`, nc), "CreateNonCompliantObjectStorage"},
+		{fmt.Sprintf(`^the detective capability detects the creation of Object Storage %s$`, nc), "DetectsNonCompliantObjectStorage"},
+		{fmt.Sprintf(`^the detective capability enforces %s on the Object Storage Bucket$`, phrase(c.Remediation)), "RemediatesNonCompliantObjectStorage"},
+	}
+}
+
+// Bind binds the shared steps that impl implements, worded for the control. The feature binds its own steps,
+// e.g. those setting the options of the Object Storage to create, alongside.
+// The steps are bound by method name, so that internal/featurelint can tell which steps each CSP implementation defines without running it.
+func Bind(s *csp.Steps, c Control, impl interface{}) {
+	v := reflect.ValueOf(impl)
+	for _, d := range Definitions(c) {
+		if m := v.MethodByName(d.Method); m.IsValid() {
+			s.Step(d.Expr, m.Interface())
+		}
+	}
+}
+
+// ExpectFailure reports whether the result of the "creation will" step is Fail, returning an error unless it is Fail or Succeed.
+func ExpectFailure(result string) (bool, error) {
+	switch {
+	case strings.EqualFold(result, Fail):
+		return true, nil
+	case strings.EqualFold(result, Succeed):
+		return false, nil
+	}
+	return false, fmt.Errorf("unsupported `result` option '%s' in the Gherkin feature - use either '%s' or '%s'", result, Fail, Succeed)
+}
+
+// phrase quotes the wording of a control for use in a step expression.
+func phrase(s string) string {
+	return regexp.QuoteMeta(s)
+}
@@ -0,0 +1,46 @@
+package suite
+
+import (
+	"fmt"
+
+	"citihub.com/compliance-as-code/internal/results"
+)
+
+// Exit policies, which decide from the outcomes of the scenarios whether a run fails.
+const (
+	// FailOnFailed fails a run when a scenario failed, or has a step without a step definition.
+	FailOnFailed = "failed"
+	// FailOnNotPassed fails a run when a scenario did not pass, including when it is not applicable on a provider or was skipped.
+	FailOnNotPassed = "not-passed"
+	// FailNever does not fail a run, e.g. when its report is reviewed rather than gating a pipeline.
+	FailNever = "never"
+)
+
+// Policies are the exit policies.
+var Policies = []string{FailOnFailed, FailOnNotPassed, FailNever}
+
+// Fails reports whether the results of the runs fail under the exit policy.
+func Fails(policy string, runs []results.Run) (bool, error) {
+	var fail func(outcome string) bool
+	switch policy {
+	case FailOnFailed:
+		fail = func(outcome string) bool { return outcome == results.Failed }
+	case FailOnNotPassed:
+		fail = func(outcome string) bool { return outcome != results.Passed }
+	case FailNever:
+		return false, nil
+	default:
+		return false, fmt.Errorf("exit policy '%s' is not one of %v", policy, Policies)
+	}
+
+	for _, r := range runs {
+		for _, f := range r.Features {
+			for _, s := range f.Elements {
+				if fail(s.Outcome()) {
+					return true, nil
+				}
+			}
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,200 @@
+package suite
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"citihub.com/compliance-as-code/internal/csp"
+	"citihub.com/compliance-as-code/internal/evidence"
+	"citihub.com/compliance-as-code/internal/featurefile"
+	"citihub.com/compliance-as-code/internal/featurelint"
+	"citihub.com/compliance-as-code/internal/results"
+	"github.com/cucumber/godog"
+)
+
+// Internal is the provider of the run of the suites whose features are tagged @internal, which check the repository itself
+// rather than a control and so are run once. An internal feature tagged with providers, e.g. @csp.azure, is only run when
+// one of them is selected.
+const Internal = "internal"
+
+// exitOptionError is the status returned by godog.RunWithOptions when the options are not valid, e.g. a tag expression.
+const exitOptionError = 2
+
+// Options select the features and scenarios to run.
+type Options struct {
+	// Root is the directory holding the features, including its sub-directories, e.g. "test/features".
+	Root string
+	// Providers are the providers that the features tagged with them are run against, e.g. "aws" for "@csp.aws".
+	Providers []string
+	// Tags is a godog tag expression selecting the scenarios, e.g. "@preventative && ~@intrusive_test".
+	Tags string
+}
+
+// Run runs the suites of the features under the root in this process, one run for each provider and one for the internal
+// suites unless the tags select none of their scenarios, and returns their results. A suite is run from its directory, as
+// go test runs it, with the CSP environment variable set to the provider, and only with the features tagged with the provider.
+// The scenarios that the guardrail stopped hold its decision, see results.Run.Annotate.
+func Run(opts Options) ([]results.Run, error) {
+	files, err := featurefile.Find(opts.Root)
+	if err != nil {
+		return nil, fmt.Errorf("cannot find features in %v: %v", opts.Root, err)
+	}
+
+	// The features of each suite, by the directory of the suite
+	suites := map[string][]*featurefile.Feature{}
+	for _, path := range files {
+		f, err := featurefile.Parse(path)
+		if err != nil {
+			return nil, err
+		}
+		dir := featurefile.SuiteDir(path)
+		suites[dir] = append(suites[dir], f)
+	}
+	var dirs []string
+	for dir := range suites {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	var providers []string
+	for _, p := range opts.Providers {
+		providers = append(providers, strings.ToLower(p))
+	}
+
+	var runs []results.Run
+	for _, p := range providers {
+		r, err := run(p, providers, opts.Tags, dirs, suites)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, r)
+	}
+	r, err := run(Internal, providers, opts.Tags, dirs, suites)
+	if err != nil {
+		return nil, err
+	}
+	if len(r.Features) > 0 {
+		runs = append(runs, r)
+	}
+	return runs, nil
+}
+
+func run(provider string, providers []string, tags string, dirs []string, suites map[string][]*featurefile.Feature) (results.Run, error) {
+	r := results.Run{Provider: provider}
+	if provider != Internal {
+		prev, set := os.LookupEnv(csp.EnvVar)
+		os.Setenv(csp.EnvVar, provider)
+		defer func() {
+			if set {
+				os.Setenv(csp.EnvVar, prev)
+			} else {
+				os.Unsetenv(csp.EnvVar)
+			}
+		}()
+	}
+
+	for _, dir := range dirs {
+		var paths []string
+		for _, f := range suites[dir] {
+			if selected(f, provider, providers) {
+				paths = append(paths, f.Path)
+			}
+		}
+		if len(paths) == 0 {
+			continue
+		}
+
+		name := filepath.Base(dir)
+		fc, ok := Lookup(name)
+		if !ok {
+			return r, fmt.Errorf("no suite is registered for %v, its package must be imported by the runner", dir)
+		}
+		features, err := runSuite(name, provider, dir, paths, tags, fc)
+		if err != nil {
+			return r, err
+		}
+		r.Features = append(r.Features, features...)
+	}
+	r.Annotate(evidence.Recorded())
+	return r, nil
+}
+
+// selected reports whether the feature is run against the provider: a feature tagged with the provider, or an internal
+// feature in the internal run when it is tagged with none of the providers or with one of the selected providers.
+func selected(f *featurefile.Feature, provider string, providers []string) bool {
+	tagged := f.TagValues(featurelint.CSPTagPrefix)
+	if f.HasTag(featurelint.InternalTag) {
+		if provider != Internal {
+			return false
+		}
+		if len(tagged) == 0 {
+			return true
+		}
+		for _, p := range providers {
+			if containsFold(tagged, p) {
+				return true
+			}
+		}
+		return false
+	}
+	return provider != Internal && containsFold(tagged, provider)
+}
+
+func containsFold(values []string, v string) bool {
+	for _, e := range values {
+		if strings.EqualFold(e, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// runSuite runs the features of a suite from its directory and returns their results, less the features without a selected scenario.
+func runSuite(name, provider, dir string, paths []string, tags string, fc FeatureContext) ([]results.Feature, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chdir(dir); err != nil {
+		return nil, fmt.Errorf("cannot run suite %s: %v", name, err)
+	}
+	defer func() {
+		if err := os.Chdir(wd); err != nil {
+			log.Panicf("suite: cannot return to %v after running suite %s: %v", wd, name, err)
+		}
+	}()
+
+	for i, p := range paths {
+		if paths[i], err = filepath.Rel(dir, p); err != nil {
+			return nil, err
+		}
+	}
+
+	log.Printf("[DEBUG] Running suite %s on %s with %v", name, csp.DisplayName(provider), paths)
+	var out bytes.Buffer
+	status := godog.RunWithOptions(name, func(s *godog.Suite) {
+		fc(s)
+	}, godog.Options{Format: "cucumber", Output: &out, Paths: paths, Tags: tags, NoColors: true})
+	if status == exitOptionError {
+		return nil, fmt.Errorf("cannot run suite %s, see its output for the reason", name)
+	}
+
+	var features, ran []results.Feature
+	if out.Len() > 0 {
+		if err := json.Unmarshal(out.Bytes(), &features); err != nil {
+			return nil, fmt.Errorf("cannot read the results of suite %s: %v", name, err)
+		}
+	}
+	for _, f := range features {
+		if len(f.Elements) > 0 {
+			ran = append(ran, f)
+		}
+	}
+	return ran, nil
+}
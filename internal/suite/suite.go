@@ -0,0 +1,54 @@
+// Package suite is the registry of the godog suites, so that they can be run in one process by cmd/run as well as one
+// at a time by go test. Each suite registers its FeatureContext from an init function, under the name of its directory.
+package suite
+
+import (
+	"log"
+	"sort"
+	"sync"
+
+	"github.com/cucumber/godog"
+)
+
+// FeatureContext binds the steps and hooks of a suite.
+type FeatureContext func(*godog.Suite)
+
+var (
+	mu       sync.RWMutex
+	registry = map[string]FeatureContext{}
+)
+
+// Register makes a suite available under the name of its directory, e.g. "encryption_in_flight".
+// It is intended to be called from the init function of the suite package.
+func Register(name string, fc FeatureContext) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if fc == nil {
+		log.Panicf("suite: Register FeatureContext of suite '%s' is nil", name)
+	}
+	if _, dup := registry[name]; dup {
+		log.Panicf("suite: Register called twice for suite '%s'", name)
+	}
+	registry[name] = fc
+}
+
+// Lookup returns the FeatureContext of the named suite.
+func Lookup(name string) (FeatureContext, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	fc, ok := registry[name]
+	return fc, ok
+}
+
+// Names returns the names of the registered suites, sorted alphabetically.
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	var n []string
+	for name := range registry {
+		n = append(n, name)
+	}
+	sort.Strings(n)
+	return n
+}
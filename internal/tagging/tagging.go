@@ -0,0 +1,207 @@
+// Package tagging is the tagging policy of the resources that the suites create: every resource carries the ID of the run,
+// the feature and scenario that created it, who ran it and when it may be deleted, so that the resources of a run can be
+// told apart, their cost allocated and the ones left behind deleted by cmd/janitor from their tags.
+//
+// The policy is carried by the context that the helpers creating resources are called with, see NewContext and
+// csp.Steps.Context.
+package tagging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"os/user"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Namespace prefixes the tag keys, so that the tags of the suites are not taken for those of other tools or teams sharing
+// the accounts and subscriptions, e.g. a "run-id" of their own, nor theirs for ours by cmd/janitor.
+const Namespace = "compliance-as-code:"
+
+// Tag keys.
+const (
+	KeyRunID     = Namespace + "run-id"
+	KeyFeature   = Namespace + "feature"
+	KeyScenario  = Namespace + "scenario"
+	KeyCreatedBy = Namespace + "created-by"
+	KeyExpiresAt = Namespace + "expires-at"
+)
+
+// Environment variables.
+const (
+	// RunIDEnvVar is the ID of the run, generated when it is not set, e.g. to share an ID between the suites of a CI job.
+	RunIDEnvVar = "RUN_ID"
+	// CreatedByEnvVar is who runs the suites, e.g. the CI job, the user and host name when it is not set.
+	CreatedByEnvVar = "RUN_CREATED_BY"
+	// TTLEnvVar is how long after their creation the resources may be deleted, e.g. "72h", DefaultTTL when it is not set.
+	TTLEnvVar = "RUN_TTL"
+)
+
+// DefaultTTL is how long after their creation the resources may be deleted by default.
+const DefaultTTL = 24 * time.Hour
+
+// maxValue is the length of the longest tag value that both AWS and Azure accept.
+const maxValue = 256
+
+// Policy is the tagging policy of a run.
+type Policy struct {
+	RunID     string
+	Feature   string
+	Scenario  string
+	CreatedBy string
+	// TTL is how long after their creation the resources may be deleted.
+	TTL time.Duration
+}
+
+// Tags returns the tags of a resource created at a time, without those of the policy that are not set.
+func (p Policy) Tags(created time.Time) map[string]string {
+	tags := map[string]string{}
+	set := func(k, v string) {
+		if v = value(v); v != "" {
+			tags[k] = v
+		}
+	}
+	set(KeyRunID, p.RunID)
+	set(KeyFeature, p.Feature)
+	set(KeyScenario, p.Scenario)
+	set(KeyCreatedBy, p.CreatedBy)
+	if p.TTL > 0 {
+		set(KeyExpiresAt, created.Add(p.TTL).UTC().Format(time.RFC3339))
+	}
+	return tags
+}
+
+// value replaces the characters that AWS does not accept in tag values, e.g. the quotes of scenario names, and truncates
+// it to the length both AWS and Azure accept.
+func value(v string) string {
+	v = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', strings.ContainsRune(" _.:/=+-@", r):
+			return r
+		}
+		return '_'
+	}, strings.TrimSpace(v))
+	if len(v) > maxValue {
+		v = v[:maxValue]
+	}
+	return v
+}
+
+// runIDBytes is the number of random bytes of a run ID.
+const runIDBytes = 4
+
+var (
+	once      sync.Once
+	runPolicy Policy
+	policyKey = struct{ name string }{"tagging.Policy"}
+)
+
+// Run returns the policy of the run, without feature and scenario. The run ID is generated on the first call when the
+// RUN_ID environment variable is not set, and then set in it so that the processes the run starts share it.
+func Run() Policy {
+	once.Do(func() {
+		runPolicy = Policy{RunID: os.Getenv(RunIDEnvVar), CreatedBy: os.Getenv(CreatedByEnvVar), TTL: DefaultTTL}
+		if runPolicy.RunID == "" {
+			runPolicy.RunID = newRunID()
+			os.Setenv(RunIDEnvVar, runPolicy.RunID)
+		}
+		if runPolicy.CreatedBy == "" {
+			runPolicy.CreatedBy = createdBy()
+		}
+		if ttl := os.Getenv(TTLEnvVar); ttl != "" {
+			d, err := time.ParseDuration(ttl)
+			if err != nil || d <= 0 {
+				log.Printf("[WARN] Ignoring %s=%s, not a positive duration, resources expire after %v", TTLEnvVar, ttl, DefaultTTL)
+			} else {
+				runPolicy.TTL = d
+			}
+		}
+		log.Printf("[DEBUG] Run ID: %s", runPolicy.RunID)
+	})
+	return runPolicy
+}
+
+// RunID returns the ID of the run, see Run.
+func RunID() string {
+	return Run().RunID
+}
+
+// newRunID returns an ID that sorts by the time of the run, e.g. "20201018t060652-1a2b3c4d". It is in lower case as Azure
+// and S3 lower some names.
+func newRunID() string {
+	b := make([]byte, runIDBytes)
+	if _, err := rand.Read(b); err != nil {
+		log.Panicf("cannot generate run ID: %v", err)
+	}
+	return fmt.Sprintf("%s-%s", strings.ToLower(time.Now().UTC().Format("20060102T150405")), hex.EncodeToString(b))
+}
+
+func createdBy() string {
+	name := "unknown"
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		name = u.Username
+	}
+	if host, err := os.Hostname(); err == nil && host != "" {
+		name += "@" + host
+	}
+	return name
+}
+
+// NewContext returns a context carrying a policy.
+func NewContext(ctx context.Context, p Policy) context.Context {
+	return context.WithValue(ctx, policyKey, func() Policy { return p })
+}
+
+// NewContextFunc returns a context carrying the policy that f returns when resources are created, e.g. that of the current
+// scenario of a suite.
+func NewContextFunc(ctx context.Context, f func() Policy) context.Context {
+	return context.WithValue(ctx, policyKey, f)
+}
+
+// FromContext returns the policy carried by a context, the policy of the run if none is.
+func FromContext(ctx context.Context) Policy {
+	if ctx != nil {
+		if f, ok := ctx.Value(policyKey).(func() Policy); ok {
+			return f()
+		}
+	}
+	return Run()
+}
+
+// Tags returns the tags of a resource created now with the policy carried by a context, merged with additional tags. The
+// tags of the policy take precedence, so that they cannot be overwritten.
+func Tags(ctx context.Context, additional map[string]string) map[string]string {
+	tags := map[string]string{}
+	for k, v := range additional {
+		tags[k] = v
+	}
+	for k, v := range FromContext(ctx).Tags(time.Now()) {
+		tags[k] = v
+	}
+	return tags
+}
+
+// Keys returns the keys of tags, sorted, e.g. to tag resources in a stable order.
+func Keys(tags map[string]string) []string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Expires returns when a resource with the tags may be deleted, zero when the tags do not tell.
+func Expires(tags map[string]string) time.Time {
+	t, err := time.Parse(time.RFC3339, tags[KeyExpiresAt])
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
@@ -1,20 +1,18 @@
 locals {
-  name         = "deny_aks_without_rbac"
-  display_name = "[AKS] Azure Kubernetes clusters must not be created without RBAC enabled [BDD]"
+  definition   = jsondecode(file("${path.module}/policy_definition.json"))
+  name         = local.definition.name
+  display_name = local.definition.displayName
 }
 
 resource "azurerm_policy_definition" "deny_aks_without_rbac" {
   name                = local.name
   display_name        = local.display_name
   policy_type         = "Custom"
-  mode                = "Indexed"
-  policy_rule         = file("${path.module}/../../../resources/azure_policy/aks_rbac_deny.json")
+  mode                = local.definition.mode
+  policy_rule         = file("${path.module}/${local.definition.policyRule}")
+  parameters          = file("${path.module}/${local.definition.parameters}")
   management_group_id = var.definition_management_group_id
-  metadata            = <<METADATA
-  {
-    "category": "AKS"
-  }
-  METADATA
+  metadata            = jsonencode(local.definition.metadata)
 
   lifecycle {
     ignore_changes = [
@@ -13,6 +13,11 @@ resource "azurerm_policy_assignment" "deny_http_storage" {
     type = "SystemAssigned"
   }
 
+  // Reported when a request is denied, the encryption_in_flight feature matches its Error Description against it
+  non_compliance_message {
+    content = "Storage Buckets must not be accessible via plain HTTP"
+  }
+
   parameters = <<PARAMETERS
   {
     "effect": {
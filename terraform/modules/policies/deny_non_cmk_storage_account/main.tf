@@ -1,16 +1,16 @@
+locals {
+  definition = jsondecode(file("${path.module}/policy_definition.json"))
+}
+
 // Policy Definition
 resource "azurerm_policy_definition" "deny_non_cmk_storage_ac" {
-  name                = "deny_non_cmk_storage_ac"
+  name                = local.definition.name
   policy_type         = "Custom"
-  mode                = "Indexed"
-  display_name        = "Deny storage account using MS Managed Key [BDD]"
-  description         = "Deny storage account using Microsoft Managed Key. Storage account should be using Customer Managed Key from keyvault"
+  mode                = local.definition.mode
+  display_name        = local.definition.displayName
+  description         = local.definition.description
   management_group_id = var.definition_management_group_id
-  metadata            = <<METADATA
-  {
-    "category": "Storage"
-  }
-  METADATA
+  metadata            = jsonencode(local.definition.metadata)
 
   lifecycle {
     ignore_changes = [
@@ -18,26 +18,9 @@ resource "azurerm_policy_definition" "deny_non_cmk_storage_ac" {
     ]
   }
 
-  parameters = <<PARAMETERS
-  {
-    "effect": {
-        "type": "String",
-        "metadata": {
-          "displayName": "Effect",
-          "description": "Enable or disable the execution of the policy"
-        },
-        "allowedValues": [
-          "Deny",
-          "Audit",
-          "Disabled"
-        ],
-        "defaultValue": "Deny"
-      }
-  }
-
-  PARAMETERS
+  parameters = file("${path.module}/${local.definition.parameters}")
 
-  policy_rule = file("${path.module}/deny_non_cmk_storage_account_rule.json")
+  policy_rule = file("${path.module}/${local.definition.policyRule}")
 }
 
 // Policy Assignment
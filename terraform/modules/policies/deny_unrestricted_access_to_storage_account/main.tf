@@ -1,16 +1,16 @@
+locals {
+  definition = jsondecode(file("${path.module}/policy_definition.json"))
+}
+
 // Policy Definition
 resource "azurerm_policy_definition" "deny_unrestricted_access_to_storage_account" {
-  name                = "deny_unrestricted_network_access_to_storage_account"
+  name                = local.definition.name
   policy_type         = "Custom"
-  mode                = "All"
-  display_name        = "Deny unrestricted network access to storage account"
-  description         = "Deny unrestricted network access in your storage account firewall settings. Instead, configure network rules so only applications from allowed networks can access the storage account. To allow connections from specific internet or on-premise clients, access can be granted to traffic from specific Azure virtual networks or to public internet IP address ranges"
+  mode                = local.definition.mode
+  display_name        = local.definition.displayName
+  description         = local.definition.description
   management_group_id = var.definition_management_group_id
-  metadata            = <<METADATA
-  {
-    "category": "Storage"
-  }
-  METADATA
+  metadata            = jsonencode(local.definition.metadata)
 
   lifecycle {
     ignore_changes = [
@@ -18,9 +18,9 @@ resource "azurerm_policy_definition" "deny_unrestricted_access_to_storage_accoun
     ]
   }
 
-  parameters = file("${path.module}/policy_parameters.json")
+  parameters = file("${path.module}/${local.definition.parameters}")
 
-  policy_rule = file("${path.module}/deny_unrestricted_access_to_storage_account.json")
+  policy_rule = file("${path.module}/${local.definition.policyRule}")
 }
 
 // Policy Assignment
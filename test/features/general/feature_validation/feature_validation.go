@@ -0,0 +1,49 @@
+// Package featurevalidation is the suite of the feature validating the Gherkin features against the metadata conventions.
+package featurevalidation
+
+import (
+	"errors"
+	"fmt"
+
+	"citihub.com/compliance-as-code/internal/featurefile"
+	"citihub.com/compliance-as-code/internal/featurelint"
+	"citihub.com/compliance-as-code/internal/suite"
+	"github.com/cucumber/godog"
+)
+
+// featureRoot is the directory scanned for Gherkin features, including its sub-directories
+const featureRoot = "../.."
+
+func testFeaturesPresent() error {
+	files, err := featurefile.Find(featureRoot)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return errors.New("there are no features to test")
+	}
+	return nil
+}
+
+func testLint() error {
+	findings, err := featurelint.Files(featureRoot)
+	if err != nil {
+		return err
+	}
+	for _, f := range findings {
+		fmt.Printf("Failed to lint %v\n", f)
+	}
+	if len(findings) > 0 {
+		return errors.New("one or more features failed linting")
+	}
+	return nil
+}
+
+func init() {
+	suite.Register("feature_validation", FeatureContext)
+}
+
+func FeatureContext(s *godog.Suite) {
+	s.Step(`^a directory of Gherkin features$`, testFeaturesPresent)
+	s.Step(`^the features must pass the lint rules$`, testLint)
+}
@@ -0,0 +1,14 @@
+@internal
+Feature: Gherkin Features Follow the Conventions of the Repository
+
+  Features that we commit to the repository must trace back to the control objectives they implement.
+  Each control feature has a @CCO: tag, a @csp. tag for each provider it is implemented for, is tagged either @intrusive_test or @non_intrusive_test
+  and has a Rule naming one of its control objectives, and each of its scenarios is tagged with the kind of control it tests, e.g. @preventative.
+  Scenarios that create resources are tagged @intrusive_test, even in a @non_intrusive_test feature.
+  Features tagged @internal only check the repository offline, and are tagged neither @intrusive_test nor @non_intrusive_test.
+  Every step must have a step definition for each provider of its feature.
+
+  Scenario:
+
+    Given a directory of Gherkin features
+    Then the features must pass the lint rules
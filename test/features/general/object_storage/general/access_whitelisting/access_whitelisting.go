@@ -1,6 +1,60 @@
-package main
+// Package accesswhitelisting is the suite of the Access Whitelisting feature of Object Storage.
+package accesswhitelisting
 
-//main holds the variables and constants used by the tests
-func main() {
+import (
+	"context"
+	"log"
 
+	"citihub.com/compliance-as-code/internal/csp"
+	"citihub.com/compliance-as-code/internal/logfilter"
+	"citihub.com/compliance-as-code/internal/steps"
+	"citihub.com/compliance-as-code/internal/suite"
+	"github.com/cucumber/godog"
+)
+
+// featureName is the name under which the CSP-specific implementations register themselves
+const featureName = "access_whitelisting"
+
+// control completes the wording of the steps shared with the other Object Storage features
+var control = steps.Control{
+	Prevents: "Prevent Object Storage from being created without network source address whitelisting",
+}
+
+// accessWhitelisting is an interface. For each CSP specific implementation
+type accessWhitelisting interface {
+	steps.Preventative
+	setup(ctx context.Context)
+	beginScenario()
+	endScenario()
+	cspSupportsWhitelisting() error
+	examineStorageContainer(containerName string) error
+	whitelistingIsConfigured() error
+	createWithWhitelist(ipPrefix string) error
+	teardown()
+}
+
+func init() {
+	suite.Register(featureName, FeatureContext)
+}
+
+func FeatureContext(s *godog.Suite) {
+	logfilter.Setup()
+	impl, err := csp.Lookup(featureName)
+	if err != nil {
+		log.Panicf("%v", err)
+	}
+	state, ok := impl.(accessWhitelisting)
+	if !ok {
+		log.Panicf("'%T' registered for '%s' does not implement accessWhitelisting", impl, csp.Name())
+	}
+
+	st := csp.NewSteps(s, featureName)
+	st.Setup(state.setup, state.teardown)
+	st.Scenario(state.beginScenario, state.endScenario)
+	steps.Bind(st, control, state)
+
+	st.Step(`^the CSP provides a whitelisting capability for Object Storage containers$`, state.cspSupportsWhitelisting)
+	st.Step(`^we examine the Object Storage container in environment variable "([^"]*)"$`, state.examineStorageContainer)
+	st.Step(`^whitelisting is configured with the given IP address range or an endpoint$`, state.whitelistingIsConfigured)
+	st.Step(`^it is created with whitelisting entry "([^"]*)"$`, state.createWithWhitelist)
 }
@@ -1,36 +1,48 @@
-package main
+package accesswhitelisting
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log"
+	"net"
 	"os"
+	"strings"
 
 	citihubAws "citihub.com/compliance-as-code/internal/aws"
+	"citihub.com/compliance-as-code/internal/csp"
+	"citihub.com/compliance-as-code/internal/evidence"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
+	messages "github.com/cucumber/messages-go/v10"
 )
 
 const (
-	notIPAddress  = "NotIpAddress"
-	ipAddress     = "IpAddress"
-	awsSourceVpc  = "aws:sourceVpc"
-	awsSourceVpce = "aws:sourceVpce"
+	// The simulated request that a bucket with network whitelisting must deny, and allow from the whitelisted sources
+	probePrincipal = "arn:aws:iam::123456789012:user/compliance-as-code"
+	probeObject    = "compliance-as-code-probe"
+	probeSourceIP  = "8.8.8.8"
+
+	noPreventativeControl = "S3 has no preventative control for network access whitelisting"
 )
 
 type accessWhitelistingAWS struct {
-	ctx        context.Context
-	tags       map[string]*string
-	svc        *s3.S3
-	session    *session.Session
+	ctx     context.Context
+	svc     *s3.S3
+	session *session.Session
+
+	// State of the scenario, reset by beginScenario
 	bucketName string
 }
 
-func (state *accessWhitelistingAWS) setup() {
+func init() {
+	csp.Register(featureName, csp.AWS, func() interface{} { return &accessWhitelistingAWS{} })
+	csp.Unsupported(featureName, csp.AWS, csp.Preventative, noPreventativeControl)
+}
+
+func (state *accessWhitelistingAWS) setup(ctx context.Context) {
 	log.Println("[DEBUG] Setting up 'accessWhitelistingAWS'")
-	state.ctx = context.Background()
+	state.ctx = ctx
 
 	var err error
 	state.session, err = session.NewSession()
@@ -44,23 +56,36 @@ func (state *accessWhitelistingAWS) teardown() {
 	log.Println("[DEBUG] Teardown completed")
 }
 
-func (state *accessWhitelistingAWS) checkPolicyAssigned() error {
-	return fmt.Errorf("AWS does not support preventative controls for access whitelisting on S3")
+func (state *accessWhitelistingAWS) beginScenario() {
+	state.bucketName = ""
 }
 
-func (state *accessWhitelistingAWS) provisionStorageContainer() error {
-	// Not supported
-	return nil
+// endScenario has nothing to delete, the bucket examined is not created by the scenario.
+func (state *accessWhitelistingAWS) endScenario() {
+}
+
+func (state *accessWhitelistingAWS) SecurityControlsApplied() error {
+	return csp.NotApplicable(noPreventativeControl)
+}
+
+func (state *accessWhitelistingAWS) SecurityControlsEnforcedWithEffect(effect string) error {
+	return csp.NotApplicable(noPreventativeControl)
+}
+
+func (state *accessWhitelistingAWS) SecurityControlsConfiguredWithParameters(table *messages.PickleStepArgument_PickleTable) error {
+	return csp.NotApplicable(noPreventativeControl)
+}
+
+func (state *accessWhitelistingAWS) ProvisionObjectStorage() error {
+	return csp.NotApplicable(noPreventativeControl)
 }
 
 func (state *accessWhitelistingAWS) createWithWhitelist(arg1 string) error {
-	// Not supported
-	return nil
+	return csp.NotApplicable(noPreventativeControl)
 }
 
-func (state *accessWhitelistingAWS) creationWill(arg1 string) error {
-	// Not supported
-	return nil
+func (state *accessWhitelistingAWS) CreationWill(result, errDescription string) error {
+	return csp.NotApplicable(noPreventativeControl)
 }
 
 func (state *accessWhitelistingAWS) cspSupportsWhitelisting() error {
@@ -75,6 +100,7 @@ func (state *accessWhitelistingAWS) examineStorageContainer(containerNameEnvVar
 	}
 
 	state.bucketName = name
+	evidence.Record(evidence.KindBucket, name)
 	log.Printf("[DEBUG] Trying to access bucket: '%s'", state.bucketName)
 
 	_, err := state.svc.HeadBucket(&s3.HeadBucketInput{
@@ -95,42 +121,104 @@ func (state *accessWhitelistingAWS) whitelistingIsConfigured() error {
 		return err
 	}
 
-	var policyDoc citihubAws.PolicyDocument
 	log.Printf("[DEBUG] policy: %v", *result.Policy)
-	err = json.Unmarshal([]byte(*result.Policy), &policyDoc)
+	policyDoc, err := citihubAws.ParsePolicyDocument(*result.Policy)
+	if err != nil {
+		return err
+	}
+
+	// A request from outside the whitelisted IP ranges and VPC endpoints must be denied by the bucket policy
+	probe := citihubAws.Request{
+		Principal:       probePrincipal,
+		Action:          "s3:GetObject",
+		Resource:        fmt.Sprintf("arn:aws:s3:::%s/%s", state.bucketName, probeObject),
+		SourceIP:        net.ParseIP(probeSourceIP),
+		SecureTransport: true,
+	}
+	eval, err := policyDoc.Evaluate(probe)
 	if err != nil {
-		log.Panicf("%v", err)
 		return err
 	}
-	for _, stmt := range *policyDoc.Statement {
-		if *stmt.Effect == "Deny" {
-			conditionMap := *stmt.Condition
-			if conditionMap["StringNotEquals"] != nil {
-				var conditionKey map[string]interface{}
-				conditionKey = conditionMap["StringNotEquals"].(map[string]interface{})
-				if conditionKey[awsSourceVpce] != nil {
-					log.Printf("[DEBUG] %v: %v", awsSourceVpce, conditionKey[awsSourceVpce])
-					return nil
+	log.Printf("[DEBUG] GetObject from %v outside any VPC endpoint: %v", probeSourceIP, eval)
+
+	if eval.Decision != citihubAws.ExplicitDeny {
+		return fmt.Errorf("bucket policy on '%v' does not deny a GetObject from non-whitelisted address %v (%v): %v", state.bucketName, probeSourceIP, eval, policyDoc)
+	}
+
+	// Requests from the whitelisted sources must not be denied, or a policy denying every request would pass
+	sources := whitelistedSources(policyDoc)
+	if len(sources) == 0 {
+		return fmt.Errorf("bucket policy on '%v' denies a GetObject from %v, but whitelists no IP address range or VPC endpoint: %v", state.bucketName, probeSourceIP, policyDoc)
+	}
+	for _, source := range sources {
+		probe.SourceIP, probe.SourceVpce, probe.SourceVpc = source.SourceIP, source.SourceVpce, source.SourceVpc
+		eval, err = policyDoc.Evaluate(probe)
+		if err != nil {
+			return err
+		}
+		log.Printf("[DEBUG] GetObject from whitelisted %v: %v", source, eval)
+		if eval.Decision == citihubAws.ExplicitDeny {
+			return fmt.Errorf("bucket policy on '%v' denies a GetObject from whitelisted %v too, rather than the non-whitelisted sources only (%v): %v", state.bucketName, source, eval, policyDoc)
+		}
+	}
+	return nil
+}
+
+// source is a whitelisted source of requests: an IP address, or a VPC or VPC endpoint, through which requests have no
+// source IP.
+type source struct {
+	SourceIP   net.IP
+	SourceVpce string
+	SourceVpc  string
+}
+
+func (s source) String() string {
+	switch {
+	case s.SourceVpce != "":
+		return "VPC endpoint " + s.SourceVpce
+	case s.SourceVpc != "":
+		return "VPC " + s.SourceVpc
+	}
+	return "address " + s.SourceIP.String()
+}
+
+// whitelistedSources returns the sources that the Deny statements of a policy exempt, from their NotIpAddress conditions on
+// aws:SourceIp and negated string conditions on aws:sourceVpce and aws:SourceVpc, e.g. 11.11.11.11 for "NotIpAddress":
+// {"aws:SourceIp": "11.11.11.11/32"}. Wildcard VPCs and VPC endpoints are skipped.
+func whitelistedSources(d citihubAws.PolicyDocument) []source {
+	var sources []source
+	for _, st := range d.Statement {
+		if st.Effect != citihubAws.Deny {
+			continue
+		}
+		for op := range st.Condition {
+			switch op.Base() {
+			case citihubAws.NotIPAddress:
+				values, _ := st.Condition.Values(op, citihubAws.KeySourceIP)
+				for _, v := range values {
+					ip := net.ParseIP(v)
+					if _, network, err := net.ParseCIDR(v); err == nil {
+						ip = network.IP
+					}
+					if ip != nil {
+						sources = append(sources, source{SourceIP: ip})
+					}
 				}
-				if conditionKey[awsSourceVpc] != nil {
-					log.Printf("[DEBUG] %v: %v", awsSourceVpc, conditionKey[awsSourceVpc])
-					return nil
+			case citihubAws.StringNotEquals, citihubAws.StringNotEqualsIgnoreCase, citihubAws.StringNotLike:
+				values, _ := st.Condition.Values(op, citihubAws.KeySourceVpce)
+				for _, v := range values {
+					if !strings.ContainsAny(v, "*?") {
+						sources = append(sources, source{SourceVpce: v})
+					}
 				}
-			}
-			if conditionMap[notIPAddress] != nil {
-				log.Printf("[DEBUG] %v: %v", notIPAddress, conditionMap[notIPAddress])
-				return nil
-			}
-		} else if *stmt.Effect == "Allow" {
-			if stmt.Condition != nil {
-				conditionMap := *stmt.Condition
-				if conditionMap[ipAddress] != nil {
-					log.Printf("[DEBUG] %v: %v", ipAddress, conditionMap[ipAddress])
-					return nil
+				values, _ = st.Condition.Values(op, citihubAws.KeySourceVpc)
+				for _, v := range values {
+					if !strings.ContainsAny(v, "*?") {
+						sources = append(sources, source{SourceVpc: v})
+					}
 				}
 			}
 		}
 	}
-
-	return fmt.Errorf("no Deny IP address in bucket policy: %v", result)
+	return sources
 }
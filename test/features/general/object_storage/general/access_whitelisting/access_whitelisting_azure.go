@@ -1,4 +1,4 @@
-package main
+package accesswhitelisting
 
 import (
 	"context"
@@ -10,9 +10,13 @@ import (
 	"citihub.com/compliance-as-code/internal/azureutil/group"
 	"citihub.com/compliance-as-code/internal/azureutil/policy"
 	"citihub.com/compliance-as-code/internal/azureutil/storage"
-	azurePolicy "github.com/Azure/azure-sdk-for-go/services/resources/mgmt/2019-01-01/policy"
+	"citihub.com/compliance-as-code/internal/clouderr"
+	"citihub.com/compliance-as-code/internal/csp"
+	"citihub.com/compliance-as-code/internal/evidence"
+	"citihub.com/compliance-as-code/internal/steps"
 	azureStorage "github.com/Azure/azure-sdk-for-go/services/storage/mgmt/2019-04-01/storage"
 	"github.com/Azure/go-autorest/autorest/to"
+	messages "github.com/cucumber/messages-go/v10"
 )
 
 const (
@@ -21,31 +25,27 @@ const (
 )
 
 type accessWhitelistingAzure struct {
-	ctx                       context.Context
-	policyAssignmentMgmtGroup string
-	tags                      map[string]*string
-	bucketName                string
-	storageAccount            azureStorage.Account
-	runningErr                error
+	ctx context.Context
+
+	// State of the scenario, reset by beginScenario
+	policyAssignment policy.EffectiveAssignment
+	bucketName       string
+	storageAccount   azureStorage.Account
+	runningErr       error
+	// created is set when the scenario created the Storage Account named bucketName
+	created bool
 }
 
-func (state *accessWhitelistingAzure) setup() {
+func init() {
+	csp.Register(featureName, csp.Azure, func() interface{} { return &accessWhitelistingAzure{} })
+}
 
-	log.Println("[DEBUG] Setting up 'accessWhitelistingAzure'")
-	state.ctx = context.Background()
+func (state *accessWhitelistingAzure) setup(ctx context.Context) {
 
-	state.policyAssignmentMgmtGroup = os.Getenv(azureutil.PolicyAssignmentManagementGroup)
-	if state.policyAssignmentMgmtGroup == "" {
-		log.Printf("[ERROR] '%v' environment variable is not defined. Policy assignment check against subscription", azureutil.PolicyAssignmentManagementGroup)
-	}
-
-	state.tags = map[string]*string{
-		"project": to.StringPtr("CICD"),
-		"env":     to.StringPtr("test"),
-		"tier":    to.StringPtr("internal"),
-	}
+	log.Println("[DEBUG] Setting up 'accessWhitelistingAzure'")
+	state.ctx = ctx
 
-	_, err := group.CreateWithTags(state.ctx, azureutil.ResourceGroup(), state.tags)
+	_, err := group.Create(state.ctx, azureutil.ResourceGroup())
 	if err != nil {
 		log.Fatalf("failed to create group: %v\n", err.Error())
 	}
@@ -61,28 +61,70 @@ func (state *accessWhitelistingAzure) teardown() {
 	log.Println("[DEBUG] Teardown completed")
 }
 
-func (state *accessWhitelistingAzure) checkPolicyAssigned() error {
-
-	var a azurePolicy.Assignment
-	var err error
+func (state *accessWhitelistingAzure) beginScenario() {
+	state.policyAssignment = policy.EffectiveAssignment{}
+	state.bucketName, state.storageAccount, state.runningErr = "", azureStorage.Account{}, nil
+	state.created = false
+}
 
-	// If a Management Group has not been set, check Policy Assignment at the Subscription
-	if state.policyAssignmentMgmtGroup == "" {
-		a, err = policy.AssignmentBySubscription(state.ctx, azureutil.SubscriptionID(), policyAssignmentName)
+// endScenario deletes the Storage Account created by the scenario, if any, rather than leave it to the deletion of the
+// Resource Group.
+func (state *accessWhitelistingAzure) endScenario() {
+	if !state.created {
+		return
+	}
+	if err := storage.Delete(state.ctx, state.bucketName, azureutil.ResourceGroup()); err != nil {
+		log.Printf("[ERROR] Failed to delete Storage Account %v: %v", state.bucketName, err)
 	} else {
-		a, err = policy.AssignmentByManagementGroup(state.ctx, state.policyAssignmentMgmtGroup, policyAssignmentName)
+		log.Printf("[DEBUG] Storage Account %v clean up successful.", state.bucketName)
 	}
+}
 
+func (state *accessWhitelistingAzure) SecurityControlsApplied() error {
+
+	// The assignment may be made at the Subscription or inherited from any of its Management Groups
+	a, err := policy.EffectiveAssignmentByName(state.ctx, azureutil.SubscriptionID(), azureutil.ResourceGroup(), policyAssignmentName)
 	if err != nil {
 		log.Printf("[ERROR] Policy Assignment error: %v", err)
 		return err
 	}
 
-	log.Printf("[DEBUG] Policy Assignment check: %v [Step PASSED]", *a.Name)
+	state.policyAssignment = a
+	evidence.Record(evidence.KindPolicyAssignment, to.String(a.ID))
+	log.Printf("[DEBUG] Policy Assignment check: %v [Step PASSED]", a)
+	return nil
+}
+
+func (state *accessWhitelistingAzure) SecurityControlsEnforcedWithEffect(effect string) error {
+	i, err := policy.InspectAssignment(state.ctx, state.policyAssignment)
+	if err != nil {
+		return err
+	}
+	return i.CheckEffect(effect)
+}
+
+func (state *accessWhitelistingAzure) SecurityControlsConfiguredWithParameters(table *messages.PickleStepArgument_PickleTable) error {
+	i, err := policy.InspectAssignment(state.ctx, state.policyAssignment)
+	if err != nil {
+		return err
+	}
+
+	// The first row holds the column headings
+	if len(table.Rows) < 2 {
+		return fmt.Errorf("expected a table of Parameter and Value")
+	}
+	for _, row := range table.Rows[1:] {
+		if len(row.Cells) != 2 {
+			return fmt.Errorf("expected a Parameter and a Value in each row, got %d cells", len(row.Cells))
+		}
+		if err := i.CheckParameter(row.Cells[0].Value, row.Cells[1].Value); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-func (state *accessWhitelistingAzure) provisionStorageContainer() error {
+func (state *accessWhitelistingAzure) ProvisionObjectStorage() error {
 	// define a bucket name, then pass the step - we will provision the account in the next step.
 	state.bucketName = azureutil.RandString(10)
 	return nil
@@ -106,15 +148,43 @@ func (state *accessWhitelistingAzure) createWithWhitelist(ipRange string) error
 		}
 	}
 
-	state.storageAccount, state.runningErr = storage.CreateWithNetworkRuleSet(state.ctx, state.bucketName, azureutil.ResourceGroup(), state.tags, true, &networkRuleSet)
+	state.storageAccount, state.runningErr = storage.CreateWithNetworkRuleSet(state.ctx, state.bucketName, azureutil.ResourceGroup(), nil, true, &networkRuleSet)
+	if state.runningErr == nil {
+		state.created = true
+		evidence.Record(evidence.KindStorageAccount, to.String(state.storageAccount.ID))
+	} else {
+		// The account was not created, e.g. because a policy denied it, so it has a name but no ID
+		evidence.Record(evidence.KindStorageAccount, state.bucketName)
+	}
 	return nil
 }
 
-func (state *accessWhitelistingAzure) creationWill(expectation string) error {
-	if expectation == "Fail" {
+func (state *accessWhitelistingAzure) CreationWill(expectation, errDescription string) error {
+	fail, err := steps.ExpectFailure(expectation)
+	if err != nil {
+		return err
+	}
+
+	if fail {
 		if state.runningErr == nil {
 			return fmt.Errorf("incorrectly created Storage Account: %v", *state.storageAccount.ID)
 		}
+
+		denial, ok := clouderr.DeniedBy(state.runningErr, policyAssignmentName)
+		if !ok {
+			return fmt.Errorf("storage account was not created, but not because of policy %v (%v error): %v", policyAssignmentName, clouderr.Classify(state.runningErr), state.runningErr)
+		}
+
+		// The Error Description must match the denial or the metadata of the policy, e.g. its non-compliance message
+		i, err := policy.InspectAssignment(state.ctx, state.policyAssignment)
+		if err != nil {
+			return err
+		}
+		if err := denial.Match(errDescription, i.Messages()...); err != nil {
+			return err
+		}
+
+		log.Printf("[DEBUG] Request was Disallowed By Policy: %v [Step PASSED]", denial)
 		return nil
 	}
 
@@ -145,6 +215,7 @@ func (state *accessWhitelistingAzure) examineStorageContainer(containerNameEnvVa
 	if state.runningErr != nil {
 		return state.runningErr
 	}
+	evidence.Record(evidence.KindStorageAccount, to.String(state.storageAccount.ID))
 
 	networkRuleSet := state.storageAccount.AccountProperties.NetworkRuleSet
 	result := false
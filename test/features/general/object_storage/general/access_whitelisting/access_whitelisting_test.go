@@ -1,30 +1,14 @@
-package main
+package accesswhitelisting
 
 import (
 	"flag"
-	"log"
 	"os"
-	"strings"
 	"testing"
 
-	"citihub.com/compliance-as-code/internal/logfilter"
 	"github.com/cucumber/godog"
 	"github.com/cucumber/godog/colors"
 )
 
-// EncryptionInFlight is an interface. For each CSP specific implementation
-type accessWhitelisting interface {
-	setup()
-	cspSupportsWhitelisting() error
-	examineStorageContainer(containerName string) error
-	whitelistingIsConfigured() error
-	checkPolicyAssigned() error
-	provisionStorageContainer() error
-	createWithWhitelist(ipPrefix string) error
-	creationWill(result string) error
-	teardown()
-}
-
 var opt = godog.Options{Output: colors.Colored(os.Stdout)}
 
 func init() {
@@ -35,7 +19,7 @@ func TestMain(m *testing.M) {
 	flag.Parse()
 	opt.Paths = flag.Args()
 
-	status := godog.RunWithOptions("access_whitelisting_test", func(s *godog.Suite) {
+	status := godog.RunWithOptions(featureName, func(s *godog.Suite) {
 		FeatureContext(s)
 	}, opt)
 
@@ -44,30 +28,3 @@ func TestMain(m *testing.M) {
 	}
 	os.Exit(status)
 }
-
-func FeatureContext(s *godog.Suite) {
-	logfilter.Setup()
-	var state accessWhitelisting
-
-	csp := strings.ToLower(os.Getenv("CSP"))
-	switch csp {
-	case "azure":
-		state = &accessWhitelistingAzure{}
-	case "aws":
-		state = &accessWhitelistingAWS{}
-	default:
-		log.Panicf("Cloud Provider '%s' not supported - set environment variable 'CSP'", csp)
-	}
-
-	s.BeforeSuite(state.setup)
-
-	s.Step(`^the CSP provides a whitelisting capability for Object Storage containers$`, state.cspSupportsWhitelisting)
-	s.Step(`^we examine the Object Storage container in environment variable "([^"]*)"$`, state.examineStorageContainer)
-	s.Step(`^whitelisting is configured with the given IP address range or an endpoint$`, state.whitelistingIsConfigured)
-	s.Step(`^security controls that Prevent Object Storage from being created without network source address whitelisting are applied$`, state.checkPolicyAssigned)
-	s.Step(`^we provision an Object Storage container$`, state.provisionStorageContainer)
-	s.Step(`^it is created with whitelisting entry "([^"]*)"$`, state.createWithWhitelist)
-	s.Step(`^creation will "([^"]*)"$`, state.creationWill)
-
-	s.AfterSuite(state.teardown)
-}
@@ -19,16 +19,21 @@ Feature: Object Storage Has Network Whitelisting Measures Enforced
       Then whitelisting is configured with the given IP address range or an endpoint
 
     @preventative
+    @intrusive_test
     Scenario Outline: Prevent Object Storage from Being Created Without Network Source Address Whitelisting
       Given security controls that Prevent Object Storage from being created without network source address whitelisting are applied
-      When we provision an Object Storage container
+      And the security controls are enforced with effect "Deny"
+      And the security controls are configured with parameters:
+        | Parameter            | Value                                |
+        | allowedAddressRanges | ["219.79.19.0/24", "170.74.231.168"] |
+      When we provision an Object Storage bucket
       And it is created with whitelisting entry "<Whitelist Entry>"
-      Then creation will "<Result>"
+      Then creation will "<Result>" with an error matching "<Error Description>"
 
       Examples:
-        | Whitelist Entry | Result  |
-        | 219.79.19.0/24  | Success |
-        | 219.79.19.1     | Fail    |
-        | 219.108.32.1    | Fail    |
-        | 170.74.231.168  | Success |
-        | nil             | Fail    |
+        | Whitelist Entry | Result  | Error Description                                   |
+        | 219.79.19.0/24  | Succeed |                                                     |
+        | 219.79.19.1     | Fail    | Deny unrestricted network access to storage account |
+        | 219.108.32.1    | Fail    | Deny unrestricted network access to storage account |
+        | 170.74.231.168  | Succeed |                                                     |
+        | nil             | Fail    | Deny unrestricted network access to storage account |
@@ -1,6 +1,60 @@
-package main
+// Package encryptionatrest is the suite of the Encryption at Rest feature of Object Storage.
+package encryptionatrest
 
-//main holds the variables and constants used by the tests
-func main() {
+import (
+	"context"
+	"log"
 
+	"citihub.com/compliance-as-code/internal/csp"
+	"citihub.com/compliance-as-code/internal/logfilter"
+	"citihub.com/compliance-as-code/internal/steps"
+	"citihub.com/compliance-as-code/internal/suite"
+	"github.com/cucumber/godog"
+)
+
+// featureName is the name under which the CSP-specific implementations register themselves
+const featureName = "encryption_at_rest"
+
+// control completes the wording of the steps shared with the other Object Storage features
+var control = steps.Control{
+	Prevents:      "restrict data from being unencrypted at rest",
+	NonCompliance: "without encryption at rest",
+	Remediation:   "encryption at rest",
+}
+
+// EncryptionAtRest is an interface. For each CSP specific implementation
+type EncryptionAtRest interface {
+	steps.Preventative
+	steps.Detective
+	steps.Corrective
+	setup(ctx context.Context)
+	beginScenario()
+	endScenario()
+	encryptionAtRestIs(encryptionOption string) error
+	policyOrRuleAssigned() error
+	teardown()
+}
+
+func init() {
+	suite.Register(featureName, FeatureContext)
+}
+
+func FeatureContext(s *godog.Suite) {
+	logfilter.Setup()
+	impl, err := csp.Lookup(featureName)
+	if err != nil {
+		log.Panicf("%v", err)
+	}
+	state, ok := impl.(EncryptionAtRest)
+	if !ok {
+		log.Panicf("'%T' registered for '%s' does not implement EncryptionAtRest", impl, csp.Name())
+	}
+
+	st := csp.NewSteps(s, featureName)
+	st.Setup(state.setup, state.teardown)
+	st.Scenario(state.beginScenario, state.endScenario)
+	steps.Bind(st, control, state)
+
+	st.Step(`^encryption at rest is "([^"]*)"$`, state.encryptionAtRestIs)
+	st.Step(`^the detective measure is enabled$`, state.policyOrRuleAssigned)
 }
@@ -1,4 +1,4 @@
-package main
+package encryptionatrest
 
 import (
 	"context"
@@ -7,7 +7,11 @@ import (
 	"time"
 	"os"
 
+	"citihub.com/compliance-as-code/internal/awsutil"
 	"citihub.com/compliance-as-code/internal/azureutil"
+	"citihub.com/compliance-as-code/internal/csp"
+	"citihub.com/compliance-as-code/internal/evidence"
+	"citihub.com/compliance-as-code/internal/ledger"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/configservice"
@@ -18,24 +22,33 @@ const (
 	encryptionAtRestRule = "s3-bucket-server-side-encryption-enabled"
 	sleepTime            = 30 * time.Second
 	maxRetry             = 10
+
+	noPreventativeControl = "AWS do not have preventive measure but instead reliant on detective measure"
 )
 
 // EncryptionAtRestAWS azure implementation of the encryption in flight for Object Storage feature
 type EncryptionAtRestAWS struct {
-	ctx              context.Context
-	session          *session.Session
+	ctx       context.Context
+	session   *session.Session
+	s3Svc     *s3.S3
+	configSvc *configservice.ConfigService
+	region    string
+
+	// State of the scenario, reset by beginScenario
 	evalResults      []*configservice.EvaluationResult
-	s3Svc            *s3.S3
-	configSvc        *configservice.ConfigService
 	bucketName       string
 	runningErr       error
 	setEncryptionErr error
-    region           string
 }
 
-func (state *EncryptionAtRestAWS) setup() {
+func init() {
+	csp.Register(featureName, csp.AWS, func() interface{} { return &EncryptionAtRestAWS{} })
+	csp.Unsupported(featureName, csp.AWS, csp.Preventative, noPreventativeControl)
+}
+
+func (state *EncryptionAtRestAWS) setup(ctx context.Context) {
 	log.Println("[DEBUG] Setting up \"EncryptionAtRestAWS\"")
-	state.ctx = context.Background()
+	state.ctx = ctx
     state.region = os.Getenv("AWS_REGION")
 
 	// Create Session
@@ -49,33 +62,45 @@ func (state *EncryptionAtRestAWS) setup() {
 }
 
 func (state *EncryptionAtRestAWS) teardown() {
-	state.deleteCurrentTestBucket()
 	log.Println("[DEBUG] Teardown completed")
 }
 
-func (state *EncryptionAtRestAWS) securityControlsThatRestrictDataFromBeingUnencryptedAtRest() error {
-	return fmt.Errorf("AWS do not have preventive measure but instead reliant on detective measure")
+func (state *EncryptionAtRestAWS) beginScenario() {
+	state.evalResults, state.bucketName = nil, ""
+	state.runningErr, state.setEncryptionErr = nil, nil
 }
 
-func (state *EncryptionAtRestAWS) weProvisionAnObjectStorageBucket() error {
-	return nil
+// endScenario deletes the bucket created by the scenario, if any.
+func (state *EncryptionAtRestAWS) endScenario() {
+	if state.bucketName != "" {
+		state.deleteCurrentTestBucket()
+	}
+}
+
+func (state *EncryptionAtRestAWS) SecurityControlsApplied() error {
+	return csp.NotApplicable(noPreventativeControl)
+}
+
+func (state *EncryptionAtRestAWS) ProvisionObjectStorage() error {
+	return csp.NotApplicable(noPreventativeControl)
 }
 
 func (state *EncryptionAtRestAWS) encryptionAtRestIs(encryptionOption string) error {
-	return nil
+	return csp.NotApplicable(noPreventativeControl)
 }
 
-func (state *EncryptionAtRestAWS) creationWillWithAnErrorMatching(result string) error {
-	return nil
+func (state *EncryptionAtRestAWS) CreationWill(result, errDescription string) error {
+	return csp.NotApplicable(noPreventativeControl)
 }
 
-func (state *EncryptionAtRestAWS) policyOrRuleAvailable() error {
+func (state *EncryptionAtRestAWS) DetectiveCapabilityAvailable() error {
 	// It is available
 	log.Printf("[DEBUG] Checking AWS Config Rule: %s", encryptionAtRestRule)
 	return nil
 }
 
-func (state *EncryptionAtRestAWS) checkPolicyOrRuleAssignment() error {
+func (state *EncryptionAtRestAWS) DetectiveCapabilityActive() error {
+	evidence.Record(evidence.KindConfigRule, encryptionAtRestRule)
 	svc := configservice.New(state.session)
 	resp, err := svc.GetComplianceDetailsByConfigRule(&configservice.GetComplianceDetailsByConfigRuleInput{
 		ConfigRuleName: aws.String(encryptionAtRestRule),
@@ -102,20 +127,27 @@ func (state *EncryptionAtRestAWS) prepareToCreateContainer() error {
 	return nil
 }
 
-func (state *EncryptionAtRestAWS) createContainerWithoutEncryption() error {
+func (state *EncryptionAtRestAWS) CreateNonCompliantObjectStorage() error {
 	state.bucketName = fmt.Sprintf("test%sunencbucket", azureutil.RandString(5))
+	evidence.Record(evidence.KindBucket, state.bucketName)
+	if err := ledger.Record(state.bucket()); err != nil {
+		return err
+	}
 	resp, err := state.s3Svc.CreateBucket(&s3.CreateBucketInput{
 		Bucket: aws.String(state.bucketName),
 		CreateBucketConfiguration: &s3.CreateBucketConfiguration{
 			LocationConstraint: aws.String(state.region),
 		},
 	})
+	if err != nil {
+		return err
+	}
 	log.Printf("[DEBUG] Created Bucket: %v", *resp.Location)
-	return err
+	return awsutil.TagBucket(state.ctx, state.s3Svc, state.bucketName)
 }
 
 // Wait for Config rule to detect the bucket has been created
-func (state *EncryptionAtRestAWS) detectiveDetectsNonCompliant() error {
+func (state *EncryptionAtRestAWS) DetectsNonCompliantObjectStorage() error {
 	log.Printf("[DEBUG] Waiting for bucket to be detected by Config Rule...")
 	for i := 0; i < maxRetry; i++ {
 		resp, err := state.configSvc.GetComplianceDetailsByConfigRule(&configservice.GetComplianceDetailsByConfigRuleInput{
@@ -165,7 +197,7 @@ func (state *EncryptionAtRestAWS) detectiveDetectsNonCompliant() error {
 	return fmt.Errorf("failed to find bucket '%v' in evaluation result of AWS Config Rule:'%v' [Step Failed]", state.bucketName, encryptionAtRestRule)
 }
 
-func (state *EncryptionAtRestAWS) containerIsRemediated() error {
+func (state *EncryptionAtRestAWS) RemediatesNonCompliantObjectStorage() error {
 	for i := 0; i < maxRetry; i++ {
 		log.Printf("[DEBUG] Checking bucket policy for SSE setting")
 		encrypted := state.checkBucketEncryption()
@@ -194,6 +226,12 @@ func (state *EncryptionAtRestAWS) deleteCurrentTestBucket() {
 	if err != nil {
 		log.Printf("[ERROR] Error in deleting test bucket %v. Please manually clean up.", state.bucketName)
 	} else {
+		ledger.Release(state.bucket())
 		log.Printf("[DEBUG] Bucket %v clean up successful.", state.bucketName)
 	}
 }
+
+// bucket returns the ledger resource of the test bucket.
+func (state *EncryptionAtRestAWS) bucket() ledger.Resource {
+	return ledger.Resource{Provider: ledger.AWS, Kind: ledger.KindBucket, ID: state.bucketName, Region: state.region}
+}
@@ -1,67 +1,81 @@
-package main
+package encryptionatrest
 
 import (
-	"log"
+	"context"
+
+	"citihub.com/compliance-as-code/internal/csp"
 )
 
+// Azure Storage accounts are encrypted by default and encryption cannot be turned off, so there is no control to test.
+// (Unless customised to check for specific key usage, see the deny_non_cmk_storage_account policy.)
+const alwaysEncrypted = "Azure Storage is always encrypted at rest and encryption cannot be turned off"
+
 // EncryptionAtRestAzure Azure implementation of the encryption in flight for Object Storage feature
 type EncryptionAtRestAzure struct {
 }
 
-func (state *EncryptionAtRestAzure) securityControlsThatRestrictDataFromBeingUnencryptedAtRest() error {
-	// It is available
-	log.Printf("[DEBUG] Azure Storage account is encrypted by default and cannot be turned off. No test to run. Checking Azure Policy. (Unless customise this test to check for specific key usage.")
-	return nil
+func init() {
+	csp.Register(featureName, csp.Azure, func() interface{} { return &EncryptionAtRestAzure{} })
+	csp.Unsupported(featureName, csp.Azure, csp.Preventative, alwaysEncrypted)
+	csp.Unsupported(featureName, csp.Azure, csp.Detective, alwaysEncrypted)
+}
+
+func (state *EncryptionAtRestAzure) SecurityControlsApplied() error {
+	return csp.NotApplicable(alwaysEncrypted)
 }
 
-func (state *EncryptionAtRestAzure) weProvisionAnObjectStorageBucket() error {
-	return nil
+func (state *EncryptionAtRestAzure) ProvisionObjectStorage() error {
+	return csp.NotApplicable(alwaysEncrypted)
 }
 func (state *EncryptionAtRestAzure) encryptionAtRestIs(encryptionOption string) error {
-	return nil
+	return csp.NotApplicable(alwaysEncrypted)
 }
-func (state *EncryptionAtRestAzure) creationWillWithAnErrorMatching(result string) error {
-	return nil
+func (state *EncryptionAtRestAzure) CreationWill(result, errDescription string) error {
+	return csp.NotApplicable(alwaysEncrypted)
 }
 
-func (state *EncryptionAtRestAzure) createContainerWithoutEncryption() error {
-	return nil
+func (state *EncryptionAtRestAzure) CreateNonCompliantObjectStorage() error {
+	return csp.NotApplicable(alwaysEncrypted)
 }
-func (state *EncryptionAtRestAzure) detectiveDetectsNonCompliant() error {
-	return nil
+func (state *EncryptionAtRestAzure) DetectsNonCompliantObjectStorage() error {
+	return csp.NotApplicable(alwaysEncrypted)
 }
-func (state *EncryptionAtRestAzure) containerIsRemediated() error {
-	return nil
+func (state *EncryptionAtRestAzure) RemediatesNonCompliantObjectStorage() error {
+	return csp.NotApplicable(alwaysEncrypted)
 }
 
-func (state *EncryptionAtRestAzure) setup() {
+func (state *EncryptionAtRestAzure) setup(ctx context.Context) {
 }
 
 func (state *EncryptionAtRestAzure) teardown() {
 }
 
-func (state *EncryptionAtRestAzure) policyOrRuleAvailable() error {
-	// It is available
-	log.Printf("[DEBUG] Azure Storage account is encrypted by default and cannot be turned off. No test to run. Checking Azure Policy. (Unless customise this test to check for specific key usage.")
-	return nil
+func (state *EncryptionAtRestAzure) beginScenario() {
+}
+
+func (state *EncryptionAtRestAzure) endScenario() {
+}
+
+func (state *EncryptionAtRestAzure) DetectiveCapabilityAvailable() error {
+	return csp.NotApplicable(alwaysEncrypted)
 }
 
-func (state *EncryptionAtRestAzure) checkPolicyOrRuleAssignment() error {
-	return nil
+func (state *EncryptionAtRestAzure) DetectiveCapabilityActive() error {
+	return csp.NotApplicable(alwaysEncrypted)
 }
 
 func (state *EncryptionAtRestAzure) policyOrRuleAssigned() error {
-	return nil
+	return csp.NotApplicable(alwaysEncrypted)
 }
 
 func (state *EncryptionAtRestAzure) prepareToCreateContainer() error {
-	return nil
+	return csp.NotApplicable(alwaysEncrypted)
 }
 
 func (state *EncryptionAtRestAzure) createContainerWithEncryptionOption(encryptionOption string) error {
-	return nil
+	return csp.NotApplicable(alwaysEncrypted)
 }
 
 func (state *EncryptionAtRestAzure) createResult(result string) error {
-	return nil
+	return csp.NotApplicable(alwaysEncrypted)
 }
@@ -1,36 +1,14 @@
-package main
+package encryptionatrest
 
 import (
 	"flag"
-	"log"
 	"os"
-	"strings"
 	"testing"
 
-	"citihub.com/compliance-as-code/internal/logfilter"
 	"github.com/cucumber/godog"
 	"github.com/cucumber/godog/colors"
 )
 
-const csp = "CSP"
-
-// EncryptionAtRest is an interface. For each CSP specific implementation
-type EncryptionAtRest interface {
-	setup()
-	securityControlsThatRestrictDataFromBeingUnencryptedAtRest() error
-	weProvisionAnObjectStorageBucket() error
-	encryptionAtRestIs(encryptionOption string) error
-	creationWillWithAnErrorMatching(result string) error
-	policyOrRuleAvailable() error
-	checkPolicyOrRuleAssignment() error
-	policyOrRuleAssigned() error
-	prepareToCreateContainer() error
-	createContainerWithoutEncryption() error
-	detectiveDetectsNonCompliant() error
-	containerIsRemediated() error
-	teardown()
-}
-
 var opt = godog.Options{Output: colors.Colored(os.Stdout)}
 
 func init() {
@@ -41,7 +19,7 @@ func TestMain(m *testing.M) {
 	flag.Parse()
 	opt.Paths = flag.Args()
 
-	status := godog.RunWithOptions("encryption_at_rest", func(s *godog.Suite) {
+	status := godog.RunWithOptions(featureName, func(s *godog.Suite) {
 		FeatureContext(s)
 	}, opt)
 
@@ -50,33 +28,3 @@ func TestMain(m *testing.M) {
 	}
 	os.Exit(status)
 }
-
-func FeatureContext(s *godog.Suite) {
-	logfilter.Setup()
-	var state EncryptionAtRest
-
-	cspEnv := strings.ToLower(os.Getenv(csp))
-	switch cspEnv {
-	case "azure":
-		state = &EncryptionAtRestAzure{}
-	case "aws":
-		state = &EncryptionAtRestAWS{}
-	default:
-		log.Panicf("Environment variable CSP is defined as \"%s\"", cspEnv)
-	}
-
-	s.BeforeSuite(state.setup)
-
-	s.Step(`^security controls that restrict data from being unencrypted at rest$`, state.securityControlsThatRestrictDataFromBeingUnencryptedAtRest)
-	s.Step(`^we provision an Object Storage bucket$`, state.weProvisionAnObjectStorageBucket)
-	s.Step(`^encryption at rest is "([^"]*)"$`, state.encryptionAtRestIs)
-	s.Step(`^creation will "([^"]*)" with an error matching "([^"]*)"$`, state.creationWillWithAnErrorMatching)
-
-	s.Step(`^there is a detective capability for creation of Object Storage without encryption at rest$`, state.policyOrRuleAvailable)
-	s.Step(`^the capability for detecting the creation of Object Storage without encryption at rest is active$`, state.checkPolicyOrRuleAssignment)
-	s.Step(`^the detective measure is enabled$`, state.policyOrRuleAssigned)
-	s.Step(`^Object Storage is created with without encryption at rest$`, state.createContainerWithoutEncryption)
-	s.Step(`^the detective capability detects the creation of Object Storage without encryption at rest$`, state.detectiveDetectsNonCompliant)
-	s.Step(`^the detective capability enforces encryption at rest on the Object Storage Bucket$`, state.containerIsRemediated)
-	s.AfterSuite(state.teardown)
-}
@@ -16,7 +16,7 @@ Feature: Object Storage Encryption at Rest
 
     @preventative
     Scenario Outline: Prevent Creation of Object Storage Without Encryption at Rest
-      Given security controls that restrict data from being unencrypted at rest
+      Given security controls that restrict data from being unencrypted at rest are applied
       When we provision an Object Storage bucket
       And encryption at rest is "<Encryption Option>"
       Then creation will "<Result>" with an error matching "<Error Description>"
@@ -30,6 +30,6 @@ Feature: Object Storage Encryption at Rest
     Scenario: Detect creation of Object Storage Without Encryption at Rest
       Given there is a detective capability for creation of Object Storage without encryption at rest
       And the capability for detecting the creation of Object Storage without encryption at rest is active
-      When Object Storage is created with without encryption at rest
+      When Object Storage is created without encryption at rest
       Then the detective capability detects the creation of Object Storage without encryption at rest
       And the detective capability enforces encryption at rest on the Object Storage Bucket
\ No newline at end of file
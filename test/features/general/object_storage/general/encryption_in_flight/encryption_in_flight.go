@@ -1,6 +1,60 @@
-package main
+// Package encryptioninflight is the suite of the Encryption in Flight feature of Object Storage.
+package encryptioninflight
 
-//main holds the variables and constants used by the tests
-func main() {
+import (
+	"context"
+	"log"
 
+	"citihub.com/compliance-as-code/internal/csp"
+	"citihub.com/compliance-as-code/internal/logfilter"
+	"citihub.com/compliance-as-code/internal/steps"
+	"citihub.com/compliance-as-code/internal/suite"
+	"github.com/cucumber/godog"
+)
+
+// featureName is the name under which the CSP-specific implementations register themselves
+const featureName = "encryption_in_flight"
+
+// control completes the wording of the steps shared with the other Object Storage features
+var control = steps.Control{
+	Prevents:      "restrict data from being unencrypted in flight",
+	NonCompliance: "with unencrypted data transfer enabled",
+	Remediation:   "encrypted data transfer",
+}
+
+// EncryptionInFlight is an interface. For each CSP specific implementation
+type EncryptionInFlight interface {
+	steps.Preventative
+	steps.Detective
+	steps.Corrective
+	setup(ctx context.Context)
+	beginScenario()
+	endScenario()
+	httpAccessIs(arg1 string) error
+	httpsAccessIs(arg1 string) error
+	teardown()
+}
+
+func init() {
+	suite.Register(featureName, FeatureContext)
+}
+
+func FeatureContext(s *godog.Suite) {
+	logfilter.Setup()
+	impl, err := csp.Lookup(featureName)
+	if err != nil {
+		log.Panicf("%v", err)
+	}
+	state, ok := impl.(EncryptionInFlight)
+	if !ok {
+		log.Panicf("'%T' registered for '%s' does not implement EncryptionInFlight", impl, csp.Name())
+	}
+
+	st := csp.NewSteps(s, featureName)
+	st.Setup(state.setup, state.teardown)
+	st.Scenario(state.beginScenario, state.endScenario)
+	steps.Bind(st, control, state)
+
+	st.Step(`^http access is "([^"]*)"$`, state.httpAccessIs)
+	st.Step(`^https access is "([^"]*)"$`, state.httpsAccessIs)
 }
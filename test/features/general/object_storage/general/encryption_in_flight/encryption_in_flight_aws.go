@@ -1,15 +1,19 @@
-package main
+package encryptioninflight
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log"
+	"net"
 	"os"
 	"time"
 
 	citihubAws "citihub.com/compliance-as-code/internal/aws"
+	"citihub.com/compliance-as-code/internal/awsutil"
 	"citihub.com/compliance-as-code/internal/azureutil"
+	"citihub.com/compliance-as-code/internal/csp"
+	"citihub.com/compliance-as-code/internal/evidence"
+	"citihub.com/compliance-as-code/internal/ledger"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/configservice"
@@ -17,29 +21,41 @@ import (
 )
 
 const (
-	sslRequestOnly     = "s3-bucket-ssl-requests-only"
-	awsSecureTransport = "aws:SecureTransport"
-	maxRetry           = 10
-	sleepTime          = 60 * time.Second
+	sslRequestOnly = "s3-bucket-ssl-requests-only"
+	maxRetry       = 10
+	sleepTime      = 60 * time.Second
+
+	// The simulated request that a bucket enforcing encryption in flight must deny
+	probePrincipal = "arn:aws:iam::123456789012:user/compliance-as-code"
+	probeObject    = "compliance-as-code-probe"
+	probeSourceIP  = "8.8.8.8"
+
+	noPreventativeControl = "S3 has no preventative control for secure transfer"
 )
 
 // EncryptionInFlightAWS stores the context used for the Encryption in Flight test on AWS.
 type EncryptionInFlightAWS struct {
-	ctx         context.Context
-	tags        map[string]*string
+	ctx       context.Context
+	session   *session.Session
+	s3Svc     *s3.S3
+	configSvc *configservice.ConfigService
+	region    string
+
+	// State of the scenario, reset by beginScenario
 	httpOption  bool
 	httpsOption bool
-	session     *session.Session
-	s3Svc       *s3.S3
-	configSvc   *configservice.ConfigService
 	bucketName  string
 	runningErr  error
-	region      string
 }
 
-func (state *EncryptionInFlightAWS) setup() {
+func init() {
+	csp.Register(featureName, csp.AWS, func() interface{} { return &EncryptionInFlightAWS{} })
+	csp.Unsupported(featureName, csp.AWS, csp.Preventative, noPreventativeControl)
+}
+
+func (state *EncryptionInFlightAWS) setup(ctx context.Context) {
 	log.Println("[DEBUG] Setting up \"EncryptionInFlightAWS\"")
-	state.ctx = context.Background()
+	state.ctx = ctx
 	state.region = os.Getenv("AWS_REGION")
 	// Create Session
 	var err error
@@ -52,52 +68,75 @@ func (state *EncryptionInFlightAWS) setup() {
 }
 
 func (state *EncryptionInFlightAWS) teardown() {
+	log.Println("[DEBUG] Teardown completed")
+}
+
+func (state *EncryptionInFlightAWS) beginScenario() {
+	state.httpOption, state.httpsOption = false, false
+	state.bucketName, state.runningErr = "", nil
+}
+
+// endScenario deletes the bucket created by the scenario, if any.
+func (state *EncryptionInFlightAWS) endScenario() {
+	if state.bucketName == "" {
+		return
+	}
 	_, err := state.s3Svc.DeleteBucket(&s3.DeleteBucketInput{Bucket: aws.String(state.bucketName)})
 	if err != nil {
 		log.Printf("[ERROR] error in deleting test bucket %v. Please manually clean up", state.bucketName)
 	} else {
+		ledger.Release(state.bucket())
 		log.Printf("[DEBUG] Bucket %v clean up successful.", state.bucketName)
 	}
-	log.Println("[DEBUG] Teardown completed")
 }
 
-func (state *EncryptionInFlightAWS) securityControlsThatRestrictDataFromBeingUnencryptedInFlight() error {
-	return fmt.Errorf("AWS do not support preventative controls for secure transfer on S3")
+// bucket returns the ledger resource of the test bucket.
+func (state *EncryptionInFlightAWS) bucket() ledger.Resource {
+	return ledger.Resource{Provider: ledger.AWS, Kind: ledger.KindBucket, ID: state.bucketName, Region: state.region}
 }
 
-func (state *EncryptionInFlightAWS) weProvisionAnObjectStorageBucket() error {
-	// Not supported
-	return nil
+func (state *EncryptionInFlightAWS) SecurityControlsApplied() error {
+	return csp.NotApplicable(noPreventativeControl)
+}
+
+func (state *EncryptionInFlightAWS) SecurityControlsEnforcedWithEffect(effect string) error {
+	return csp.NotApplicable(noPreventativeControl)
+}
+
+func (state *EncryptionInFlightAWS) ProvisionObjectStorage() error {
+	return csp.NotApplicable(noPreventativeControl)
 }
 
 func (state *EncryptionInFlightAWS) httpAccessIs(arg1 string) error {
-	// Not supported
-	return nil
+	return csp.NotApplicable(noPreventativeControl)
 }
 
 func (state *EncryptionInFlightAWS) httpsAccessIs(arg1 string) error {
-	// Not supported
-	return nil
+	return csp.NotApplicable(noPreventativeControl)
 }
 
-func (state *EncryptionInFlightAWS) creationWillWithAnErrorMatching(result, errDescription string) error {
-	// Not supported
-	return nil
+func (state *EncryptionInFlightAWS) CreationWill(result, errDescription string) error {
+	return csp.NotApplicable(noPreventativeControl)
 }
 
-func (state *EncryptionInFlightAWS) detectObjectStorageUnencryptedTransferAvailable() error {
+func (state *EncryptionInFlightAWS) DetectiveCapabilityAvailable() error {
 	return nil
 }
 
-func (state *EncryptionInFlightAWS) detectObjectStorageUnencryptedTransferEnabled() error {
+func (state *EncryptionInFlightAWS) DetectiveCapabilityActive() error {
+	evidence.Record(evidence.KindConfigRule, sslRequestOnly)
 	_, err := state.configSvc.GetComplianceDetailsByConfigRule(&configservice.GetComplianceDetailsByConfigRuleInput{
 		ConfigRuleName: aws.String(sslRequestOnly),
 	})
 	return err
 }
 
-func (state *EncryptionInFlightAWS) createUnencryptedTransferObjectStorage() error {
+func (state *EncryptionInFlightAWS) CreateNonCompliantObjectStorage() error {
 	state.bucketName = fmt.Sprintf("test%sunencbucket", azureutil.RandString(5))
+	evidence.Record(evidence.KindBucket, state.bucketName)
+	if err := ledger.Record(state.bucket()); err != nil {
+		return err
+	}
 	resp, err := state.s3Svc.CreateBucket(&s3.CreateBucketInput{
 		Bucket: aws.String(state.bucketName),
 		CreateBucketConfiguration: &s3.CreateBucketConfiguration{
@@ -109,11 +148,11 @@ func (state *EncryptionInFlightAWS) createUnencryptedTransferObjectStorage() err
 		return err
 	}
 	log.Printf("[DEBUG] Created Bucket: %v", resp)
-	return nil
+	return awsutil.TagBucket(state.ctx, state.s3Svc, state.bucketName)
 }
 
 // Wait for Config rule to detect the bucket has been created
-func (state *EncryptionInFlightAWS) detectsTheObjectStorage() error {
+func (state *EncryptionInFlightAWS) DetectsNonCompliantObjectStorage() error {
 	log.Printf("[DEBUG] Waiting for bucket to be detected by Config Rule...")
 	for i := 0; i < maxRetry; i++ {
 		resp, err := state.configSvc.GetComplianceDetailsByConfigRule(&configservice.GetComplianceDetailsByConfigRuleInput{
@@ -164,7 +203,7 @@ func (state *EncryptionInFlightAWS) detectsTheObjectStorage() error {
 }
 
 // Checking with a sleep and retry mechanism on the bucket being remediated to secure transport enabled
-func (state *EncryptionInFlightAWS) encryptedDataTrafficIsEnforced() error {
+func (state *EncryptionInFlightAWS) RemediatesNonCompliantObjectStorage() error {
 	for i := 0; i < maxRetry; i++ {
 		log.Printf("[DEBUG] Checking bucket policy for secure transport setting...")
 		err := state.checkIsSSLRequestOnly()
@@ -177,8 +216,9 @@ func (state *EncryptionInFlightAWS) encryptedDataTrafficIsEnforced() error {
 	return fmt.Errorf("after 5 mins the bucket '%v' is still not remediated [Step Failed]", state.bucketName)
 }
 
-// This is just to check if it there is a bucket policy that's configured with SSL
-// return nil when found the right bucket policy statement on secure transport
+// Evaluates the bucket policy against simulated plain HTTP and HTTPS requests from a public address
+// return nil when the bucket policy explicitly denies the plain HTTP request, and does not the HTTPS one, i.e. the deny is
+// on the transport rather than e.g. on every request
 func (state *EncryptionInFlightAWS) checkIsSSLRequestOnly() error {
 	result, err := state.s3Svc.GetBucketPolicy(&s3.GetBucketPolicyInput{
 		Bucket: aws.String(state.bucketName),
@@ -187,29 +227,35 @@ func (state *EncryptionInFlightAWS) checkIsSSLRequestOnly() error {
 		return err
 	}
 
-	var policyDoc citihubAws.PolicyDocument
-	err = json.Unmarshal([]byte(*result.Policy), &policyDoc)
+	policyDoc, err := citihubAws.ParsePolicyDocument(*result.Policy)
 	if err != nil {
 		return err
 	}
-	for _, stmt := range *policyDoc.Statement {
-		if *stmt.Effect == "Deny" {
-			conditionMap := *stmt.Condition
-
-			// Only start checking of a "Bool" condition
-			if conditionMap["Bool"] != nil {
-				conditionKey := conditionMap["Bool"].(map[string]interface{})
-				v := conditionKey[awsSecureTransport]
-				if v != nil {
-					log.Printf("[DEBUG] %v: %v", awsSecureTransport, v)
-
-					// Only return nil positive when found the right bucket policy statement
-					if v == "false" {
-						return nil
-					}
-				}
-			}
-		}
+
+	probe := citihubAws.Request{
+		Principal:       probePrincipal,
+		Action:          "s3:GetObject",
+		Resource:        fmt.Sprintf("arn:aws:s3:::%s/%s", state.bucketName, probeObject),
+		SourceIP:        net.ParseIP(probeSourceIP),
+		SecureTransport: false,
+	}
+	eval, err := policyDoc.Evaluate(probe)
+	if err != nil {
+		return err
+	}
+	log.Printf("[DEBUG] Plain HTTP GetObject from %v: %v", probeSourceIP, eval)
+	if eval.Decision != citihubAws.ExplicitDeny {
+		return fmt.Errorf("bucket policy on '%v' does not deny a plain HTTP GetObject from %v (%v): %v", state.bucketName, probeSourceIP, eval, policyDoc)
 	}
-	return fmt.Errorf("incorrect bucket policy setting on '%v': %v", state.bucketName, result)
+
+	probe.SecureTransport = true
+	eval, err = policyDoc.Evaluate(probe)
+	if err != nil {
+		return err
+	}
+	log.Printf("[DEBUG] HTTPS GetObject from %v: %v", probeSourceIP, eval)
+	if eval.Decision == citihubAws.ExplicitDeny {
+		return fmt.Errorf("bucket policy on '%v' denies an HTTPS GetObject from %v too, rather than plain HTTP only (%v): %v", state.bucketName, probeSourceIP, eval, policyDoc)
+	}
+	return nil
 }
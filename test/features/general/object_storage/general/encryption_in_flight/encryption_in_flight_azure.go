@@ -1,51 +1,50 @@
-package main
+package encryptioninflight
 
 import (
 	"context"
 	"fmt"
 	"log"
-	"os"
-	"strings"
 
 	"citihub.com/compliance-as-code/internal/azureutil"
 	"citihub.com/compliance-as-code/internal/azureutil/group"
 	"citihub.com/compliance-as-code/internal/azureutil/policy"
 	"citihub.com/compliance-as-code/internal/azureutil/storage"
-	azurePolicy "github.com/Azure/azure-sdk-for-go/services/resources/mgmt/2019-01-01/policy"
+	"citihub.com/compliance-as-code/internal/clouderr"
+	"citihub.com/compliance-as-code/internal/csp"
+	"citihub.com/compliance-as-code/internal/evidence"
+	"citihub.com/compliance-as-code/internal/steps"
 	azureStorage "github.com/Azure/azure-sdk-for-go/services/storage/mgmt/2019-04-01/storage"
-	"github.com/Azure/go-autorest/autorest"
-	"github.com/Azure/go-autorest/autorest/azure"
 	"github.com/Azure/go-autorest/autorest/to"
 )
 
 const (
 	policyName = "deny_http_storage"
+
+	noDetectiveControl = "Azure Policy prevents the creation of Storage Accounts without secure transfer, so there is nothing to detect"
 )
 
 // EncryptionInFlightAzure azure implementation of the encryption in flight for Object Storage feature
 type EncryptionInFlightAzure struct {
-	ctx                       context.Context
-	tags                      map[string]*string
-	httpOption                bool
-	httpsOption               bool
-	policyAssignmentMgmtGroup string
+	ctx context.Context
+
+	// State of the scenario, reset by beginScenario
+	httpOption       bool
+	httpsOption      bool
+	policyAssignment policy.EffectiveAssignment
+	// accountName is the Storage Account created by the scenario, empty if none was
+	accountName string
 }
 
-func (state *EncryptionInFlightAzure) setup() {
-	log.Println("[DEBUG] Setting up \"EncryptionInFlightAzure\"")
-	state.ctx = context.Background()
-	state.policyAssignmentMgmtGroup = os.Getenv(azureutil.PolicyAssignmentManagementGroup)
-	if state.policyAssignmentMgmtGroup == "" {
-		log.Printf("[ERROR] '%v' environment variable is not defined. Policy assignment check against subscription", azureutil.PolicyAssignmentManagementGroup)
-	}
+func init() {
+	csp.Register(featureName, csp.Azure, func() interface{} { return &EncryptionInFlightAzure{} })
+	csp.Unsupported(featureName, csp.Azure, csp.Detective, noDetectiveControl)
+}
 
-	state.tags = map[string]*string{
-		"project": to.StringPtr("CICD"),
-		"env":     to.StringPtr("test"),
-		"tier":    to.StringPtr("internal"),
-	}
+func (state *EncryptionInFlightAzure) setup(ctx context.Context) {
+	log.Println("[DEBUG] Setting up \"EncryptionInFlightAzure\"")
+	state.ctx = ctx
 
-	_, err := group.CreateWithTags(state.ctx, azureutil.ResourceGroup(), state.tags)
+	_, err := group.Create(state.ctx, azureutil.ResourceGroup())
 
 	if err != nil {
 		log.Fatalf("failed to create group: %v\n", err.Error())
@@ -59,26 +58,48 @@ func (state *EncryptionInFlightAzure) teardown() {
 	log.Println("[DEBUG] Teardown completed")
 }
 
-func (state *EncryptionInFlightAzure) securityControlsThatRestrictDataFromBeingUnencryptedInFlight() error {
-	var policyAssignment azurePolicy.Assignment
-	var aerr error
-	// Search assignment from Management Group instead of subscription
-	if state.policyAssignmentMgmtGroup != "" {
-		policyAssignment, aerr = policy.AssignmentByManagementGroup(state.ctx, state.policyAssignmentMgmtGroup, policyName)
+func (state *EncryptionInFlightAzure) beginScenario() {
+	state.httpOption, state.httpsOption = false, false
+	state.policyAssignment = policy.EffectiveAssignment{}
+	state.accountName = ""
+}
+
+// endScenario deletes the Storage Account created by the scenario, if any, rather than leave it to the deletion of the
+// Resource Group.
+func (state *EncryptionInFlightAzure) endScenario() {
+	if state.accountName == "" {
+		return
+	}
+	if err := storage.Delete(state.ctx, state.accountName, azureutil.ResourceGroup()); err != nil {
+		log.Printf("[ERROR] error in deleting test Storage Account %v: %v", state.accountName, err)
 	} else {
-		policyAssignment, aerr = policy.AssignmentBySubscription(state.ctx, azureutil.SubscriptionID(), policyName)
+		log.Printf("[DEBUG] Storage Account %v clean up successful.", state.accountName)
 	}
+}
 
+func (state *EncryptionInFlightAzure) SecurityControlsApplied() error {
+	// The assignment may be made at the Subscription or inherited from any of its Management Groups
+	policyAssignment, aerr := policy.EffectiveAssignmentByName(state.ctx, azureutil.SubscriptionID(), azureutil.ResourceGroup(), policyName)
 	if aerr != nil {
 		log.Printf("[ERROR] Get policy assignment error: %v", aerr)
 		return aerr
 	}
 
-	log.Printf("[DEBUG] Policy assignment check: %v [Step PASSED]", *policyAssignment.Name)
+	state.policyAssignment = policyAssignment
+	evidence.Record(evidence.KindPolicyAssignment, to.String(policyAssignment.ID))
+	log.Printf("[DEBUG] Policy assignment check: %v [Step PASSED]", policyAssignment)
 	return nil
 }
 
-func (state *EncryptionInFlightAzure) weProvisionAnObjectStorageBucket() error {
+func (state *EncryptionInFlightAzure) SecurityControlsEnforcedWithEffect(effect string) error {
+	i, err := policy.InspectAssignment(state.ctx, state.policyAssignment)
+	if err != nil {
+		return err
+	}
+	return i.CheckEffect(effect)
+}
+
+func (state *EncryptionInFlightAzure) ProvisionObjectStorage() error {
 	// Nothing to do here
 	return nil
 }
@@ -101,9 +122,10 @@ func (state *EncryptionInFlightAzure) httpsAccessIs(arg1 string) error {
 	return nil
 }
 
-func (state *EncryptionInFlightAzure) creationWillWithAnErrorMatching(expectation, errDescription string) error {
+func (state *EncryptionInFlightAzure) CreationWill(expectation, errDescription string) error {
 	accountName := azureutil.RandString(5) + "storageac"
 
+	var account azureStorage.Account
 	var err error
 
 	networkRuleSet := azureStorage.NetworkRuleSet{
@@ -114,67 +136,79 @@ func (state *EncryptionInFlightAzure) creationWillWithAnErrorMatching(expectatio
 	// Both true take it as http option is try
 	if state.httpsOption && state.httpOption {
 		log.Printf("[DEBUG] Creating Storage Account with HTTPS: %v", false)
-		_, err = storage.CreateWithNetworkRuleSet(state.ctx, accountName,
-			azureutil.ResourceGroup(), state.tags, false, &networkRuleSet)
+		account, err = storage.CreateWithNetworkRuleSet(state.ctx, accountName,
+			azureutil.ResourceGroup(), nil, false, &networkRuleSet)
 	} else if state.httpsOption {
 		log.Printf("[DEBUG] Creating Storage Account with HTTPS: %v", state.httpsOption)
-		_, err = storage.CreateWithNetworkRuleSet(state.ctx, accountName,
-			azureutil.ResourceGroup(), state.tags, state.httpsOption, &networkRuleSet)
+		account, err = storage.CreateWithNetworkRuleSet(state.ctx, accountName,
+			azureutil.ResourceGroup(), nil, state.httpsOption, &networkRuleSet)
 	} else if state.httpOption {
 		log.Printf("[DEBUG] Creating Storage Account with HTTPS: %v", state.httpsOption)
-		_, err = storage.CreateWithNetworkRuleSet(state.ctx, accountName,
-			azureutil.ResourceGroup(), state.tags, state.httpsOption, &networkRuleSet)
+		account, err = storage.CreateWithNetworkRuleSet(state.ctx, accountName,
+			azureutil.ResourceGroup(), nil, state.httpsOption, &networkRuleSet)
+	}
+
+	if err == nil {
+		state.accountName = accountName
+		evidence.Record(evidence.KindStorageAccount, to.String(account.ID))
+	} else {
+		// The account was not created, e.g. because a policy denied it, so it has a name but no ID
+		evidence.Record(evidence.KindStorageAccount, accountName)
+	}
+
+	fail, ferr := steps.ExpectFailure(expectation)
+	if ferr != nil {
+		return ferr
 	}
 
-	if expectation == "Fail" {
+	if fail {
 
 		if err == nil {
 			return fmt.Errorf("storage account was created, but should not have been: policy is not working or incorrectly configured")
 		}
 
-		detailedError := err.(autorest.DetailedError)
-		originalErr := detailedError.Original
-		detailed := originalErr.(*azure.ServiceError)
-
-		log.Printf("[DEBUG] Detailed Error: %v", detailed)
-
-		if strings.EqualFold(detailed.Code, "RequestDisallowedByPolicy") {
-			// Now check if it is the right policy
-			if strings.Contains(detailed.Message, policyName) {
-				log.Printf("[DEBUG] Request was Disallowed By Policy: %v [Step PASSED]", policyName)
-				return nil
-			}
-			return fmt.Errorf("storage account was not created but blocked not by the right policy: %v", detailed.Message)
+		// Check that it is the right policy that denied the request
+		denial, ok := clouderr.DeniedBy(err, policyName)
+		if !ok {
+			return fmt.Errorf("storage account was not created, but not because of policy %v (%v error): %v", policyName, clouderr.Classify(err), err)
 		}
 
-		return fmt.Errorf("storage account was not created")
-	} else if expectation == "Succeed" {
-		if err != nil {
-			log.Printf("[ERROR] Unexpected failure in create storage ac [Step FAILED]")
-			return err
+		// The Error Description must match the denial or the metadata of the policy, e.g. its non-compliance message
+		i, ierr := policy.InspectAssignment(state.ctx, state.policyAssignment)
+		if ierr != nil {
+			return ierr
 		}
+		if merr := denial.Match(errDescription, i.Messages()...); merr != nil {
+			return merr
+		}
+
+		log.Printf("[DEBUG] Request was Disallowed By Policy: %v [Step PASSED]", denial)
 		return nil
 	}
 
-	return fmt.Errorf("unsupported `result` option '%s' in the Gherkin feature - use either 'Fail' or 'Succeed'", expectation)
+	if err != nil {
+		log.Printf("[ERROR] Unexpected failure in create storage ac [Step FAILED]")
+		return err
+	}
+	return nil
 }
 
-func (state *EncryptionInFlightAzure) detectObjectStorageUnencryptedTransferAvailable() error {
-	return fmt.Errorf("azure policy prevent creation of object storage with in-secure transport making this test irrelevant")
+func (state *EncryptionInFlightAzure) DetectiveCapabilityAvailable() error {
+	return csp.NotApplicable(noDetectiveControl)
 }
 
-func (state *EncryptionInFlightAzure) detectObjectStorageUnencryptedTransferEnabled() error {
-	return nil
+func (state *EncryptionInFlightAzure) DetectiveCapabilityActive() error {
+	return csp.NotApplicable(noDetectiveControl)
 }
 
-func (state *EncryptionInFlightAzure) createUnencryptedTransferObjectStorage() error {
-	return nil
+func (state *EncryptionInFlightAzure) CreateNonCompliantObjectStorage() error {
+	return csp.NotApplicable(noDetectiveControl)
 }
 
-func (state *EncryptionInFlightAzure) detectsTheObjectStorage() error {
-	return nil
+func (state *EncryptionInFlightAzure) DetectsNonCompliantObjectStorage() error {
+	return csp.NotApplicable(noDetectiveControl)
 }
 
-func (state *EncryptionInFlightAzure) encryptedDataTrafficIsEnforced() error {
-	return nil
+func (state *EncryptionInFlightAzure) RemediatesNonCompliantObjectStorage() error {
+	return csp.NotApplicable(noDetectiveControl)
 }
@@ -15,7 +15,8 @@ Feature: Object Storage Encryption in Flight
 
     @preventative
     Scenario Outline: Prevent Creation of Object Storage Without Encryption in Flight
-      Given security controls that restrict data from being unencrypted in flight
+      Given security controls that restrict data from being unencrypted in flight are applied
+      And the security controls are enforced with effect "Deny"
       When we provision an Object Storage bucket
       And http access is "<HTTP Option>"
       And https access is "<HTTPS Option>"
@@ -0,0 +1,22 @@
+@non_intrusive_test
+@json
+@CCO:CHC2-SVD001
+@CCO:CHC2-SVD030
+@csp.azure
+Feature: Deployed Azure Policy Definitions Match Source Control
+
+  As a Cloud Security Architect
+  I want to ensure that the policy definitions deployed to Azure are the ones we commit to the repository
+  So that I can evidence that the preventative controls in force are the reviewed ones
+
+  Every policy module with a policy_definition.json manifest is compared with the definition of the same name deployed to Azure.
+  Differences in the rule, parameters, mode and metadata are reported.
+
+  Rule: CHC2-SVD001, CHC2-SVD030 - the preventative controls in force, e.g. denying storage accounts without customer-managed keys or with unrestricted network access, are the reviewed ones
+
+    @detective
+    Scenario: Policy Definitions Have Not Drifted From Source Control
+
+      Given the policy definitions in source control
+      When we fetch the deployed definitions from the management group in environment variable "AZURE_POLICY_DEFINITION_MANAGEMENT_GROUP"
+      Then the deployed policy matches source control
@@ -0,0 +1,82 @@
+// Package policydrift is the suite of the feature checking that the deployed Azure Policy definitions match source control.
+package policydrift
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	"citihub.com/compliance-as-code/internal/azurepolicy"
+	"citihub.com/compliance-as-code/internal/azureutil/policy"
+	"citihub.com/compliance-as-code/internal/csp"
+	"citihub.com/compliance-as-code/internal/suite"
+	"github.com/cucumber/godog"
+)
+
+// policyModules is the directory of the terraform policy modules, each of which may hold a policy_definition.json manifest
+const policyModules = "../../../../terraform/modules/policies"
+
+type policyDrift struct {
+	ctx     context.Context
+	sources []azurepolicy.Definition
+	drifts  []policy.Drift
+}
+
+func (p *policyDrift) policyDefinitionsInSourceControl() error {
+	defs, err := azurepolicy.LoadDefinitions(policyModules)
+	if err != nil {
+		return err
+	}
+	if len(defs) == 0 {
+		return errors.New("there are no policy definitions in source control")
+	}
+	p.sources = defs
+	return nil
+}
+
+func (p *policyDrift) fetchDeployedDefinitions(envVar string) error {
+	// Definitions are deployed to the Subscription when no Management Group is given
+	mg := os.Getenv(envVar)
+	if mg == "" {
+		log.Printf("[DEBUG] Environment variable %v is not set, comparing with the Policy Definitions of the Subscription", envVar)
+	}
+
+	p.drifts = nil
+	for _, source := range p.sources {
+		drift, err := policy.DefinitionDrift(p.ctx, mg, source)
+		if err != nil {
+			return err
+		}
+		p.drifts = append(p.drifts, drift)
+	}
+	return nil
+}
+
+func (p *policyDrift) deployedPolicyMatchesSourceControl() error {
+	var success error
+	for _, d := range p.drifts {
+		for _, diff := range d.Differences {
+			success = errors.New("one or more deployed policy definitions differ from source control")
+			fmt.Printf("Policy Definition %v at %v has drifted - %s\n", d.Definition, d.Scope, diff)
+		}
+	}
+	return success
+}
+
+const featureName = "policy_drift"
+
+func init() {
+	suite.Register(featureName, FeatureContext)
+}
+
+func FeatureContext(s *godog.Suite) {
+	state := &policyDrift{ctx: context.Background()}
+
+	// Bound through csp so that the evidence of the scenario is recorded like that of the other controls
+	st := csp.NewSteps(s, featureName)
+	st.Step(`^the policy definitions in source control$`, state.policyDefinitionsInSourceControl)
+	st.Step(`^we fetch the deployed definitions from the management group in environment variable "([^"]*)"$`, state.fetchDeployedDefinitions)
+	st.Step(`^the deployed policy matches source control$`, state.deployedPolicyMatchesSourceControl)
+}
@@ -0,0 +1,34 @@
+@internal
+@json
+Feature: Azure Policy Rules Yield the Expected Effect for Example Resources
+
+  Every Azure Policy rule that we commit to the repository comes with example ARM resources in the "compliant" and "noncompliant" directories next to it.
+  The rules are evaluated offline against these fixtures, so that a broken rule is caught before it is deployed with terraform.
+
+  Scenario Outline: Policy Rules Yield the Expected Effect
+
+    Then policy "<Policy>" yields effect "<Effect>" for fixture "<Fixture>"
+
+    Examples:
+      | Policy                    | Fixture                                              | Effect |
+      | storageaccount_https      | compliant/storageaccount_https_https_only            | none   |
+      | storageaccount_https      | noncompliant/storageaccount_https_http_allowed       | deny   |
+      | storageaccount_private    | compliant/storageaccount_private_default_deny        | none   |
+      | storageaccount_private    | noncompliant/storageaccount_private_default_allow    | deny   |
+      | storageaccount_encryption | compliant/storageaccount_encryption_enabled          | none   |
+      | storageaccount_encryption | noncompliant/storageaccount_encryption_blob_disabled | deny   |
+      | aks_rbac_deny             | compliant/aks_rbac_deny_rbac_enabled                 | none   |
+      | aks_rbac_deny             | noncompliant/aks_rbac_deny_rbac_disabled             | deny   |
+      | aks_rbac_deny             | noncompliant/aks_rbac_deny_rbac_missing              | deny   |
+
+  Scenario Outline: Storage Accounts Are Only Accessible From Whitelisted Address Ranges
+
+    Given policy "deny_unrestricted_access_to_storage_account" is assigned with parameter "allowedAddressRanges" set to '["219.79.19.0/24", "170.74.231.168"]'
+    Then policy "deny_unrestricted_access_to_storage_account" yields effect "<Effect>" for fixture "<Fixture>"
+
+    Examples:
+      | Fixture                           | Effect |
+      | compliant/whitelisted_ip_rules    | none   |
+      | compliant/no_ip_rules             | none   |
+      | noncompliant/default_action_allow | deny   |
+      | noncompliant/unlisted_ip_rule     | deny   |
@@ -0,0 +1,145 @@
+// Package policyevaluation is the suite of the feature evaluating the Azure Policy rules against compliant and noncompliant resource fixtures.
+package policyevaluation
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"citihub.com/compliance-as-code/internal/azurepolicy"
+	"citihub.com/compliance-as-code/internal/azurepolicy/alias"
+	"citihub.com/compliance-as-code/internal/suite"
+	"github.com/cucumber/godog"
+	messages "github.com/cucumber/messages-go/v10"
+)
+
+// policyRoots are the directories holding Azure Policy rules, either as files directly in the directory or one directory per policy
+var policyRoots = []string{
+	"../../../../terraform/resources/azure_policy",
+	"../../../../terraform/modules/policies",
+}
+
+// aliasCatalog is the catalog of Azure Policy aliases used to resolve policy fields to resource properties
+const aliasCatalog = "../../../../catalog/azure_policy_aliases.json"
+
+// Fixture directories, next to the policy rule
+const (
+	compliantFixtures    = "compliant"
+	noncompliantFixtures = "noncompliant"
+)
+
+type policyEvaluation struct {
+	aliases *alias.Catalog
+	// values holds the assignment parameter values of the scenario, keyed by policy name
+	values map[string]map[string]interface{}
+}
+
+func (p *policyEvaluation) reset(*messages.Pickle) {
+	p.values = make(map[string]map[string]interface{})
+}
+
+func (p *policyEvaluation) policyIsAssignedWithParameter(policy, name, value string) error {
+	var v interface{}
+	d := json.NewDecoder(strings.NewReader(value))
+	d.UseNumber()
+	if err := d.Decode(&v); err != nil {
+		return fmt.Errorf("value of parameter '%s' is not valid JSON: %v", name, err)
+	}
+
+	if p.values[policy] == nil {
+		p.values[policy] = make(map[string]interface{})
+	}
+	p.values[policy][name] = v
+	return nil
+}
+
+func (p *policyEvaluation) policyYieldsEffectForFixture(policy, effect, fixture string) error {
+	dir, file, err := findPolicy(policy)
+	if err != nil {
+		return err
+	}
+	rule, err := azurepolicy.LoadRule(file)
+	if err != nil {
+		return err
+	}
+	params, err := loadParameters(dir, policy)
+	if err != nil {
+		return err
+	}
+
+	if filepath.Ext(fixture) == "" {
+		fixture += ".json"
+	}
+	resource, err := azurepolicy.LoadResource(filepath.Join(dir, filepath.FromSlash(fixture)))
+	if err != nil {
+		return err
+	}
+
+	e := azurepolicy.Evaluator{Parameters: params, Values: p.values[policy], Aliases: p.aliases}
+	result, err := e.Evaluate(rule, resource)
+	if err != nil {
+		return fmt.Errorf("cannot evaluate policy '%s' against fixture '%s': %v", policy, fixture, err)
+	}
+	log.Printf("[DEBUG] Policy '%s' yields effect '%s' for fixture '%s'", policy, result.Effect, fixture)
+
+	if !strings.EqualFold(result.Effect, effect) {
+		return fmt.Errorf("policy '%s' yields effect '%s' for fixture '%s', expected '%s'", policy, result.Effect, fixture, effect)
+	}
+	return nil
+}
+
+// findPolicy returns the directory and path of the named policy rule, looking for <policy>.json in each of the policy roots and their sub-directories.
+func findPolicy(policy string) (string, string, error) {
+	for _, root := range policyRoots {
+		candidates := []string{filepath.Join(root, policy+".json")}
+
+		dirs, err := ioutil.ReadDir(root)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to read policy directory %s: %v", root, err)
+		}
+		for _, d := range dirs {
+			if d.IsDir() && d.Name() != compliantFixtures && d.Name() != noncompliantFixtures {
+				candidates = append(candidates, filepath.Join(root, d.Name(), policy+".json"))
+			}
+		}
+
+		for _, c := range candidates {
+			if _, err := os.Stat(c); err == nil {
+				return filepath.Dir(c), c, nil
+			}
+		}
+	}
+	return "", "", fmt.Errorf("policy '%s' not found in %v", policy, policyRoots)
+}
+
+// loadParameters loads the parameter definitions of a policy from policy_parameters.json or <policy>_parameters.json in the policy directory, if present.
+func loadParameters(dir, policy string) (azurepolicy.Parameters, error) {
+	for _, name := range []string{"policy_parameters.json", policy + "_parameters.json"} {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return azurepolicy.LoadParameters(path)
+		}
+	}
+	return nil, nil
+}
+
+func init() {
+	suite.Register("policy_evaluation", FeatureContext)
+}
+
+func FeatureContext(s *godog.Suite) {
+	aliases, err := alias.Load(aliasCatalog)
+	if err != nil {
+		log.Fatalf("[ERROR] Cannot load alias catalog %v due to %v", aliasCatalog, err)
+	}
+	state := &policyEvaluation{aliases: aliases}
+
+	s.BeforeScenario(state.reset)
+
+	s.Step(`^policy "([^"]*)" is assigned with parameter "([^"]*)" set to '(.*)'$`, state.policyIsAssignedWithParameter)
+	s.Step(`^policy "([^"]*)" yields effect "([^"]*)" for fixture "([^"]*)"$`, state.policyYieldsEffectForFixture)
+}
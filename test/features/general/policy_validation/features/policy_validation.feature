@@ -1,11 +1,13 @@
 @internal
 @json
-Feature: Azure Policy JSON Documents are Legal JSON and Valid Against the Published Microsoft Schema
+Feature: Azure Policy JSON Documents are Legal JSON and Valid Against the Policy Rule Schema of the Repository
 
-  Azure Policy documents that we commit to the repository must be both well-formed JSONs and also must comply with the appropriate schema.
+  Azure Policy documents that we commit to the repository must be both well-formed JSONs and also must comply with the policy rule schema published by Microsoft for API version 2019-06-01, vendored in the repository.
+  They must also pass lint rules that the schema cannot express: the effect is a parameter, parameters are declared and used, and storage fields are known aliases.
 
   Scenario:
 
     Given a directory of Azure Policy files in JSON format
     Then the documents must be valid JSON
-    And the JSON must be valid against the Microsoft schema
+    And the JSON must be valid against the policy rule schema
+    And the policies must pass the lint rules
@@ -1,6 +1,226 @@
-package main
+// Package policyvalidation is the suite of the feature validating the Azure Policy files.
+package policyvalidation
 
-//main holds the variables and constants used by the tests
-func main() {
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
 
+	"citihub.com/compliance-as-code/internal/azurepolicy"
+	"citihub.com/compliance-as-code/internal/azurepolicy/alias"
+	"citihub.com/compliance-as-code/internal/suite"
+	"github.com/cucumber/godog"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// policyRoots are the directories scanned for Azure Policy JSON files, including their sub-directories
+var policyRoots = []string{
+	"../../../../terraform/resources/azure_policy",
+	"../../../../terraform/modules/policies",
+}
+
+// schemaFile is the published schema of the policy rules, vendored in the repository so that the suite runs offline
+var schemaFile = filepath.Join("../../../..", azurepolicy.SchemaFile)
+
+// aliasCatalog is the catalog of Azure Policy aliases that policy fields are checked against
+const aliasCatalog = "../../../../catalog/azure_policy_aliases.json"
+
+var policySchema *gojsonschema.Schema
+
+var aliases *alias.Catalog
+
+func testJSONPresent() error {
+	files := getJSONPolicies()
+	if len(files) == 0 {
+		return errors.New("there are no JSON files to test")
+	}
+	return nil
+}
+
+// getJSONPolicies returns the paths of the JSON files under the policy roots, skipping the fixture directories used by policy_evaluation
+func getJSONPolicies() []string {
+	var files []string
+	for _, root := range policyRoots {
+		err := filepath.Walk(root, func(path string, fi os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if fi.IsDir() && (fi.Name() == "compliant" || fi.Name() == "noncompliant") {
+				return filepath.SkipDir
+			}
+			if !fi.IsDir() && filepath.Ext(path) == ".json" {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			panic("Failed to read or open JSON policy directory")
+		}
+	}
+	return files
+}
+
+// isParametersFile reports whether the file holds policy parameter definitions rather than a policy rule
+func isParametersFile(path string) bool {
+	return strings.HasSuffix(filepath.Base(path), "parameters.json")
+}
+
+// isManifestFile reports whether the file is a policy definition manifest naming the rule and parameter files of a policy module
+func isManifestFile(path string) bool {
+	return filepath.Base(path) == azurepolicy.ManifestFile
+}
+
+// parametersFile returns the parameter definitions file of a policy rule: policy_parameters.json in a policy module, or <rule>_parameters.json next to the rule
+func parametersFile(rule string) string {
+	dir := filepath.Dir(rule)
+	if p := filepath.Join(dir, "policy_parameters.json"); fileExists(p) {
+		return p
+	}
+	return strings.TrimSuffix(rule, ".json") + "_parameters.json"
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func loadSchema() {
+	abs, err := filepath.Abs(schemaFile)
+	if err != nil {
+		log.Fatalf("[ERROR] Cannot resolve path of %v due to %v", schemaFile, err)
+	}
+	if !fileExists(abs) {
+		log.Fatalf("[ERROR] Schema %v is not vendored, fetch it from %v with go run ./cmd/schemas", schemaFile, azurepolicy.SchemaURL)
+	}
+	policySchema, err = gojsonschema.NewSchema(gojsonschema.NewReferenceLoader("file://" + filepath.ToSlash(abs)))
+	if err != nil {
+		log.Fatalf("[ERROR] Cannot load schema %v due to %v", schemaFile, err)
+	}
+}
+
+func loadAliases() {
+	var err error
+	aliases, err = alias.Load(aliasCatalog)
+	if err != nil {
+		log.Fatalf("[ERROR] Cannot load alias catalog %v due to %v", aliasCatalog, err)
+	}
+}
+
+func testValidJSON() error {
+	files := getJSONPolicies()
+	for _, f := range files {
+
+		fb, err := ioutil.ReadFile(f)
+		if err != nil {
+			log.Fatalf("Failed to read JSON file wih name %v", f)
+			return err
+		}
+		var j interface{}
+
+		err = json.NewDecoder(bytes.NewReader(fb)).Decode(&j)
+		if err != nil {
+			log.Fatalf("Failed to decode JSON from file with name %v", f)
+			return err
+		}
+	}
+	return nil
+}
+
+func testValidSchemaJSON() error {
+
+	files := getJSONPolicies()
+	var success error = nil
+	for _, f := range files {
+		if isParametersFile(f) || isManifestFile(f) {
+			continue
+		}
+
+		fb, err := ioutil.ReadFile(f)
+		if err != nil {
+			log.Printf("[ERROR] Cannot read %v due to %v", f, err)
+			return err
+		}
+		documentLoader := gojsonschema.NewBytesLoader(fb)
+
+		result, err := policySchema.Validate(documentLoader)
+		if err != nil {
+			log.Printf("[ERROR] Cannot validate %v due to %v", f, err)
+			return err
+		}
+
+		if !result.Valid() {
+			success = errors.New("one or more documents failed validation")
+			for _, err := range result.Errors() {
+				fmt.Printf("Failed to validate %v - %s\n", f, err)
+			}
+		}
+	}
+	return success
+}
+
+func testLint() error {
+
+	files := getJSONPolicies()
+	var success error = nil
+	usedParameters := map[string]bool{}
+	for _, f := range files {
+		if isManifestFile(f) {
+			if _, err := azurepolicy.LoadDefinition(f); err != nil {
+				success = errors.New("one or more documents failed linting")
+				fmt.Printf("Failed to lint %v - %s\n", f, err)
+			}
+			continue
+		}
+		if isParametersFile(f) {
+			continue
+		}
+
+		rule, err := azurepolicy.LoadRule(f)
+		if err != nil {
+			log.Printf("[ERROR] Cannot lint %v due to %v", f, err)
+			return err
+		}
+
+		var params azurepolicy.Parameters
+		if pf := parametersFile(f); fileExists(pf) {
+			usedParameters[pf] = true
+			if params, err = azurepolicy.LoadParameters(pf); err != nil {
+				log.Printf("[ERROR] Cannot lint %v due to %v", f, err)
+				return err
+			}
+		}
+
+		for _, finding := range azurepolicy.Lint(rule, params, aliases) {
+			success = errors.New("one or more documents failed linting")
+			fmt.Printf("Failed to lint %v - %s\n", f, finding)
+		}
+	}
+
+	for _, f := range files {
+		if isParametersFile(f) && !usedParameters[f] {
+			success = errors.New("one or more documents failed linting")
+			fmt.Printf("Failed to lint %v - parameters are not used by any policy rule\n", f)
+		}
+	}
+	return success
+}
+
+func init() {
+	suite.Register("policy_validation", FeatureContext)
+}
+
+func FeatureContext(s *godog.Suite) {
+	s.BeforeSuite(loadSchema)
+	s.BeforeSuite(loadAliases)
+
+	s.Step(`^a directory of Azure Policy files in JSON format`, testJSONPresent)
+	s.Step(`^the documents must be valid JSON`, testValidJSON)
+	s.Step(`^the JSON must be valid against the policy rule schema`, testValidSchemaJSON)
+	s.Step(`^the policies must pass the lint rules`, testLint)
 }